@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/linkcheck"
+	"github.com/jschaf/jsc/pkg/log"
+	"github.com/jschaf/jsc/pkg/process"
+)
+
+var distDirFlag = flag.String("dist", dirs.Dist, "the dist dir of a site build to check")
+
+func check(distDir string) error {
+	start := time.Now()
+	slog.Info("start link check", slog.String("dist", distDir))
+	problems, err := linkcheck.Check(distDir)
+	if err != nil {
+		return fmt.Errorf("check links: %w", err)
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	slog.Info("finish link check", slog.Int("problems", len(problems)), slog.Duration("duration", time.Since(start)))
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d broken links", len(problems))
+	}
+	return nil
+}
+
+func main() {
+	process.RunMain(runMain)
+}
+
+func runMain(ctx context.Context) error {
+	_, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fset := flag.CommandLine
+	logLevel := log.DefineFlags(fset)
+	if err := fset.Parse(os.Args[1:]); err != nil {
+		return fmt.Errorf("parse flags: %w", err)
+	}
+
+	slog.SetDefault(slog.New(log.NewDevHandler(os.Stderr, &slog.HandlerOptions{
+		Level: logLevel,
+	})))
+
+	if err := check(*distDirFlag); err != nil {
+		return fmt.Errorf("check: %w", err)
+	}
+	return nil
+}
@@ -14,16 +14,29 @@ import (
 	"github.com/jschaf/jsc/pkg/process"
 )
 
-var postGlobFlag = flag.String("glob", "", "if given, only compile files that match glob")
+var (
+	postGlobFlag = flag.String("glob", "", "if given, only compile files that match glob")
+	previewFlag  = flag.Bool("preview", false, "also compile unpublished posts with a draft banner into "+dirs.DistPreview+" instead of "+dirs.Dist)
+)
 
-func compile(glob string) error {
+func compile(glob string, preview bool) error {
 	start := time.Now()
 	globStr := *postGlobFlag
 	if globStr == "" {
 		globStr = "all"
 	}
-	slog.Info("start compile", slog.String("glob", globStr))
-	c := compiler.NewDetailCompiler(dirs.Dist)
+	// Only write drafts to the preview dir so they're never deployed from the
+	// dist dir.
+	distDir, mode := dirs.Dist, compiler.PublishModeProd
+	if preview {
+		distDir, mode = dirs.DistPreview, compiler.PublishModePreview
+	}
+	cfg, err := compiler.LoadSite()
+	if err != nil {
+		return err
+	}
+	slog.Info("start compile", slog.String("glob", globStr), slog.String("dist_dir", distDir))
+	c := compiler.NewDetailCompiler(distDir, mode, cfg)
 	if err := c.Compile(glob); err != nil {
 		return fmt.Errorf("compile detail posts: %w", err)
 	}
@@ -49,7 +62,7 @@ func runMain(ctx context.Context) error {
 		Level: logLevel,
 	})))
 
-	if err := compile(*postGlobFlag); err != nil {
+	if err := compile(*postGlobFlag, *previewFlag); err != nil {
 		return fmt.Errorf("compile: %w", err)
 	}
 	return nil
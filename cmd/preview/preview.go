@@ -0,0 +1,117 @@
+// Command preview refreshes the committed cache of link previews. Builds only
+// read the cache, so run this command after adding external links to a post.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/log"
+	"github.com/jschaf/jsc/pkg/markdown/linkio"
+	"github.com/jschaf/jsc/pkg/process"
+)
+
+var (
+	allFlag   = flag.Bool("all", false, "refetch previews for links already in the cache")
+	delayFlag = flag.Duration("delay", 200*time.Millisecond, "the delay between fetches to avoid rate limits")
+)
+
+// markdownLinks returns the previewable links in all markdown files in dirs.
+func markdownLinks(dirs []string) ([]string, error) {
+	seen := make(map[string]struct{})
+	var links []string
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".md" {
+				return err
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read markdown: %w", err)
+			}
+			for _, link := range linkio.ExternalLinks(src) {
+				if _, ok := seen[link]; !ok {
+					seen[link] = struct{}{}
+					links = append(links, link)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walk %s: %w", dir, err)
+		}
+	}
+	return links, nil
+}
+
+func refresh(ctx context.Context) error {
+	root := git.RootDir()
+	links, err := markdownLinks([]string{
+		filepath.Join(root, dirs.Posts),
+		filepath.Join(root, dirs.TIL),
+		filepath.Join(root, dirs.Book),
+	})
+	if err != nil {
+		return fmt.Errorf("find links: %w", err)
+	}
+	cache, err := linkio.LoadCache(linkio.CachePath())
+	if err != nil {
+		return fmt.Errorf("load cache: %w", err)
+	}
+	snippeter := linkio.NewSnippeter(&http.Client{Timeout: 15 * time.Second})
+
+	fetched, failed := 0, 0
+	for _, link := range links {
+		if _, ok := cache.Get(link); ok && !*allFlag {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			break
+		}
+		p, err := snippeter.Snippet(link)
+		if err != nil {
+			slog.Warn("fetch link preview", "link", link, "error", err)
+			failed++
+			continue
+		}
+		slog.Debug("fetched link preview", "link", link, "title", p.Title)
+		cache.Put(p)
+		fetched++
+		time.Sleep(*delayFlag)
+	}
+	// Save partial progress, even if interrupted.
+	if err := cache.Save(); err != nil {
+		return fmt.Errorf("save cache: %w", err)
+	}
+	slog.Info("refreshed link previews", "links", len(links), "fetched", fetched, "failed", failed)
+	return nil
+}
+
+func main() {
+	process.RunMain(runMain)
+}
+
+func runMain(ctx context.Context) error {
+	fset := flag.CommandLine
+	logLevel := log.DefineFlags(fset)
+	if err := fset.Parse(os.Args[1:]); err != nil {
+		return fmt.Errorf("parse flags: %w", err)
+	}
+
+	slog.SetDefault(slog.New(log.NewDevHandler(os.Stderr, &slog.HandlerOptions{
+		Level: logLevel,
+	})))
+
+	if err := refresh(ctx); err != nil {
+		return fmt.Errorf("refresh link previews: %w", err)
+	}
+	return nil
+}
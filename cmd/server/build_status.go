@@ -0,0 +1,57 @@
+package main
+
+import (
+	"html"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// buildStatus records the error of the latest build so the dev server shows
+// the error in the browser instead of a stale page or crashing.
+type buildStatus struct {
+	mu  sync.Mutex
+	err error
+}
+
+// set records the result of a build. A nil err clears the previous error.
+func (b *buildStatus) set(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		slog.Error("build failed", "error", err)
+	}
+	b.err = err
+}
+
+func (b *buildStatus) get() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// errorPageHandler serves an error page for page requests while the latest
+// build failed. Serves everything else, like CSS and images, with next.
+func (b *buildStatus) errorPageHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := b.get()
+		// Pages have clean URLs without an extension.
+		if err == nil || strings.Contains(r.URL.Path, ".") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(strings.Join([]string{
+			"<!DOCTYPE html>",
+			"<html lang='en'>",
+			"<head><meta charset='utf-8'><title>Build failed</title></head>",
+			"<body>",
+			"<h1>Build failed</h1>",
+			"<pre>" + html.EscapeString(err.Error()) + "</pre>",
+			"</body>",
+			"</html>",
+		}, "\n")))
+	})
+}
@@ -15,6 +15,7 @@ import (
 type buildRoutesOpts struct {
 	distDir string
 	lr      *livereload.LiveReload
+	status  *buildStatus
 }
 
 func buildRoutes(opts buildRoutesOpts) *http.ServeMux {
@@ -39,7 +40,7 @@ func buildRoutes(opts buildRoutesOpts) *http.ServeMux {
 			lrJSPath, port, strings.TrimLeft(lrPath, "/")),
 		"</script>",
 	}, "")
-	mux.Handle("/", opts.lr.NewHTMLInjector(lrScript, distDirHandler))
+	mux.Handle("/", opts.lr.NewHTMLInjector(lrScript, opts.status.errorPageHandler(distDirHandler)))
 	return mux
 }
 
@@ -18,6 +18,7 @@ import (
 	"github.com/jschaf/jsc/pkg/git"
 	"github.com/jschaf/jsc/pkg/livereload"
 	"github.com/jschaf/jsc/pkg/log"
+	"github.com/jschaf/jsc/pkg/markdown/compiler"
 	"github.com/jschaf/jsc/pkg/net/srv"
 	"github.com/jschaf/jsc/pkg/process"
 	"github.com/jschaf/jsc/pkg/sites"
@@ -74,9 +75,15 @@ func InitServer(ctx context.Context, opts ServerOpts) (*Server, error) {
 		return nil, fmt.Errorf("clean public dir: %w", err)
 	}
 
-	// Rebuild in case content changed since last run.
-	if err := sites.Rebuild(opts.DistDir); err != nil {
-		return nil, fmt.Errorf("rebuild site: %w", err)
+	// Rebuild in case content changed since last run. The builder tracks page
+	// dependencies so the watcher only rebuilds pages affected by a change.
+	// Preview drafts so we can see them before publishing.
+	// A failed build shows the error in the browser until the watcher
+	// rebuilds the site from fixed input.
+	builder := sites.NewBuilder(opts.DistDir, compiler.PublishModePreview)
+	status := &buildStatus{}
+	if err := builder.Rebuild(); err != nil {
+		status.set(fmt.Errorf("rebuild site: %w", err))
 	}
 
 	// Live reload.
@@ -84,8 +91,12 @@ func InitServer(ctx context.Context, opts ServerOpts) (*Server, error) {
 	go lr.Start(ctx)
 
 	// File system watcher.
-	watcher := NewFSWatcher(opts.DistDir, lr)
+	watcher := NewFSWatcher(opts.DistDir, builder, status, lr)
 	root := git.RootDir()
+	// Watch the root for bib files, like ref.bib.
+	if err := watcher.watchFiles(root); err != nil {
+		return nil, fmt.Errorf("watch root files: %w", err)
+	}
 	if err := watcher.watchDirs(
 		filepath.Join(root, dirs.Book),
 		filepath.Join(root, dirs.Cmd),
@@ -108,6 +119,7 @@ func InitServer(ctx context.Context, opts ServerOpts) (*Server, error) {
 	routeHandler := buildRoutes(buildRoutesOpts{
 		distDir: opts.DistDir,
 		lr:      lr,
+		status:  status,
 	})
 	h2s := &http2.Server{}
 	httpSrv := &http.Server{
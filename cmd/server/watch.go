@@ -14,6 +14,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/jschaf/jsc/pkg/css"
+	"github.com/jschaf/jsc/pkg/dirs"
 	"github.com/jschaf/jsc/pkg/errs"
 	"github.com/jschaf/jsc/pkg/git"
 	"github.com/jschaf/jsc/pkg/livereload"
@@ -27,17 +28,21 @@ type FSWatcher struct {
 	liveReload *livereload.LiveReload
 	watcher    *fsnotify.Watcher
 	distDir    string
+	builder    *sites.Builder
+	status     *buildStatus
 	stopOnce   *sync.Once
 	stopC      chan struct{}
 }
 
-func NewFSWatcher(distDir string, lr *livereload.LiveReload) *FSWatcher {
+func NewFSWatcher(distDir string, builder *sites.Builder, status *buildStatus, lr *livereload.LiveReload) *FSWatcher {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		panic(err)
 	}
 	return &FSWatcher{
 		distDir:    distDir,
+		builder:    builder,
+		status:     status,
 		liveReload: lr,
 		watcher:    watcher,
 		stopOnce:   &sync.Once{},
@@ -59,10 +64,21 @@ func (f *FSWatcher) Start() (mErr error) {
 				// Intellij temp file
 				break
 			}
-			// Ignore everything except writes.
-			if event.Op&fsnotify.Write != fsnotify.Write {
+			// Writes and creates change a file. Removes and renames delete the
+			// old name, so the builder removes the pages of deleted markdown files.
+			// Ignore chmod.
+			if !event.Has(fsnotify.Write | fsnotify.Create | fsnotify.Remove | fsnotify.Rename) {
 				break
 			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// Watch new dirs, like the dir of a new post.
+					if err := f.AddRecursively(event.Name); err != nil {
+						slog.Error("watch new dir", "dir", event.Name, "error", err)
+					}
+					break
+				}
+			}
 
 			rel, err := filepath.Rel(rootDir, event.Name)
 			if err != nil {
@@ -70,6 +86,14 @@ func (f *FSWatcher) Start() (mErr error) {
 				break
 			}
 
+			// Pages include Go files in code blocks, like
+			// pkg/errs/testdata/capture.go, so rebuild the dependent pages before
+			// handling Go source below.
+			if filepath.Ext(rel) == ".go" && f.builder.HasDependents(event.Name) {
+				f.compileReloadMd(event.Name)
+				f.liveReload.ReloadFile("")
+			}
+
 			switch {
 			case rel == "style/main.css":
 				f.reloadMainCSS()
@@ -83,19 +107,20 @@ func (f *FSWatcher) Start() (mErr error) {
 				f.liveReload.ReloadFile("")
 
 			case filepath.Ext(rel) == ".md":
-				if err := f.compileReloadMd(); err != nil {
-					return fmt.Errorf("failed to compiled changed markdown: %w", err)
-				}
+				f.compileReloadMd(event.Name)
 				f.liveReload.ReloadFile(event.Name)
 
-			case strings.HasPrefix(rel, "pkg/markdown/html"):
-				err := f.compileReloadMd()
-				if err != nil {
-					return fmt.Errorf("compile markdown for changed file %s: %w", rel, err)
-				}
+			case strings.HasPrefix(rel, "pkg/markdown/html"),
+				filepath.Ext(rel) == ".bib",
+				strings.HasPrefix(rel, dirs.Posts+"/"),
+				strings.HasPrefix(rel, dirs.TIL+"/"),
+				strings.HasPrefix(rel, dirs.Book+"/"):
+				// Templates, bib files, and post assets. The builder ignores files
+				// that no page depends on.
+				f.compileReloadMd(event.Name)
 				f.liveReload.ReloadFile("")
 
-			case strings.HasPrefix(rel, "pkg/markdown/"):
+			case strings.HasPrefix(rel, "pkg/markdown/") && filepath.Ext(rel) == ".go":
 				// Skip recompiling since we don't have server hot-reload enabled.
 
 			case filepath.Ext(rel) == ".go" && !strings.HasSuffix(rel, "_test.go"):
@@ -103,6 +128,12 @@ func (f *FSWatcher) Start() (mErr error) {
 				if err := f.rebuildServer(); err != nil {
 					slog.Error("rebuild server", "error", err)
 				}
+
+			case filepath.Ext(rel) != ".go":
+				// Other files, like non-Go source files included in code blocks.
+				// The builder ignores files that no page depends on.
+				f.compileReloadMd(event.Name)
+				f.liveReload.ReloadFile("")
 			}
 		case err := <-f.watcher.Errors:
 			slog.Info("error", "error", err)
@@ -140,11 +171,21 @@ func (f *FSWatcher) watchDirs(dirs ...string) error {
 	return nil
 }
 
-func (f *FSWatcher) compileReloadMd() error {
-	if err := sites.Rebuild(f.distDir); err != nil {
-		return fmt.Errorf("rebuild for changed md: %w", err)
+// compileReloadMd rebuilds the pages affected by the changed file. Records
+// build errors, like a typo in frontmatter, in the build status instead of
+// stopping the watcher.
+func (f *FSWatcher) compileReloadMd(changed string) {
+	rebuild := func() error { return f.builder.RebuildChanged(changed) }
+	if f.status.get() != nil {
+		// The last build failed, so other pages might be missing. Only clear the
+		// error once the whole site builds.
+		rebuild = f.builder.Rebuild
 	}
-	return nil
+	if err := rebuild(); err != nil {
+		f.status.set(fmt.Errorf("rebuild for changed file %s: %w", changed, err))
+		return
+	}
+	f.status.set(nil)
 }
 
 func (f *FSWatcher) reloadMainCSS() {
@@ -157,6 +198,15 @@ func (f *FSWatcher) reloadMainCSS() {
 	}
 }
 
+// watchFiles watches the files directly in dir without watching
+// subdirectories.
+func (f *FSWatcher) watchFiles(dir string) error {
+	if err := f.watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch dir files: %w", err)
+	}
+	return nil
+}
+
 func (f *FSWatcher) AddRecursively(name string) error {
 	walk := func(path string, info os.FileInfo, err error) error {
 		if err != nil {
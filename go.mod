@@ -14,6 +14,7 @@ require (
 	github.com/karrick/godirwalk v1.17.0
 	github.com/yuin/goldmark v1.7.8
 	go.uber.org/atomic v1.11.0
+	golang.org/x/image v0.23.0
 	golang.org/x/net v0.33.0
 	golang.org/x/oauth2 v0.24.0
 	golang.org/x/sync v0.10.0
@@ -1,13 +1,49 @@
 package cite
 
-import "github.com/jschaf/bibtex"
+import (
+	"fmt"
+	"strings"
 
+	"github.com/jschaf/bibtex"
+)
+
+// Style is a citation style that determines how in-text citations and
+// references look. Posts choose a style with the cite_style TOML key.
 type Style string
 
 const (
+	// IEEE numbers citations by appearance, like "[1]".
 	IEEE Style = "IEEE"
+	// ACM numbers citations by appearance like IEEE but formats references
+	// with the ACM Reference Format.
+	ACM Style = "ACM"
+	// APA is the author-date style of the American Psychological Association,
+	// like "(Pelkonen et al., 2015)".
+	APA Style = "APA"
+	// Chicago is the author-date style of the Chicago Manual of Style, like
+	// "(Pelkonen et al. 2015)".
+	Chicago Style = "Chicago"
 )
 
+// Styles are all supported citation styles.
+var Styles = []Style{IEEE, ACM, APA, Chicago}
+
+// ParseStyle returns the style named s, ignoring case.
+func ParseStyle(s string) (Style, error) {
+	for _, style := range Styles {
+		if strings.EqualFold(s, string(style)) {
+			return style, nil
+		}
+	}
+	return "", fmt.Errorf("unknown cite style %q; want one of %v", s, Styles)
+}
+
+// IsAuthorDate returns true if the style cites by author and year instead of
+// by number.
+func (s Style) IsAuthorDate() bool {
+	return s == APA || s == Chicago
+}
+
 // Biber controls parsing, resolving, and rendering of bibtex used in side notes
 // and end notes.
 var Biber = bibtex.New(
@@ -0,0 +1,270 @@
+package cite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/jsc/pkg/errs"
+)
+
+// WriteBibTeX writes the bibtex source of the entries with keys, in order of
+// keys, as declared in the bibtex files at bibPaths. Preserves the source so
+// readers get the same entries we cite, including TeX markup.
+func WriteBibTeX(w io.Writer, bibPaths []string, keys []bibtex.CiteKey) error {
+	sources := make(map[bibtex.CiteKey][]byte, len(keys))
+	for _, bibPath := range bibPaths {
+		src, err := os.ReadFile(bibPath)
+		if err != nil {
+			return fmt.Errorf("read bibtex file: %w", err)
+		}
+		f, err := Biber.Parse(bytes.NewReader(src))
+		if err != nil {
+			return fmt.Errorf("parse bibtex file %s: %w", bibPath, err)
+		}
+		for _, decl := range f.Entries {
+			if d, ok := decl.(*ast.BibDecl); ok {
+				// Positions are 1-based offsets since each parse uses a new file
+				// set.
+				sources[d.Key.Name] = src[d.Entry-1 : d.RBrace]
+			}
+		}
+	}
+	for i, key := range keys {
+		src, ok := sources[key]
+		if !ok {
+			return fmt.Errorf("no bibtex entry for key %s", key)
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return fmt.Errorf("write bibtex: %w", err)
+			}
+		}
+		if _, err := w.Write(append(src, '\n')); err != nil {
+			return fmt.Errorf("write bibtex: %w", err)
+		}
+	}
+	return nil
+}
+
+// cslItem is a single item in CSL-JSON, the bibliography format used by
+// citation processors like citeproc and reference managers like Zotero.
+// See https://citeproc-js.readthedocs.io/en/latest/csl-json/markup.html.
+type cslItem struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title,omitempty"`
+	Author         []cslName `json:"author,omitempty"`
+	ContainerTitle string    `json:"container-title,omitempty"`
+	Volume         string    `json:"volume,omitempty"`
+	Issue          string    `json:"issue,omitempty"`
+	Number         string    `json:"number,omitempty"`
+	Page           string    `json:"page,omitempty"`
+	Publisher      string    `json:"publisher,omitempty"`
+	PublisherPlace string    `json:"publisher-place,omitempty"`
+	DOI            string    `json:"DOI,omitempty"`
+	URL            string    `json:"URL,omitempty"`
+	ISBN           string    `json:"ISBN,omitempty"`
+	ISSN           string    `json:"ISSN,omitempty"`
+	Issued         *cslDate  `json:"issued,omitempty"`
+}
+
+// cslName is a person or, for literal names, an organization.
+type cslName struct {
+	Family              string `json:"family,omitempty"`
+	Given               string `json:"given,omitempty"`
+	NonDroppingParticle string `json:"non-dropping-particle,omitempty"`
+	Suffix              string `json:"suffix,omitempty"`
+	Literal             string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// cslTypes maps bibtex entry types to CSL types. Unlisted types map to
+// "document".
+var cslTypes = map[bibtex.EntryType]string{
+	bibtex.EntryArticle:       "article-journal",
+	bibtex.EntryBook:          "book",
+	bibtex.EntryBooklet:       "pamphlet",
+	bibtex.EntryInBook:        "chapter",
+	bibtex.EntryInCollection:  "chapter",
+	bibtex.EntryInProceedings: "paper-conference",
+	bibtex.EntryManual:        "report",
+	bibtex.EntryMastersThesis: "thesis",
+	bibtex.EntryPhDThesis:     "thesis",
+	bibtex.EntryProceedings:   "book",
+	bibtex.EntryTechReport:    "report",
+	bibtex.EntryUnpublished:   "manuscript",
+	"conference":              "paper-conference",
+	"online":                  "webpage",
+}
+
+// WriteCSLJSON writes the resolved entries, in order, as a CSL-JSON array.
+func WriteCSLJSON(w io.Writer, entries []bibtex.Entry) error {
+	items := make([]cslItem, len(entries))
+	for i, e := range entries {
+		items[i] = newCSLItem(e)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(items); err != nil {
+		return fmt.Errorf("encode CSL-JSON: %w", err)
+	}
+	return nil
+}
+
+func newCSLItem(e bibtex.Entry) cslItem {
+	field := func(f bibtex.Field) string {
+		return exprText(e.Tags[f])
+	}
+	typ, ok := cslTypes[e.Type]
+	if !ok {
+		typ = "document"
+	}
+	item := cslItem{
+		ID:        e.Key,
+		Type:      typ,
+		Title:     field(bibtex.FieldTitle),
+		Author:    cslNames(e.Tags[bibtex.FieldAuthor]),
+		Volume:    field(bibtex.FieldVolume),
+		Page:      strings.Replace(field(bibtex.FieldPages), "--", "-", 1),
+		Publisher: field(bibtex.FieldPublisher),
+		DOI:       field("doi"),
+		URL:       field("url"),
+		ISBN:      field("isbn"),
+		ISSN:      field("issn"),
+		Issued:    cslIssued(field(bibtex.FieldYear), field(bibtex.FieldMonth)),
+	}
+	item.PublisherPlace = field(bibtex.FieldAddress)
+	switch e.Type {
+	case bibtex.EntryArticle:
+		item.ContainerTitle = field(bibtex.FieldJournal)
+		item.Issue = field(bibtex.FieldNumber)
+	case bibtex.EntryInProceedings, bibtex.EntryInCollection, bibtex.EntryInBook, "conference":
+		item.ContainerTitle = field(bibtex.FieldBookTitle)
+	case bibtex.EntryTechReport:
+		item.Number = field(bibtex.FieldNumber)
+		if item.Publisher == "" {
+			item.Publisher = field(bibtex.FieldInstitution)
+		}
+	case bibtex.EntryMastersThesis, bibtex.EntryPhDThesis:
+		if item.Publisher == "" {
+			item.Publisher = field(bibtex.FieldSchool)
+		}
+	}
+	return item
+}
+
+// cslNames converts resolved bibtex authors into CSL names. Authors without a
+// given name, like "{First Round Review}", are organizations.
+func cslNames(x ast.Expr) []cslName {
+	authors, ok := x.(ast.Authors)
+	if !ok {
+		return nil
+	}
+	names := make([]cslName, 0, len(authors))
+	for _, a := range authors {
+		if a.IsOthers() {
+			continue
+		}
+		given := exprText(a.First)
+		if given == "" {
+			names = append(names, cslName{Literal: exprText(a.Last)})
+			continue
+		}
+		names = append(names, cslName{
+			Family:              exprText(a.Last),
+			Given:               given,
+			NonDroppingParticle: exprText(a.Prefix),
+			Suffix:              exprText(a.Suffix),
+		})
+	}
+	return names
+}
+
+// cslIssued returns the CSL date for the year and optional month, or nil if
+// the year isn't a number.
+func cslIssued(year, month string) *cslDate {
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return nil
+	}
+	parts := []int{y}
+	if m := parseMonth(month); m > 0 {
+		parts = append(parts, m)
+	}
+	return &cslDate{DateParts: [][]int{parts}}
+}
+
+var monthPrefixes = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+// parseMonth returns the 1-based month for a bibtex month, like "8", "aug", or
+// "August", or 0 if unknown.
+func parseMonth(s string) int {
+	if m, err := strconv.Atoi(s); err == nil {
+		if m >= 1 && m <= 12 {
+			return m
+		}
+		return 0
+	}
+	s = strings.ToLower(s)
+	for i, prefix := range monthPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// exprText returns the text of a resolved bibtex expression or the empty
+// string if x is nil or not text.
+func exprText(x ast.Expr) string {
+	switch t := x.(type) {
+	case *ast.Text:
+		return t.Value
+	case *ast.Number:
+		return t.Value
+	case *ast.Ident:
+		return t.Name
+	default:
+		return ""
+	}
+}
+
+// ExportBibTeX writes the bibtex file at dest with the entries for keys. See
+// WriteBibTeX.
+func ExportBibTeX(dest string, bibPaths []string, keys []bibtex.CiteKey) error {
+	return writeFile(dest, func(w io.Writer) error {
+		return WriteBibTeX(w, bibPaths, keys)
+	})
+}
+
+// ExportCSLJSON writes the CSL-JSON file at dest with the entries. See
+// WriteCSLJSON.
+func ExportCSLJSON(dest string, entries []bibtex.Entry) error {
+	return writeFile(dest, func(w io.Writer) error {
+		return WriteCSLJSON(w, entries)
+	})
+}
+
+func writeFile(dest string, write func(io.Writer) error) (mErr error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("make dir for %s: %w", dest, err)
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("open %s for write: %w", dest, err)
+	}
+	defer errs.Capture(&mErr, f.Close, "close "+dest)
+	return write(f)
+}
@@ -0,0 +1,139 @@
+package cite
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex"
+)
+
+const testBib = `@article{foo,
+  author = "Fred Q. Blogs and {ACME Corp}",
+  title = "Turtles in the {TIME} continuum",
+  journal = "Nature",
+  year = 2016,
+  month = aug,
+  pages = "12--14",
+}
+
+@book{bar,
+    title={Concurrent Programming},
+    author={Lea, Douglas},
+    year={2000},
+}
+
+@misc{unused, title = "Unused"}
+`
+
+func writeTestBib(t *testing.T) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "refs.bib")
+	if err := os.WriteFile(p, []byte(testBib), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestWriteBibTeX(t *testing.T) {
+	bibPath := writeTestBib(t)
+	sb := &strings.Builder{}
+	if err := WriteBibTeX(sb, []string{bibPath}, []bibtex.CiteKey{"bar", "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `@book{bar,
+    title={Concurrent Programming},
+    author={Lea, Douglas},
+    year={2000},
+}
+
+@article{foo,
+  author = "Fred Q. Blogs and {ACME Corp}",
+  title = "Turtles in the {TIME} continuum",
+  journal = "Nature",
+  year = 2016,
+  month = aug,
+  pages = "12--14",
+}
+`
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("WriteBibTeX mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteBibTeX_MissingKey(t *testing.T) {
+	bibPath := writeTestBib(t)
+	err := WriteBibTeX(&strings.Builder{}, []string{bibPath}, []bibtex.CiteKey{"missing"})
+	if err == nil {
+		t.Fatal("WriteBibTeX with missing key: want error, got nil")
+	}
+}
+
+func TestWriteCSLJSON(t *testing.T) {
+	f, err := Biber.Parse(strings.NewReader(testBib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := Biber.Resolve(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKey := make(map[bibtex.CiteKey]bibtex.Entry, len(resolved))
+	for _, e := range resolved {
+		byKey[e.Key] = e
+	}
+	sb := &strings.Builder{}
+	if err := WriteCSLJSON(sb, []bibtex.Entry{byKey["foo"], byKey["bar"]}); err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "id": "foo",
+    "type": "article-journal",
+    "title": "Turtles in the TIME continuum",
+    "author": [
+      {
+        "family": "Blogs",
+        "given": "Fred Q."
+      },
+      {
+        "literal": "ACME Corp"
+      }
+    ],
+    "container-title": "Nature",
+    "page": "12-14",
+    "issued": {
+      "date-parts": [
+        [
+          2016,
+          8
+        ]
+      ]
+    }
+  },
+  {
+    "id": "bar",
+    "type": "book",
+    "title": "Concurrent Programming",
+    "author": [
+      {
+        "family": "Lea",
+        "given": "Douglas"
+      }
+    ],
+    "issued": {
+      "date-parts": [
+        [
+          2000
+        ]
+      ]
+    }
+  }
+]
+`
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("WriteCSLJSON mismatch (-want +got):\n%s", diff)
+	}
+}
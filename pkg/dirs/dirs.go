@@ -20,6 +20,9 @@ const (
 	Style  = "style"
 	TIL    = "til"
 	Dist   = "dist"
+	// DistPreview is the output dir for builds that include unpublished
+	// posts. Separate from Dist so drafts are never deployed.
+	DistPreview = "dist-preview"
 )
 
 // RemoveAllChildren removes all children in the directory.
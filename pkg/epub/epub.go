@@ -0,0 +1,240 @@
+// Package epub writes EPUB 3 books for reading the site offline on
+// e-readers. The compiler renders each chapter as HTML and converts it to
+// XHTML with ToXHTML.
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	_ "embed"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	mimetype = "application/epub+zip"
+	// The dir of the package document and all content in the container.
+	contentDir = "OEBPS"
+	navName    = "nav.xhtml"
+	styleName  = "style.css"
+)
+
+//go:embed style.css
+var style string
+
+// Book is an EPUB publication.
+type Book struct {
+	// A unique identifier of the book, like the URL of the book on the site.
+	ID       string
+	Title    string
+	Author   string
+	Language string
+	// The last modification date of the book. Also used as the modification
+	// time of the files in the container so the same book produces the same
+	// bytes.
+	Modified time.Time
+	// The chapters in reading order.
+	Chapters []Chapter
+	// The images and other files referenced by chapters.
+	Resources []Resource
+	// The name of the cover image in Resources. Empty for no cover.
+	Cover string
+}
+
+// Chapter is an XHTML content document of the book.
+type Chapter struct {
+	// The file name in the book, like "chapter-01.xhtml".
+	Name  string
+	Title string
+	// The XHTML content of the body element, typically from ToXHTML.
+	Body []byte
+}
+
+// Resource is a file referenced by chapters, like an image.
+type Resource struct {
+	// The path in the book, like "images/foo.png".
+	Name string
+	Data []byte
+}
+
+// Write writes the book as an EPUB container.
+func (b *Book) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	// The mimetype must be the first file in the container and uncompressed.
+	if err := b.writeFile(zw, "mimetype", zip.Store, []byte(mimetype)); err != nil {
+		return err
+	}
+	if err := b.writeFile(zw, "META-INF/container.xml", zip.Deflate, []byte(containerXML)); err != nil {
+		return err
+	}
+	opf, err := b.packageDoc()
+	if err != nil {
+		return err
+	}
+	files := []Resource{
+		{Name: "content.opf", Data: opf},
+		{Name: navName, Data: b.navDoc()},
+		{Name: styleName, Data: []byte(style)},
+	}
+	for _, ch := range b.Chapters {
+		files = append(files, Resource{Name: ch.Name, Data: contentDoc(ch.Title, ch.Body)})
+	}
+	files = append(files, b.Resources...)
+	for _, f := range files {
+		if err := b.writeFile(zw, path.Join(contentDir, f.Name), zip.Deflate, f.Data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close epub: %w", err)
+	}
+	return nil
+}
+
+func (b *Book) writeFile(zw *zip.Writer, name string, method uint16, data []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: b.Modified.UTC()})
+	if err != nil {
+		return fmt.Errorf("create epub file %s: %w", name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("write epub file %s: %w", name, err)
+	}
+	return nil
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="` + contentDir + `/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// packageDoc returns the package document, content.opf, listing the metadata,
+// every file in the book, and the reading order.
+func (b *Book) packageDoc() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="pub-id" xml:lang="` + escape(b.Language) + `">` + "\n")
+	buf.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	buf.WriteString(`    <dc:identifier id="pub-id">` + escape(b.ID) + "</dc:identifier>\n")
+	buf.WriteString(`    <dc:title>` + escape(b.Title) + "</dc:title>\n")
+	buf.WriteString(`    <dc:creator>` + escape(b.Author) + "</dc:creator>\n")
+	buf.WriteString(`    <dc:language>` + escape(b.Language) + "</dc:language>\n")
+	buf.WriteString(`    <meta property="dcterms:modified">` + b.Modified.UTC().Format("2006-01-02T15:04:05Z") + "</meta>\n")
+	if b.Cover != "" {
+		buf.WriteString(`    <meta name="cover" content="` + itemID(b.Cover) + `"/>` + "\n")
+	}
+	buf.WriteString("  </metadata>\n  <manifest>\n")
+	writeItem := func(name, props string) error {
+		mt := MediaType(name)
+		if mt == "" {
+			return fmt.Errorf("unknown media type of epub file %s", name)
+		}
+		buf.WriteString(`    <item id="` + itemID(name) + `" href="` + escape(name) + `" media-type="` + mt + `"`)
+		if props != "" {
+			buf.WriteString(` properties="` + props + `"`)
+		}
+		buf.WriteString("/>\n")
+		return nil
+	}
+	if err := writeItem(navName, "nav"); err != nil {
+		return nil, err
+	}
+	if err := writeItem(styleName, ""); err != nil {
+		return nil, err
+	}
+	for _, ch := range b.Chapters {
+		props := ""
+		if bytes.Contains(ch.Body, []byte("<math")) {
+			props = "mathml"
+		}
+		if err := writeItem(ch.Name, props); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range b.Resources {
+		props := ""
+		if r.Name == b.Cover {
+			props = "cover-image"
+		}
+		if err := writeItem(r.Name, props); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteString("  </manifest>\n  <spine>\n")
+	for _, ch := range b.Chapters {
+		buf.WriteString(`    <itemref idref="` + itemID(ch.Name) + `"/>` + "\n")
+	}
+	buf.WriteString("  </spine>\n</package>\n")
+	return buf.Bytes(), nil
+}
+
+// navDoc returns the navigation document with the table of contents.
+func (b *Book) navDoc() []byte {
+	body := &bytes.Buffer{}
+	body.WriteString(`<nav epub:type="toc" id="toc"><h1>Contents</h1><ol>`)
+	for _, ch := range b.Chapters {
+		body.WriteString(`<li><a href="` + escape(ch.Name) + `">` + escape(ch.Title) + `</a></li>`)
+	}
+	body.WriteString(`</ol></nav>`)
+	return contentDoc(b.Title, body.Bytes())
+}
+
+// contentDoc wraps the XHTML body content in an XHTML content document.
+func contentDoc(title string, body []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString("<!DOCTYPE html>\n")
+	buf.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">` + "\n")
+	buf.WriteString("<head>\n")
+	buf.WriteString(`<meta charset="utf-8"/>` + "\n")
+	buf.WriteString("<title>" + escape(title) + "</title>\n")
+	buf.WriteString(`<link rel="stylesheet" type="text/css" href="` + styleName + `"/>` + "\n")
+	buf.WriteString("</head>\n<body>\n")
+	buf.Write(body)
+	buf.WriteString("\n</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+// MediaType returns the media type of a file in the book based on the
+// extension, or the empty string for unsupported files.
+func MediaType(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".xhtml":
+		return "application/xhtml+xml"
+	case ".css":
+		return "text/css"
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".svg":
+		return "image/svg+xml"
+	default:
+		return ""
+	}
+}
+
+// itemID returns the manifest ID of the file. IDs must be XML names, so
+// replace everything but letters and digits.
+func itemID(name string) string {
+	return "item-" + strings.Map(func(r rune) rune {
+		if ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+func escape(s string) string {
+	b := &strings.Builder{}
+	_ = xml.EscapeText(b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBook_Write(t *testing.T) {
+	book := &Book{
+		ID:       "https://example.com/foo/",
+		Title:    "Foo & bar",
+		Author:   "Alice",
+		Language: "en",
+		Modified: time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC),
+		Chapters: []Chapter{
+			{Name: "chapter-01.xhtml", Title: "One", Body: []byte(`<p>one</p>`)},
+			{Name: "chapter-02.xhtml", Title: "Two", Body: []byte(`<math xmlns="http://www.w3.org/1998/Math/MathML"></math>`)},
+		},
+		Resources: []Resource{{Name: "cover.png", Data: []byte("png")}},
+		Cover:     "cover.png",
+	}
+	b := &bytes.Buffer{}
+	if err := book.Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	wantNames := []string{
+		"mimetype",
+		"META-INF/container.xml",
+		"OEBPS/content.opf",
+		"OEBPS/nav.xhtml",
+		"OEBPS/style.css",
+		"OEBPS/chapter-01.xhtml",
+		"OEBPS/chapter-02.xhtml",
+		"OEBPS/cover.png",
+	}
+	if diff := cmp.Diff(wantNames, names); diff != "" {
+		t.Fatalf("epub files mismatch (-want +got):\n%s", diff)
+	}
+	if zr.File[0].Method != zip.Store || files["mimetype"] != mimetype {
+		t.Errorf("mimetype must be stored uncompressed with content %q; got method %d, content %q",
+			mimetype, zr.File[0].Method, files["mimetype"])
+	}
+
+	for _, want := range []string{
+		`<dc:title>Foo &amp; bar</dc:title>`,
+		`<meta property="dcterms:modified">2021-03-04T00:00:00Z</meta>`,
+		`<item id="item-chapter-01-xhtml" href="chapter-01.xhtml" media-type="application/xhtml+xml"/>`,
+		`<item id="item-chapter-02-xhtml" href="chapter-02.xhtml" media-type="application/xhtml+xml" properties="mathml"/>`,
+		`<item id="item-cover-png" href="cover.png" media-type="image/png" properties="cover-image"/>`,
+		`<itemref idref="item-chapter-01-xhtml"/>`,
+	} {
+		if !strings.Contains(files["OEBPS/content.opf"], want) {
+			t.Errorf("content.opf doesn't include %q:\n\n%s", want, files["OEBPS/content.opf"])
+		}
+	}
+	if want := `<li><a href="chapter-02.xhtml">Two</a></li>`; !strings.Contains(files["OEBPS/nav.xhtml"], want) {
+		t.Errorf("nav.xhtml doesn't include %q:\n\n%s", want, files["OEBPS/nav.xhtml"])
+	}
+}
+
+func TestToXHTML(t *testing.T) {
+	opts := XHTMLOptions{
+		ResolveLink: func(href string) string { return "https://example.com" + href },
+		ResolveImage: func(src string) (string, bool) {
+			if strings.HasPrefix(src, "http") {
+				return "", false
+			}
+			return "images/" + src, true
+		},
+	}
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"void elements", `<p>a<br>b</p>`, `<p>a<br/>b</p>`},
+		{
+			"katex to mathml",
+			`<span class="katex"><span class="katex-mathml"><math xmlns="http://www.w3.org/1998/Math/MathML"><mi>x</mi></math></span><span class="katex-html">x</span></span>`,
+			`<math xmlns="http://www.w3.org/1998/Math/MathML"><mi>x</mi></math>`,
+		},
+		{"custom element", `<pre><code-kw class="foo">func</code-kw></pre>`, `<pre><span class="code-kw foo">func</span></pre>`},
+		{"drop button", `<p>a<button>copy</button></p>`, `<p>a</p>`},
+		{"drop data attrs", `<a href="/foo" data-preview-snippet="x">foo</a>`, `<a href="https://example.com/foo">foo</a>`},
+		{"resolve image", `<img src="foo.png" srcset="foo-2x.png 2x" alt="foo">`, `<img src="images/foo.png" alt="foo"/>`},
+		{"drop remote image", `<p><img src="https://example.com/foo.png"></p>`, `<p></p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToXHTML([]byte(tt.src), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("ToXHTML() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
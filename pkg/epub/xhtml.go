@@ -0,0 +1,167 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// XHTMLOptions configures how ToXHTML rewrites URLs.
+type XHTMLOptions struct {
+	// ResolveLink returns the href in the book for an href on the site. Nil
+	// keeps links unchanged.
+	ResolveLink func(href string) string
+	// ResolveImage returns the src in the book for an image src on the site.
+	// Returns false to drop the image. Nil keeps images unchanged.
+	ResolveImage func(src string) (string, bool)
+}
+
+// droppedElems are interactive or scripted elements that don't work in
+// e-readers.
+var droppedElems = map[atom.Atom]bool{
+	atom.Button:   true,
+	atom.Form:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Noscript: true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Template: true,
+}
+
+// droppedAttrs are attributes for the site scripts or responsive images,
+// which e-readers don't need.
+var droppedAttrs = map[string]bool{
+	"srcset":   true,
+	"sizes":    true,
+	"loading":  true,
+	"decoding": true,
+}
+
+// ToXHTML converts an HTML fragment rendered for the site into well-formed
+// XHTML for the body of an EPUB content document:
+//
+//   - KaTeX math becomes the MathML that KaTeX renders alongside the HTML.
+//   - Custom elements, like <code-kw> from code highlighting, become spans
+//     with the element name as the class.
+//   - Scripts, buttons, and forms are removed, as are data attributes.
+func ToXHTML(fragment []byte, opts XHTMLOptions) ([]byte, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(bytes.NewReader(fragment), body)
+	if err != nil {
+		return nil, fmt.Errorf("parse html fragment: %w", err)
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	rewrite(body, opts)
+	buf := &bytes.Buffer{}
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(buf, c); err != nil {
+			return nil, fmt.Errorf("render xhtml: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// rewrite rewrites the children of n in place.
+func rewrite(n *html.Node, opts XHTMLOptions) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type != html.ElementNode || c.Namespace != "" {
+			c = next
+			continue
+		}
+		switch {
+		case droppedElems[c.DataAtom]:
+			n.RemoveChild(c)
+			c = next
+			continue
+		case hasClass(c, "katex"):
+			if m := findMath(c); m != nil {
+				m.Parent.RemoveChild(m)
+				n.InsertBefore(m, c)
+			}
+			n.RemoveChild(c)
+			c = next
+			continue
+		case c.DataAtom == atom.Img && opts.ResolveImage != nil:
+			src, ok := opts.ResolveImage(attr(c, "src"))
+			if !ok {
+				n.RemoveChild(c)
+				c = next
+				continue
+			}
+			setAttr(c, "src", src)
+		case c.DataAtom == atom.A && opts.ResolveLink != nil:
+			if href := attr(c, "href"); href != "" {
+				setAttr(c, "href", opts.ResolveLink(href))
+			}
+		case c.DataAtom == 0 && strings.Contains(c.Data, "-"):
+			addClass(c, c.Data)
+			c.Data, c.DataAtom = "span", atom.Span
+		}
+		attrs := c.Attr[:0]
+		for _, a := range c.Attr {
+			if droppedAttrs[a.Key] || strings.HasPrefix(a.Key, "data-") {
+				continue
+			}
+			attrs = append(attrs, a)
+		}
+		c.Attr = attrs
+		rewrite(c, opts)
+		c = next
+	}
+}
+
+// findMath returns the first MathML math element below n.
+func findMath(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Namespace == "math" && c.Data == "math" {
+			return c
+		}
+		if m := findMath(c); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func addClass(n *html.Node, class string) {
+	if cur := attr(n, "class"); cur != "" {
+		setAttr(n, "class", class+" "+cur)
+		return
+	}
+	setAttr(n, "class", class)
+}
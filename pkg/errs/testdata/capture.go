@@ -0,0 +1,25 @@
+// The version of errs.Capture shown in the post
+// posts/capture-deferred-errors-in-go.md, without the details of the errs
+// package doc comment.
+
+// region Capture
+
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Capture runs errFunc and assigns the error, if any, to *errPtr.
+// Preserves the original error by wrapping with errors.Join if
+// errFunc returns a non-nil error.
+func Capture(errPtr *error, errFunc func() error, msg string) {
+	err := errFunc()
+	if err == nil {
+		return
+	}
+	*errPtr = errors.Join(*errPtr, fmt.Errorf("%s: %w", msg, err))
+}
+
+// endregion
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Commit is a commit that touched a file.
+type Commit struct {
+	Hash    string
+	Subject string
+	// The author date.
+	Date time.Time
+	// The number of lines added to and deleted from the file. Zero for binary
+	// files and pure renames.
+	Added, Deleted int
+}
+
+// ShortHash returns the abbreviated commit hash.
+func (c Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// Separators of the log output, written by the %x1e and %x00 placeholders of
+// the log format. Commit subjects can't contain either.
+const (
+	recordSep = "\x1e"
+	fieldSep  = "\x00"
+)
+
+// FileLog returns the commits that touched the file at path, newest first,
+// following renames. Returns no commits for untracked files.
+func FileLog(path string) ([]Commit, error) {
+	cmd := exec.Command("git", "log", "--follow", "--numstat",
+		"--format=%x1e%H%x00%aI%x00%s", "--", path)
+	cmd.Dir = RootDir()
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: %w\n%s", path, err, stderr.String())
+	}
+	return parseLog(string(out))
+}
+
+// isShallow caches IsShallow since every post checks it on every build. A
+// running dev server needs a restart to notice git fetch --unshallow.
+var isShallow = sync.OnceValues(func() (bool, error) {
+	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository")
+	cmd.Dir = RootDir()
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("git rev-parse --is-shallow-repository: %w\n%s", err, stderr.String())
+	}
+	return strings.TrimSpace(string(out)) == "true", nil
+})
+
+// IsShallow returns true if the repo is a shallow clone, like the default
+// checkout in CI, so the log is missing older commits. Only runs git once per
+// process.
+func IsShallow() (bool, error) {
+	return isShallow()
+}
+
+// parseLog parses the output of git log using the format of FileLog.
+func parseLog(out string) ([]Commit, error) {
+	var commits []Commit
+	for _, record := range strings.Split(out, recordSep) {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		header, stats, _ := strings.Cut(record, "\n")
+		fields := strings.Split(header, fieldSep)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("parse git log header %q: want 3 fields, got %d", header, len(fields))
+		}
+		date, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("parse git log date of %s: %w", fields[0], err)
+		}
+		c := Commit{Hash: fields[0], Date: date, Subject: fields[2]}
+		// Each numstat line is "added\tdeleted\tpath". Binary files use "-".
+		for _, line := range strings.Split(stats, "\n") {
+			parts := strings.SplitN(line, "\t", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			added, _ := strconv.Atoi(parts[0])
+			deleted, _ := strconv.Atoi(parts[1])
+			c.Added += added
+			c.Deleted += deleted
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
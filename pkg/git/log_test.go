@@ -0,0 +1,36 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseLog(t *testing.T) {
+	out := "\x1eaaa\x002022-05-06T12:00:00+02:00\x00Fix typo\n\n1\t2\tposts/foo.md\n" +
+		"\x1ebbb\x002021-03-04T00:00:00Z\x00Rename foo\n\n0\t0\t{old => posts}/foo.md\n-\t-\tposts/foo.png\n" +
+		"\x1eccc\x002021-03-01T00:00:00Z\x00Add foo\n\n20\t0\told/foo.md\n"
+	got, err := parseLog(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Commit{
+		{Hash: "aaa", Subject: "Fix typo", Date: time.Date(2022, time.May, 6, 10, 0, 0, 0, time.UTC), Added: 1, Deleted: 2},
+		{Hash: "bbb", Subject: "Rename foo", Date: time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{Hash: "ccc", Subject: "Add foo", Date: time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC), Added: 20},
+	}
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+		t.Errorf("parseLog mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseLog_Empty(t *testing.T) {
+	got, err := parseLog("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("parseLog of empty output = %v; want no commits", got)
+	}
+}
@@ -0,0 +1,409 @@
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/paths"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// The size in pixels of a social card, the size recommended by OpenGraph
+// consumers for large link previews.
+const (
+	CardWidth  = 1200
+	CardHeight = 630
+)
+
+// cardVersion is part of the cache key. Bump it when changing the layout so
+// cached cards are rendered again.
+const cardVersion = "1"
+
+const (
+	cardMargin = 80
+	// The title font size shrinks from the first size until the title fits in
+	// maxTitleLines.
+	maxTitleLines = 4
+	footerSize    = 32
+	// Small caps and superscripts are drawn with a scaled-down face.
+	smallCapsScale   = 0.8
+	superscriptScale = 0.6
+	// smallCapsMinRun is the shortest run of capitals drawn as small caps,
+	// matching the small caps markdown extension.
+	smallCapsMinRun = 3
+)
+
+var titleSizes = []float64{72, 64, 56, 48}
+
+var (
+	cardBackground = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	cardText       = color.RGBA{R: 0x1b, G: 0x1b, B: 0x1b, A: 0xff}
+	cardMuted      = color.RGBA{R: 0x68, G: 0x67, B: 0x67, A: 0xff}
+	cardRule       = color.RGBA{R: 0xea, G: 0xea, B: 0xea, A: 0xff}
+)
+
+// superscripts maps the superscript runes produced for math in titles to the
+// base rune. Mirrors toSuperscript in mdext/latex_unicode.go. The card fonts
+// lack most superscript glyphs, so superscripts are drawn as smaller, raised
+// base glyphs.
+var superscripts = func() map[rune]rune {
+	const (
+		sups  = "⁰¹²³⁴⁵⁶⁷⁸⁹ᵃᵇᶜᵈᵉᶠᵍʰⁱʲᵏˡᵐⁿᵒᵖʳˢᵗᵘᵛʷˣʸᶻ"
+		bases = "0123456789abcdefghijklmnoprstuvwxyz"
+	)
+	m := make(map[rune]rune, len(bases))
+	bs := []rune(bases)
+	for i, r := range []rune(sups) {
+		m[r] = bs[i]
+	}
+	return m
+}()
+
+// glyphFallbacks maps runes missing from the card fonts to a similar rune.
+var glyphFallbacks = map[rune]rune{
+	'‘': '\'',
+	'’': '\'',
+	'“': '"',
+	'”': '"',
+	'–': '-',
+	'—': '-',
+	'·': '.',
+}
+
+// Card is the social preview image of a page, shown by sites that unfurl
+// links to the page.
+type Card struct {
+	// The title of the page. Math should already be converted to Unicode.
+	Title string
+	// The publish date, omitted if zero.
+	Date time.Time
+	// The name of the site shown in the footer.
+	Site string
+}
+
+// Key returns a hash of the card content. Cards with the same key render the
+// same image.
+func (c Card) Key() string {
+	h := sha256.New()
+	for _, s := range []string{cardVersion, c.Title, c.dateText(), c.Site} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c Card) dateText() string {
+	if c.Date.IsZero() {
+		return ""
+	}
+	return c.Date.Format("January 2, 2006")
+}
+
+// RenderCached writes the card as a PNG to dest. Renders the card only if the
+// user cache dir doesn't already have a card with the same key, since
+// rendering every card on each build is slow.
+func (c Card) RenderCached(dest string) error {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return c.Render(dest)
+	}
+	cached := filepath.Join(cacheDir, "jsc", "social-cards", c.Key()+".png")
+	if _, err := os.Stat(cached); err != nil {
+		if err := c.Render(cached); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("make dir for social card: %w", err)
+	}
+	if err := paths.Copy(dest, cached); err != nil {
+		return fmt.Errorf("copy cached social card: %w", err)
+	}
+	return nil
+}
+
+// Render writes the card as a PNG to dest.
+func (c Card) Render(dest string) (mErr error) {
+	img, err := c.draw()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("make dir for social card: %w", err)
+	}
+	// Write to a temp file and rename so a concurrent build never copies a
+	// partial card from the cache.
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".social-card-*.png")
+	if err != nil {
+		return fmt.Errorf("create social card: %w", err)
+	}
+	defer func() {
+		if mErr != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if err := png.Encode(tmp, img); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("encode social card %s: %w", dest, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close social card: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("rename social card: %w", err)
+	}
+	return nil
+}
+
+func (c Card) draw() (*image.RGBA, error) {
+	fonts, err := loadCardFonts()
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, CardWidth, CardHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(cardBackground), image.Point{}, draw.Src)
+
+	// Title, wrapped to the widest size that fits.
+	maxWidth := fixed.I(CardWidth - 2*cardMargin)
+	var lines [][]cardWord
+	var size float64
+	var faces *cardFaces
+	for _, size = range titleSizes {
+		faces, err = newCardFaces(fonts.bold, size)
+		if err != nil {
+			return nil, err
+		}
+		lines = wrapWords(splitCardWords(c.Title, fonts.bold, faces), faces.space, maxWidth)
+		if len(lines) <= maxTitleLines {
+			break
+		}
+	}
+	lineHeight := int(size * 1.25)
+	y := cardMargin + int(size)
+	for _, line := range lines {
+		x := fixed.I(cardMargin)
+		for i, w := range line {
+			if i > 0 {
+				x += faces.space
+			}
+			x = w.draw(img, x, y)
+		}
+		y += lineHeight
+	}
+
+	// Footer with the date and site, separated from the title by a rule.
+	footerY := CardHeight - cardMargin
+	ruleY := footerY - footerSize - 24
+	draw.Draw(img, image.Rect(cardMargin, ruleY, CardWidth-cardMargin, ruleY+2), image.NewUniform(cardRule), image.Point{}, draw.Src)
+	footer, err := opentype.NewFace(fonts.regular, &opentype.FaceOptions{Size: footerSize, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("create social card footer face: %w", err)
+	}
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(cardMuted), Face: footer}
+	d.Dot = fixed.P(cardMargin, footerY)
+	d.DrawString(foldGlyphs(c.dateText(), fonts.regular))
+	site := foldGlyphs(c.Site, fonts.regular)
+	d.Dot = fixed.Point26_6{X: fixed.I(CardWidth-cardMargin) - d.MeasureString(site), Y: fixed.I(footerY)}
+	d.DrawString(site)
+	return img, nil
+}
+
+type cardFonts struct {
+	bold    *sfnt.Font
+	regular *sfnt.Font
+}
+
+// loadCardFonts parses the fonts shipped in the style dir once.
+var loadCardFonts = sync.OnceValues(func() (cardFonts, error) {
+	bold, err := parseFont("KaTeX_Main-Bold.ttf")
+	if err != nil {
+		return cardFonts{}, err
+	}
+	regular, err := parseFont("KaTeX_Main-Regular.ttf")
+	if err != nil {
+		return cardFonts{}, err
+	}
+	return cardFonts{bold: bold, regular: regular}, nil
+})
+
+func parseFont(name string) (*sfnt.Font, error) {
+	b, err := os.ReadFile(filepath.Join(git.RootDir(), dirs.Style, dirs.Fonts, name))
+	if err != nil {
+		return nil, fmt.Errorf("read font %s: %w", name, err)
+	}
+	f, err := opentype.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse font %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// cardFaces are the faces of a font at a size for each style of title text.
+type cardFaces struct {
+	size      float64
+	normal    font.Face
+	smallCaps font.Face
+	super     font.Face
+	space     fixed.Int26_6
+}
+
+func newCardFaces(f *sfnt.Font, size float64) (*cardFaces, error) {
+	newFace := func(s float64) (font.Face, error) {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: s, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, fmt.Errorf("create social card face: %w", err)
+		}
+		return face, nil
+	}
+	cf := &cardFaces{size: size}
+	var err error
+	if cf.normal, err = newFace(size); err != nil {
+		return nil, err
+	}
+	if cf.smallCaps, err = newFace(size * smallCapsScale); err != nil {
+		return nil, err
+	}
+	if cf.super, err = newFace(size * superscriptScale); err != nil {
+		return nil, err
+	}
+	cf.space = font.MeasureString(cf.normal, " ")
+	return cf, nil
+}
+
+// cardRun is text drawn with a single face.
+type cardRun struct {
+	text string
+	face font.Face
+	// Pixels to raise the baseline, for superscripts.
+	raise int
+}
+
+// cardWord is a word of the title, split into runs so that small caps and
+// superscripts inside the word use their own face.
+type cardWord struct {
+	runs  []cardRun
+	width fixed.Int26_6
+}
+
+func (w cardWord) draw(img draw.Image, x fixed.Int26_6, y int) fixed.Int26_6 {
+	for _, r := range w.runs {
+		d := &font.Drawer{Dst: img, Src: image.NewUniform(cardText), Face: r.face}
+		d.Dot = fixed.Point26_6{X: x, Y: fixed.I(y - r.raise)}
+		d.DrawString(r.text)
+		x = d.Dot.X
+	}
+	return x
+}
+
+// splitCardWords splits the title into words of runs.
+func splitCardWords(title string, f *sfnt.Font, faces *cardFaces) []cardWord {
+	var words []cardWord
+	for _, field := range strings.Fields(title) {
+		var w cardWord
+		add := func(text string, face font.Face, raise int) {
+			if text == "" {
+				return
+			}
+			w.runs = append(w.runs, cardRun{text: text, face: face, raise: raise})
+			w.width += font.MeasureString(face, text)
+		}
+		s := foldGlyphs(field, f)
+		for s != "" {
+			r, n := utf8.DecodeRuneInString(s)
+			if base, ok := superscripts[r]; ok {
+				add(string(base), faces.super, int(faces.size*0.4))
+				s = s[n:]
+				continue
+			}
+			if caps := capsRun(s); caps >= smallCapsMinRun {
+				add(s[:caps], faces.smallCaps, 0)
+				s = s[caps:]
+				continue
+			}
+			// Collect plain text until the next superscript or capital.
+			end := n
+			for end < len(s) {
+				r, n := utf8.DecodeRuneInString(s[end:])
+				if _, ok := superscripts[r]; ok || capsRun(s[end:]) >= smallCapsMinRun {
+					break
+				}
+				end += n
+			}
+			add(s[:end], faces.normal, 0)
+			s = s[end:]
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+// capsRun returns the length of the run of ASCII capitals at the start of s.
+func capsRun(s string) int {
+	n := 0
+	for n < len(s) && 'A' <= s[n] && s[n] <= 'Z' {
+		n++
+	}
+	return n
+}
+
+// wrapWords greedily wraps words into lines no wider than maxWidth. A word
+// wider than maxWidth gets its own line.
+func wrapWords(words []cardWord, space, maxWidth fixed.Int26_6) [][]cardWord {
+	var lines [][]cardWord
+	var line []cardWord
+	var width fixed.Int26_6
+	for _, w := range words {
+		if len(line) > 0 && width+space+w.width > maxWidth {
+			lines = append(lines, line)
+			line, width = nil, 0
+		}
+		if len(line) > 0 {
+			width += space
+		}
+		line = append(line, w)
+		width += w.width
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// foldGlyphs replaces runes missing from the font with a fallback and drops
+// runes without a fallback so the card never shows a missing glyph box.
+// Keeps superscripts, which are drawn with the base glyph.
+func foldGlyphs(s string, f *sfnt.Font) string {
+	var buf sfnt.Buffer
+	has := func(r rune) bool {
+		idx, err := f.GlyphIndex(&buf, r)
+		return err == nil && idx != 0
+	}
+	sb := strings.Builder{}
+	for _, r := range s {
+		switch {
+		case has(r):
+			sb.WriteRune(r)
+		case superscripts[r] != 0:
+			sb.WriteRune(r)
+		case glyphFallbacks[r] != 0 && has(glyphFallbacks[r]):
+			sb.WriteRune(glyphFallbacks[r])
+		}
+	}
+	return sb.String()
+}
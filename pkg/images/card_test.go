@@ -0,0 +1,122 @@
+package images
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCard_Render(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "social.png")
+	card := Card{
+		Title: "Fix sluggish ZSH shells with 2ᵏ factorial designs — a very long title that wraps onto several lines",
+		Date:  time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC),
+		Site:  "Joe Schafer’s Blog",
+	}
+	if err := card.Render(dest); err != nil {
+		t.Fatal(err)
+	}
+	w, h, err := Size(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != CardWidth || h != CardHeight {
+		t.Errorf("Render size = %dx%d; want %dx%d", w, h, CardWidth, CardHeight)
+	}
+}
+
+func TestCard_RenderCached(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	card := Card{Title: "Foo", Date: time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC), Site: "Site"}
+
+	dest1 := filepath.Join(t.TempDir(), "a", "social.png")
+	if err := card.RenderCached(dest1); err != nil {
+		t.Fatal(err)
+	}
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		t.Skipf("no user cache dir: %v", err)
+	}
+	cached := filepath.Join(userCache, "jsc", "social-cards", card.Key()+".png")
+	if _, err := os.Stat(cached); err != nil {
+		t.Fatalf("want cached card: %v", err)
+	}
+
+	// An unchanged card copies the cached file instead of rendering.
+	marker := []byte("cached")
+	if err := os.WriteFile(cached, marker, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dest2 := filepath.Join(t.TempDir(), "social.png")
+	if err := card.RenderCached(dest2); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest2); !bytes.Equal(got, marker) {
+		t.Errorf("RenderCached rendered the card again; want the cached file")
+	}
+
+	// A changed title renders a new card.
+	card.Title = "Bar"
+	dest3 := filepath.Join(t.TempDir(), "social.png")
+	if err := card.RenderCached(dest3); err != nil {
+		t.Fatal(err)
+	}
+	if w, h, err := Size(dest3); err != nil || w != CardWidth || h != CardHeight {
+		t.Errorf("RenderCached changed card size = %dx%d, %v; want %dx%d", w, h, err, CardWidth, CardHeight)
+	}
+}
+
+func TestSplitCardWords(t *testing.T) {
+	fonts, err := loadCardFonts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	faces, err := newCardFaces(fonts.bold, 72)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := splitCardWords("2ᵏ ZSH’s", fonts.bold, faces)
+	if len(words) != 2 {
+		t.Fatalf("want 2 words, got %d", len(words))
+	}
+	type run struct {
+		text  string
+		face  string
+		raise bool
+	}
+	faceName := func(r cardRun) string {
+		switch r.face {
+		case faces.normal:
+			return "normal"
+		case faces.smallCaps:
+			return "smallCaps"
+		case faces.super:
+			return "super"
+		}
+		return "unknown"
+	}
+	var got []run
+	for _, w := range words {
+		for _, r := range w.runs {
+			got = append(got, run{r.text, faceName(r), r.raise > 0})
+		}
+	}
+	want := []run{
+		{"2", "normal", false},
+		{"k", "super", true},
+		{"ZSH", "smallCaps", false},
+		{"’s", "normal", false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("runs = %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("runs = %v; want %v", got, want)
+			break
+		}
+	}
+}
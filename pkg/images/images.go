@@ -0,0 +1,134 @@
+// Package images resizes raster images into the smaller variants used by
+// responsive <img> srcsets.
+package images
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/jsc/pkg/errs"
+)
+
+// Widths are the widths in pixels of the resized variants of an image. An
+// image only gets the variants narrower than itself.
+var Widths = []int{640, 1280, 2048}
+
+// jpegQuality is the quality used to re-encode resized JPEGs.
+const jpegQuality = 85
+
+// IsResizable returns true if the image at path has a format this package can
+// resize, based on the extension.
+func IsResizable(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	default:
+		return false
+	}
+}
+
+// Size returns the width and height of the image at path without decoding the
+// entire image.
+func Size(path string) (width, height int, mErr error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("open image: %w", err)
+	}
+	defer errs.Capture(&mErr, f.Close, "close image")
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("decode image config %s: %w", path, err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// VariantPath returns the path of the variant of the image at path with
+// width, like foo-640w.png for foo.png.
+func VariantPath(path string, width int) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + strconv.Itoa(width) + "w" + ext
+}
+
+// Resize writes the image at src scaled to width into dest, preserving the
+// aspect ratio and the image format.
+func Resize(dest, src string, width int) (mErr error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open image: %w", err)
+	}
+	defer errs.Capture(&mErr, f.Close, "close image")
+	img, format, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decode image %s: %w", src, err)
+	}
+	b := img.Bounds()
+	if width <= 0 || width > b.Dx() {
+		return fmt.Errorf("resize image %s: width %d must be in (0, %d]", src, width, b.Dx())
+	}
+	height := max(1, (b.Dy()*width+b.Dx()/2)/b.Dx())
+	scaled := scale(img, width, height)
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("make dir for resized image: %w", err)
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create resized image: %w", err)
+	}
+	defer errs.Capture(&mErr, out.Close, "close resized image")
+	switch format {
+	case "png":
+		err = png.Encode(out, scaled)
+	case "jpeg":
+		err = jpeg.Encode(out, scaled, &jpeg.Options{Quality: jpegQuality})
+	default:
+		err = fmt.Errorf("unsupported image format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("encode resized image %s: %w", dest, err)
+	}
+	return nil
+}
+
+// scale downsamples img to width by height with a box filter, averaging the
+// source pixels covered by each destination pixel.
+func scale(img image.Image, width, height int) *image.RGBA {
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	sw, sh := b.Dx(), b.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0 := dy * sh / height
+		y1 := max(y0+1, (dy+1)*sh/height)
+		for dx := 0; dx < width; dx++ {
+			x0 := dx * sw / width
+			x1 := max(x0+1, (dx+1)*sw/width)
+			var r, g, bl, a, n uint64
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride+x0*4 : y*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					bl += uint64(row[i+2])
+					a += uint64(row[i+3])
+					n++
+				}
+			}
+			o := dy*dst.Stride + dx*4
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(bl / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
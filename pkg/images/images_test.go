@@ -0,0 +1,86 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jschaf/jsc/pkg/errs"
+)
+
+// writePNG writes a solid PNG with the size to a new file in a temp dir.
+func writePNG(t *testing.T, name string, width, height int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer errs.CaptureT(t, f.Close, "close png")
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResize(t *testing.T) {
+	src := writePNG(t, "foo.png", 1000, 500)
+	dest := filepath.Join(t.TempDir(), "out", "foo-640w.png")
+
+	if err := Resize(dest, src, 640); err != nil {
+		t.Fatal(err)
+	}
+
+	w, h, err := Size(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != 640 || h != 320 {
+		t.Errorf("Resize size = %dx%d; want 640x320", w, h)
+	}
+	f, err := os.Open(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer errs.CaptureT(t, f.Close, "close resized png")
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := color.RGBAModel.Convert(img.At(10, 10)), (color.RGBA{R: 200, G: 100, B: 50, A: 255}); got != want {
+		t.Errorf("Resize pixel = %v; want %v", got, want)
+	}
+}
+
+func TestResize_WiderThanImage(t *testing.T) {
+	src := writePNG(t, "foo.png", 100, 100)
+	if err := Resize(filepath.Join(t.TempDir(), "foo.png"), src, 640); err == nil {
+		t.Error("Resize wider than image should error")
+	}
+}
+
+func TestVariantPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		width int
+		want  string
+	}{
+		{"/foo/bar.png", 640, "/foo/bar-640w.png"},
+		{"bar.JPG", 1280, "bar-1280w.JPG"},
+		{"/foo.d/bar", 640, "/foo.d/bar-640w"},
+	}
+	for _, tt := range tests {
+		if got := VariantPath(tt.path, tt.width); got != tt.want {
+			t.Errorf("VariantPath(%q, %d) = %q; want %q", tt.path, tt.width, got, tt.want)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package js
 
 import (
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	esbuild "github.com/evanw/esbuild/pkg/api"
@@ -21,11 +24,26 @@ type jsCache struct {
 
 var mainJSCache = &jsCache{}
 
-func (jsCache *jsCache) isUnchanged(mainJSPath string) (bool, uint64, error) {
-	newKey, err := files.HashContentsFnv64(mainJSPath)
+// isUnchanged reports whether the TypeScript sources bundled into main.js are
+// unchanged since the last build. main.ts imports the other non-test .ts files
+// in the static dir, so the key covers all of them.
+func (jsCache *jsCache) isUnchanged(staticDir string) (bool, uint64, error) {
+	srcs, err := filepath.Glob(filepath.Join(staticDir, "*.ts"))
 	if err != nil {
-		return false, 0, fmt.Errorf("hash main.js for JS build cache: %w", err)
+		return false, 0, fmt.Errorf("glob typescript for JS build cache: %w", err)
 	}
+	h := fnv.New64a()
+	for _, src := range srcs {
+		if strings.HasSuffix(src, "_test.ts") {
+			continue
+		}
+		key, err := files.HashContentsFnv64(src)
+		if err != nil {
+			return false, 0, fmt.Errorf("hash %s for JS build cache: %w", filepath.Base(src), err)
+		}
+		_ = binary.Write(h, binary.LittleEndian, key)
+	}
+	newKey := h.Sum64()
 	curKey := mainJSCache.key
 	if newKey == curKey {
 		return true, newKey, nil
@@ -34,13 +52,14 @@ func (jsCache *jsCache) isUnchanged(mainJSPath string) (bool, uint64, error) {
 }
 
 func bundleTypeScript(distDir string) (esbuild.BuildResult, error) {
-	mainTS := filepath.Join(git.RootDir(), dirs.Static, "main.ts")
+	staticDir := filepath.Join(git.RootDir(), dirs.Static)
+	mainTS := filepath.Join(staticDir, "main.ts")
 	mainTSOut := filepath.Join(distDir, "main.js")
 
 	// Check if the file is the same; if so, skip the bundle step.
 	mainJSCache.mu.Lock()
 	defer mainJSCache.mu.Unlock()
-	ok, newKey, err := mainJSCache.isUnchanged(mainTS)
+	ok, newKey, err := mainJSCache.isUnchanged(staticDir)
 	if err != nil {
 		return esbuild.BuildResult{}, err
 	} else if ok {
@@ -51,7 +70,7 @@ func bundleTypeScript(distDir string) (esbuild.BuildResult, error) {
 		EntryPoints: []string{mainTS},
 		Outfile:     mainTSOut,
 		Target:      esbuild.ES2019,
-		Bundle:      false,
+		Bundle:      true,
 		Write:       true,
 		LogLevel:    esbuild.LogLevelError,
 	})
@@ -0,0 +1,215 @@
+// Package linkcheck finds broken internal links, fragments, and assets in the
+// generated HTML of a site build.
+package linkcheck
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jschaf/jsc/pkg/errs"
+	"github.com/jschaf/jsc/pkg/paths"
+	"golang.org/x/net/html"
+)
+
+// Problem is a broken link in a generated HTML file.
+type Problem struct {
+	// The HTML file with the link, relative to the dist dir, like
+	// "foo/index.html".
+	File string
+	// The URL as written in the HTML attribute.
+	URL string
+	// Why the link is broken.
+	Reason string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.File, p.URL, p.Reason)
+}
+
+// ignoredPrefixes are URL paths served by something other than the dist dir,
+// like the Firebase rewrite to the tracking server.
+var ignoredPrefixes = []string{"/_/", "/dev/"}
+
+// page is a parsed HTML file in the dist dir.
+type page struct {
+	// The path relative to the dist dir using forward slashes.
+	file string
+	ids  map[string]struct{}
+	refs []string
+}
+
+// Check parses every HTML file in distDir and returns the problems sorted by
+// file. Internal links must resolve to a file using the same clean URL rules
+// as Firebase and the dev server: a path without a dot resolves to the file
+// or to the index.html of the directory. Fragments must match an element ID
+// on the target page. Referenced assets, like images, must exist.
+func Check(distDir string) ([]Problem, error) {
+	pages, err := paths.WalkCollect(distDir, func(path string, dirent fs.DirEntry) ([]*page, error) {
+		if !dirent.Type().IsRegular() || filepath.Ext(path) != ".html" {
+			return nil, nil
+		}
+		rel, err := filepath.Rel(distDir, path)
+		if err != nil {
+			return nil, fmt.Errorf("rel path for html file: %w", err)
+		}
+		p, err := parsePage(path, filepath.ToSlash(rel))
+		if err != nil {
+			return nil, err
+		}
+		return []*page{p}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse html files: %w", err)
+	}
+
+	byFile := make(map[string]*page, len(pages))
+	for _, p := range pages {
+		byFile[p.file] = p
+	}
+	c := checker{distDir: distDir, pages: byFile}
+	var problems []Problem
+	for _, p := range pages {
+		for _, ref := range p.refs {
+			if reason := c.checkRef(p, ref); reason != "" {
+				problems = append(problems, Problem{File: p.file, URL: ref, Reason: reason})
+			}
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].URL < problems[j].URL
+	})
+	return problems, nil
+}
+
+func parsePage(path, file string) (p *page, mErr error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open html file: %w", err)
+	}
+	defer errs.Capture(&mErr, f.Close, "close html file")
+	doc, err := html.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse html file %s: %w", file, err)
+	}
+
+	p = &page{file: file, ids: make(map[string]struct{})}
+	seen := make(map[string]struct{})
+	addRef := func(ref string) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return
+		}
+		if _, ok := seen[ref]; !ok {
+			seen[ref] = struct{}{}
+			p.refs = append(p.refs, ref)
+		}
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, a := range n.Attr {
+				switch {
+				case a.Key == "id":
+					p.ids[a.Val] = struct{}{}
+				case a.Key == "name" && n.Data == "a":
+					p.ids[a.Val] = struct{}{}
+				case a.Key == "href" || a.Key == "src":
+					addRef(a.Val)
+				case a.Key == "srcset":
+					for _, candidate := range strings.Split(a.Val, ",") {
+						// A candidate is a URL followed by an optional descriptor.
+						if fields := strings.Fields(candidate); len(fields) > 0 {
+							addRef(fields[0])
+						}
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return p, nil
+}
+
+type checker struct {
+	distDir string
+	pages   map[string]*page
+}
+
+// checkRef returns why ref on page p is broken or the empty string if ref is
+// fine or external.
+func (c checker) checkRef(p *page, ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "invalid URL: " + err.Error()
+	}
+	if u.Scheme != "" || u.Host != "" || u.Opaque != "" {
+		return "" // external, like https:, mailto:, or data:
+	}
+
+	target := p
+	if u.Path != "" {
+		upath := u.Path
+		if !strings.HasPrefix(upath, "/") {
+			// Relative to the served URL of the page, which has no trailing slash.
+			upath = path.Join(path.Dir(servedPath(p.file)), upath)
+		}
+		for _, prefix := range ignoredPrefixes {
+			if strings.HasPrefix(upath, prefix) {
+				return ""
+			}
+		}
+		file, reason := c.resolve(upath)
+		if reason != "" {
+			return reason
+		}
+		target = c.pages[file]
+	}
+
+	if u.Fragment == "" || target == nil {
+		// No fragment or the fragment points into a non-HTML file, like a PDF.
+		return ""
+	}
+	if _, ok := target.ids[u.Fragment]; !ok {
+		return fmt.Sprintf("no element with id %q in %s", u.Fragment, target.file)
+	}
+	return ""
+}
+
+// resolve returns the dist file, relative to the dist dir, served for the
+// absolute URL path upath using the clean URL rules.
+func (c checker) resolve(upath string) (string, string) {
+	name := strings.TrimPrefix(path.Clean(upath), "/")
+	info, err := os.Stat(filepath.Join(c.distDir, filepath.FromSlash(name)))
+	if err != nil {
+		return "", "no file for URL path " + upath
+	}
+	if !info.IsDir() {
+		return name, ""
+	}
+	index := path.Join(name, "index.html")
+	if _, err := os.Stat(filepath.Join(c.distDir, filepath.FromSlash(index))); err != nil {
+		return "", "no index.html in dir for URL path " + upath
+	}
+	return index, ""
+}
+
+// servedPath returns the clean URL path that serves the dist file, like /foo
+// for foo/index.html.
+func servedPath(file string) string {
+	if file == "index.html" {
+		return "/"
+	}
+	return "/" + strings.TrimSuffix(file, "/index.html")
+}
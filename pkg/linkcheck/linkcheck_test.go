@@ -0,0 +1,63 @@
+package linkcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheck(t *testing.T) {
+	distDir := t.TempDir()
+	files := map[string]string{
+		"index.html": `<!DOCTYPE html><html><body>
+			<a href="/foo">ok clean URL</a>
+			<a href="/foo/#intro">ok fragment</a>
+			<a href="/foo#missing">missing fragment</a>
+			<a href="#top">ok same page</a>
+			<a href="#nope">missing same page</a>
+			<a href="/bar/">missing page</a>
+			<a href="/empty-dir">dir without index</a>
+			<a href="https://example.com/nope">external</a>
+			<a href="mailto:me@example.com">mail</a>
+			<a href="/_/heap/js/heap.js">rewrite</a>
+			<h1 id="top">Top</h1>
+			</body></html>`,
+		"foo/index.html": `<!DOCTYPE html><html><body>
+			<h2 id="intro">Intro</h2>
+			<img src="/foo/image.png" srcset="/foo/image.png 1x, image-2x.png 2x">
+			<img src="/foo/missing.png">
+			<a href="./#top">ok relative to served URL /foo</a>
+			<a href="/paper.pdf#page=2">ok non-html fragment</a>
+			</body></html>`,
+		"foo/image.png": "png",
+		"paper.pdf":     "pdf",
+		"empty-dir/a":   "a",
+	}
+	for name, content := range files {
+		path := filepath.Join(distDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Check(distDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Problem{
+		{File: "foo/index.html", URL: "/foo/missing.png", Reason: "no file for URL path /foo/missing.png"},
+		{File: "foo/index.html", URL: "image-2x.png", Reason: "no file for URL path /image-2x.png"},
+		{File: "index.html", URL: "#nope", Reason: `no element with id "nope" in index.html`},
+		{File: "index.html", URL: "/bar/", Reason: "no file for URL path /bar/"},
+		{File: "index.html", URL: "/empty-dir", Reason: "no index.html in dir for URL path /empty-dir"},
+		{File: "index.html", URL: "/foo#missing", Reason: `no element with id "missing" in foo/index.html`},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Check() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -2,27 +2,54 @@ package assets
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/jschaf/jsc/pkg/paths"
 )
 
 type Blob struct {
-	// Absolute path of the source file. If nil, GenPath must be non-nil.
+	// Absolute path of the source file. For generated blobs, the file the
+	// output derives from, or empty to regenerate on every build.
 	Src string
 	// Path relative to the pub dir of the destination file path.
 	Dest string
-	// If non-nil, how to generate the output for Dest.
-	GenFunc func() error
+	// If non-nil, how to generate the output at the absolute dest path from
+	// Src instead of copying Src.
+	GenFunc func(dest string) error
 }
 
 // CopyAll copies all assets into the distDir, overwriting existing files.
+// Generates assets with a GenFunc unless the existing output is newer than the
+// source.
 func CopyAll(distDir string, assets []Blob) error {
 	for _, blob := range assets {
 		dest := filepath.Join(distDir, blob.Dest)
+		if blob.GenFunc != nil {
+			if isFresh(dest, blob.Src) {
+				continue
+			}
+			if err := blob.GenFunc(dest); err != nil {
+				return fmt.Errorf("generate asset %s: %w", blob.Dest, err)
+			}
+			continue
+		}
 		if _, err := paths.CopyLazy(dest, blob.Src); err != nil {
 			return fmt.Errorf("failed to copy asset to dest: %w", err)
 		}
 	}
 	return nil
 }
+
+// isFresh returns true if dest exists and was modified after src.
+func isFresh(dest, src string) bool {
+	destStat, err := os.Stat(dest)
+	if err != nil {
+		return false
+	}
+	srcStat, err := os.Stat(src)
+	if err != nil {
+		return false
+	}
+	return destStat.ModTime().After(srcStat.ModTime())
+}
@@ -65,6 +65,18 @@ func GetStringAttr(n ast.Node, k string) string {
 	}
 }
 
+// Remove removes the attribute named k from the node, preserving the order of
+// other attributes. Goldmark only supports removing all attributes.
+func Remove(n ast.Node, k string) {
+	old := n.Attributes()
+	n.RemoveAttributes()
+	for _, attr := range old {
+		if string(attr.Name) != k {
+			n.SetAttribute(attr.Name, attr.Value)
+		}
+	}
+}
+
 // RenderAll renders all of a given node's attributes.
 func RenderAll(w util.BufWriter, node ast.Node) {
 	for _, attr := range node.Attributes() {
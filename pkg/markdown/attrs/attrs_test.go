@@ -36,3 +36,21 @@ func TestAddClass(t *testing.T) {
 		})
 	}
 }
+
+func TestRemove(t *testing.T) {
+	n := ast.NewParagraph()
+	n.SetAttributeString("id", "foo")
+	n.SetAttributeString("style", "margin-top: -18px")
+	n.SetAttributeString("role", "doc-endnote")
+
+	Remove(n, "style")
+	Remove(n, "missing")
+
+	var got []string
+	for _, attr := range n.Attributes() {
+		got = append(got, string(attr.Name))
+	}
+	if diff := cmp.Diff([]string{"id", "role"}, got); diff != "" {
+		t.Errorf("Remove() attribute names mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,23 @@
+package compiler
+
+import (
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+	"github.com/yuin/goldmark/ast"
+)
+
+// articleBody returns the first node after the header in the article of a
+// post, skipping the title and date. Returns nil if the article has no body.
+func articleBody(a *markdown.AST) ast.Node {
+	for n := a.Node.FirstChild(); n != nil; n = n.NextSibling() {
+		if n.Kind() != mdext.KindArticle {
+			continue
+		}
+		first := n.FirstChild()
+		if first != nil && first.Kind() == mdext.KindHeader {
+			return first.NextSibling()
+		}
+		return first
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/assets"
+	"github.com/jschaf/jsc/pkg/markdown/html"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+	"github.com/yuin/goldmark/ast"
+)
+
+// BookManifestName is the name of the manifest file in the book dir.
+const BookManifestName = "book.toml"
+
+// bookPath is the URL path of the book landing page, which has the table of
+// contents and the bibliography. Chapters live below it at /book/<slug>/ as
+// computed by the TOML parser.
+const bookPath = "/" + dirs.Book + "/"
+
+// BookManifest is the TOML manifest of a book:
+//
+//	title = "Notes on business"
+//	slug = "notes-on-business"
+//	chapters = [
+//	  "art-of-profitability/art-of-profitability.md",
+//	]
+type BookManifest struct {
+	Title string
+	// The file name, without extension, of the EPUB of the book at
+	// /book/<slug>.epub. Empty skips the EPUB.
+	Slug string
+	// Paths to the markdown file of each chapter, relative to the book dir, in
+	// reading order.
+	Chapters []string
+}
+
+// BookCompiler compiles the book dir into a landing page with a table of
+// contents and a bibliography, and a page for each chapter with links to the
+// previous and next chapter.
+type BookCompiler struct {
+	md      *markdown.Markdown
+	epub    *EPUBCompiler
+	bookDir string
+	distDir string
+	mode    PublishMode
+}
+
+// NewBookCompiler creates a compiler for the book in the book dir at the root
+// of the repo.
+func NewBookCompiler(distDir string, mode PublishMode, cfg SiteConfig) *BookCompiler {
+	md := markdown.New(
+		markdown.WithCiteStyle(cfg.CiteStyle),
+		markdown.WithLinkPreviews(cfg.LinkPreviews),
+		markdown.WithHeadingAnchorStyle(mdext.HeadingAnchorStyleShow),
+		markdown.WithTOCStyle(mdext.TOCStyleShow),
+		markdown.WithExtender(mdext.NewNopContinueReadingExt()),
+		// The bibliography on the landing page replaces per-chapter references.
+		markdown.WithCiteAttacher(mdext.NewCitationNopAttacher()),
+	)
+	return &BookCompiler{
+		md:      md,
+		epub:    NewEPUBCompiler(distDir, cfg),
+		bookDir: filepath.Join(git.RootDir(), dirs.Book),
+		distDir: distDir,
+		mode:    mode,
+	}
+}
+
+// ManifestPath returns the absolute path to the book manifest.
+func (bc *BookCompiler) ManifestPath() string {
+	return filepath.Join(bc.bookDir, BookManifestName)
+}
+
+// bookChapter is a parsed chapter of the book.
+type bookChapter struct {
+	ast  *markdown.AST
+	link html.ChapterLink
+}
+
+// Compile compiles the book. Returns the parsed chapters in reading order so
+// callers can inspect the inputs used to build the book. Chapters skipped by
+// the publish mode aren't written or returned. Writes nothing if the publish
+// mode skips every chapter.
+func (bc *BookCompiler) Compile() ([]*markdown.AST, error) {
+	manifest, err := bc.readManifest()
+	if err != nil {
+		return nil, err
+	}
+	chapters, err := bc.parseChapters(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if len(chapters) == 0 {
+		slog.Debug("skip book without published chapters")
+		return nil, nil
+	}
+	links := make([]html.ChapterLink, len(chapters))
+	for i, ch := range chapters {
+		links[i] = ch.link
+	}
+
+	bib, err := bc.buildBibliography(chapters)
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.writeBook(manifest, links, bib); err != nil {
+		return nil, err
+	}
+	if manifest.Slug != "" {
+		paths := make([]string, len(chapters))
+		for i, ch := range chapters {
+			paths[i] = ch.ast.Path
+		}
+		if err := bc.epub.CompileBook(manifest.Title, bookEPUBPath(manifest), paths); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	for i, ch := range chapters {
+		addSocialCard(ch.ast)
+		p := html.ChapterParams{
+			Title:       ch.ast.Meta.Title,
+			Social:      socialMeta(ch.ast.Meta),
+			DraftBanner: draftBanner(ch.ast.Meta, now),
+			BookTitle:   manifest.Title,
+			BookPath:    bookPath,
+			Chapters:    links,
+			Num:         ch.link.Num,
+		}
+		if i > 0 {
+			p.Prev = &links[i-1]
+		}
+		if i < len(links)-1 {
+			p.Next = &links[i+1]
+		}
+		if err := bc.writeChapter(ch.ast, p); err != nil {
+			return nil, fmt.Errorf("write chapter %s: %w", ch.ast.Path, err)
+		}
+	}
+
+	asts := make([]*markdown.AST, len(chapters))
+	for i, ch := range chapters {
+		asts[i] = ch.ast
+	}
+	return asts, nil
+}
+
+func (bc *BookCompiler) readManifest() (BookManifest, error) {
+	path := bc.ManifestPath()
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return BookManifest{}, fmt.Errorf("read book manifest: %w", err)
+	}
+	m := BookManifest{}
+	if err := toml.Unmarshal(bs, &m); err != nil {
+		return BookManifest{}, fmt.Errorf("parse book manifest %s: %w", path, err)
+	}
+	if m.Title == "" {
+		return BookManifest{}, fmt.Errorf("book manifest %s: empty title", path)
+	}
+	if len(m.Chapters) == 0 {
+		return BookManifest{}, fmt.Errorf("book manifest %s: no chapters", path)
+	}
+	return m, nil
+}
+
+// parseChapters parses the chapters in the manifest that the publish mode
+// writes and numbers them in reading order.
+func (bc *BookCompiler) parseChapters(m BookManifest) ([]bookChapter, error) {
+	now := time.Now()
+	chapters := make([]bookChapter, 0, len(m.Chapters))
+	slugs := make(map[string]string, len(m.Chapters))
+	for _, rel := range m.Chapters {
+		path := filepath.Join(bc.bookDir, rel)
+		slog.Debug("compiling book chapter", "path", path)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read book chapter: %w", err)
+		}
+		chAST, err := bc.md.Parse(path, bytes.NewReader(src))
+		if err != nil {
+			return nil, fmt.Errorf("parse book chapter %s: %w", path, err)
+		}
+		slug := chAST.Meta.Slug
+		if slug == "" {
+			return nil, fmt.Errorf("empty slug for book chapter: %s", path)
+		}
+		if prev, ok := slugs[slug]; ok {
+			return nil, fmt.Errorf("duplicate slug %q for book chapters %s and %s", slug, prev, path)
+		}
+		slugs[slug] = path
+		if !bc.mode.shouldWrite(chAST.Meta, now) {
+			slog.Debug("skip unpublished book chapter", "path", path)
+			continue
+		}
+		titleHTML, err := renderTitle(bc.md, chAST)
+		if err != nil {
+			return nil, fmt.Errorf("render book chapter title: %w", err)
+		}
+		chapters = append(chapters, bookChapter{
+			ast: chAST,
+			link: html.ChapterLink{
+				Num:       len(chapters) + 1,
+				Title:     chAST.Meta.Title,
+				TitleHTML: titleHTML,
+				Path:      chAST.Meta.Path,
+			},
+		})
+	}
+	return chapters, nil
+}
+
+// buildBibliography collects every citation across all chapters into a single
+// bibliography sorted by cite key and points the citation links in each
+// chapter to the bibliography entry.
+func (bc *BookCompiler) buildBibliography(chapters []bookChapter) ([]html.BibEntryParams, error) {
+	type entry struct {
+		citation *mdext.Citation
+		source   []byte
+		citedIn  []html.ChapterLink
+	}
+	entries := make(map[bibtex.CiteKey]*entry)
+	for _, ch := range chapters {
+		err := ast.Walk(ch.ast.Node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			switch n := n.(type) {
+			case *mdext.FootnoteLink:
+				if n.Variant == mdext.FootnoteVariantCite {
+					c := &mdext.Citation{Key: bibtex.CiteKey(n.Name)}
+					n.SetAttributeString("href", bookPath+"#"+c.ReferenceID())
+				}
+			case *mdext.Citation:
+				e, ok := entries[n.Key]
+				if !ok {
+					e = &entry{citation: n, source: ch.ast.Source}
+					entries[n.Key] = e
+				}
+				if len(e.citedIn) == 0 || e.citedIn[len(e.citedIn)-1].Num != ch.link.Num {
+					e.citedIn = append(e.citedIn, ch.link)
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("collect citations for %s: %w", ch.ast.Path, err)
+		}
+	}
+
+	keys := make([]bibtex.CiteKey, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	bib := make([]html.BibEntryParams, 0, len(keys))
+	r := bc.md.Renderer()
+	for _, k := range keys {
+		e := entries[k]
+		b := &bytes.Buffer{}
+		if err := r.Render(b, e.source, e.citation); err != nil {
+			return nil, fmt.Errorf("render bibliography entry %s: %w", k, err)
+		}
+		bib = append(bib, html.BibEntryParams{
+			ID:      e.citation.ReferenceID(),
+			Content: template.HTML(b.String()),
+			CitedIn: e.citedIn,
+		})
+	}
+	return bib, nil
+}
+
+func (bc *BookCompiler) writeBook(m BookManifest, links []html.ChapterLink, bib []html.BibEntryParams) error {
+	feats := mdctx.NewFeatureSet()
+	feats.Add(mdctx.FeatureKatex)
+	data := html.BookParams{
+		Title:        m.Title,
+		Path:         bookPath,
+		Features:     feats,
+		Chapters:     links,
+		Bibliography: bib,
+	}
+	if m.Slug != "" {
+		data.EPUBPath = bookEPUBPath(m)
+	}
+	return writeDistFile(bc.distDir, filepath.Join(bookPath, "index.html"), func(w io.Writer) error {
+		return html.RenderBook(w, data)
+	})
+}
+
+// bookEPUBPath returns the URL path of the EPUB of the book.
+func bookEPUBPath(m BookManifest) string {
+	return bookPath + m.Slug + ".epub"
+}
+
+func (bc *BookCompiler) writeChapter(chAST *markdown.AST, p html.ChapterParams) error {
+	b := &bytes.Buffer{}
+	if err := bc.md.Render(b, chAST.Source, chAST); err != nil {
+		return fmt.Errorf("render markdown: %w", err)
+	}
+	p.Content = template.HTML(b.String())
+	p.Features = chAST.Features
+	err := writeDistFile(bc.distDir, filepath.Join(chAST.Meta.Path, "index.html"), func(w io.Writer) error {
+		return html.RenderChapter(w, p)
+	})
+	if err != nil {
+		return err
+	}
+	return assets.CopyAll(bc.distDir, chAST.Assets)
+}
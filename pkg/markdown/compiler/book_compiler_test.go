@@ -0,0 +1,114 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/texts"
+)
+
+func TestBookCompiler_Compile(t *testing.T) {
+	// The TOML parser computes /book/<slug>/ paths for files in a book dir.
+	bookDir := filepath.Join(t.TempDir(), dirs.Book)
+	// Absolute bib paths start from the root of the repo.
+	const bib = "/pkg/markdown/mdext/testdata/citation_test.bib"
+	files := map[string]string{
+		BookManifestName: texts.Dedent(`
+			title = "Test book"
+			chapters = ["two/two.md", "one.md"]
+		`),
+		"one.md": texts.Dedent(`
+			+++
+			slug = "one"
+			bib_paths = ["` + bib + `"]
+			+++
+			# Chapter one
+
+			Spanner. [^@corbett2012spanner]
+		`),
+		"two/two.md": texts.Dedent(`
+			+++
+			slug = "two"
+			bib_paths = ["` + bib + `"]
+			+++
+			# Chapter two
+
+			Concurrency. [^@lea2000concurrent] Spanner. [^@corbett2012spanner]
+		`),
+	}
+	for name, content := range files {
+		path := filepath.Join(bookDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	distDir := t.TempDir()
+	bc := NewBookCompiler(distDir, PublishModePreview, DefaultSiteConfig())
+	bc.bookDir = bookDir
+	asts, err := bc.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(asts) != 2 || asts[0].Meta.Slug != "two" || asts[1].Meta.Slug != "one" {
+		t.Fatalf("want chapters in manifest order [two, one]; got %d chapters", len(asts))
+	}
+
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{
+			"book/index.html",
+			[]string{
+				`<h1 class="title">Test book</h1>`,
+				`<li><a href="/book/two/" title="Chapter two">Chapter two</a></li>`,
+				`<div id="cite_ref_corbett2012spanner" class="cite-reference">`,
+				`Cited in <a href="/book/two/">chapter 1</a>, <a href="/book/one/">chapter 2</a>.`,
+				`<div id="cite_ref_lea2000concurrent" class="cite-reference">`,
+			},
+			nil,
+		},
+		{
+			"book/two/index.html",
+			[]string{
+				`href="/book/#cite_ref_lea2000concurrent"`,
+				`<a class="book-pager-next" href="/book/one/" rel="next" title="Chapter one">Chapter one →</a>`,
+			},
+			[]string{"book-pager-prev", `class="cite-references"`},
+		},
+		{
+			"book/one/index.html",
+			[]string{
+				`<a class="book-pager-prev" href="/book/two/" rel="prev" title="Chapter two">← Chapter two</a>`,
+			},
+			[]string{"book-pager-next"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(distDir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(b)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s missing %q; got:\n%s", tt.file, want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("%s contains %q; got:\n%s", tt.file, notWant, got)
+				}
+			}
+		})
+	}
+}
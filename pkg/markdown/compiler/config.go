@@ -0,0 +1,95 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jschaf/jsc/pkg/cite"
+	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/markdown/linkio"
+)
+
+// SiteConfigName is the name of the site config file at the root of the repo.
+const SiteConfigName = "site.toml"
+
+// SiteConfig is the TOML config of the site:
+//
+//	page_size = 10
+//	cite_style = "IEEE"
+//	repo_url = "https://github.com/jschaf/b2"
+type SiteConfig struct {
+	// The number of posts on each page of the main index.
+	PageSize int `toml:"page_size"`
+	// The citation style for posts that don't set cite_style in the
+	// frontmatter.
+	CiteStyle cite.Style `toml:"cite_style"`
+	// The GitHub repo of the site, used to link commits on the revision
+	// history pages. Commits aren't linked if empty.
+	RepoURL string `toml:"repo_url"`
+	// The committed cache of fetched link previews, read from
+	// linkio.CachePath by LoadSite instead of the TOML.
+	LinkPreviews *linkio.Cache `toml:"-"`
+}
+
+// DefaultSiteConfig returns the config used for unset fields.
+func DefaultSiteConfig() SiteConfig {
+	return SiteConfig{PageSize: defaultPageSize, CiteStyle: cite.IEEE}
+}
+
+// SiteConfigPath returns the absolute path of the site config.
+func SiteConfigPath() string {
+	return filepath.Join(git.RootDir(), SiteConfigName)
+}
+
+// IsSiteInput returns true if path is read by LoadSite. The site inputs apply
+// to every page.
+func IsSiteInput(path string) bool {
+	return path == SiteConfigPath() || path == linkio.CachePath()
+}
+
+// LoadSite reads the site config at SiteConfigPath and the link previews.
+// Builds without fetched previews if the cache is corrupt so that a bad
+// refresh doesn't block editing.
+func LoadSite() (SiteConfig, error) {
+	cfg, err := LoadSiteConfig(SiteConfigPath())
+	if err != nil {
+		return SiteConfig{}, err
+	}
+	previews, err := linkio.LoadCache(linkio.CachePath())
+	if err != nil {
+		slog.Error("load link previews", "error", err)
+	}
+	cfg.LinkPreviews = previews
+	return cfg, nil
+}
+
+// LoadSiteConfig reads the site config at path. Unset fields and a missing
+// file use the defaults.
+func LoadSiteConfig(path string) (SiteConfig, error) {
+	cfg := DefaultSiteConfig()
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return SiteConfig{}, fmt.Errorf("read site config: %w", err)
+	}
+	if err := toml.Unmarshal(bs, &cfg); err != nil {
+		return SiteConfig{}, fmt.Errorf("parse site config %s: %w", path, err)
+	}
+	if cfg.PageSize < 1 {
+		return SiteConfig{}, fmt.Errorf("site config %s: page_size must be positive; got %d", path, cfg.PageSize)
+	}
+	style, err := cite.ParseStyle(string(cfg.CiteStyle))
+	if err != nil {
+		return SiteConfig{}, fmt.Errorf("site config %s: cite_style: %w", path, err)
+	}
+	cfg.CiteStyle = style
+	cfg.RepoURL = strings.TrimSuffix(cfg.RepoURL, "/")
+	return cfg, nil
+}
@@ -0,0 +1,69 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/jsc/pkg/cite"
+	"github.com/jschaf/jsc/pkg/markdown/linkio"
+)
+
+func TestLoadSiteConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // empty for a missing file
+		want    SiteConfig
+		wantErr string
+	}{
+		{"missing file", "", DefaultSiteConfig(), ""},
+		{"page size", "page_size = 5\n", SiteConfig{PageSize: 5, CiteStyle: cite.IEEE}, ""},
+		{"defaults", "# empty\n", DefaultSiteConfig(), ""},
+		{"zero page size", "page_size = 0\n", SiteConfig{}, "page_size must be positive"},
+		{"cite style", "cite_style = \"apa\"\n", SiteConfig{PageSize: defaultPageSize, CiteStyle: cite.APA}, ""},
+		{"unknown cite style", "cite_style = \"harvard\"\n", SiteConfig{}, `cite_style: unknown cite style "harvard"`},
+		{"repo url", "repo_url = \"https://github.com/foo/bar/\"\n", SiteConfig{PageSize: defaultPageSize, CiteStyle: cite.IEEE, RepoURL: "https://github.com/foo/bar"}, ""},
+		{"bad toml", "page_size = \n", SiteConfig{}, "parse site config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), SiteConfigName)
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := LoadSiteConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadSiteConfig() error = %v; want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("LoadSiteConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsSiteInput(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{SiteConfigPath(), true},
+		{linkio.CachePath(), true},
+		{filepath.Join(filepath.Dir(SiteConfigPath()), "posts", "foo.md"), false},
+	}
+	for _, tt := range tests {
+		if got := IsSiteInput(tt.path); got != tt.want {
+			t.Errorf("IsSiteInput(%q) = %t; want %t", tt.path, got, tt.want)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/jschaf/jsc/pkg/dirs"
 	"github.com/jschaf/jsc/pkg/errs"
@@ -26,17 +27,22 @@ import (
 // DetailCompiler compiles the detail page for each post.
 type DetailCompiler struct {
 	md      *markdown.Markdown
+	epub    *EPUBCompiler
 	distDir string
+	mode    PublishMode
+	repoURL string
 }
 
 // NewDetailCompiler creates a compiler for a detail page.
-func NewDetailCompiler(distDir string) *DetailCompiler {
+func NewDetailCompiler(distDir string, mode PublishMode, cfg SiteConfig) *DetailCompiler {
 	md := markdown.New(
+		markdown.WithCiteStyle(cfg.CiteStyle),
+		markdown.WithLinkPreviews(cfg.LinkPreviews),
 		markdown.WithHeadingAnchorStyle(mdext.HeadingAnchorStyleShow),
 		markdown.WithTOCStyle(mdext.TOCStyleShow),
 		markdown.WithExtender(mdext.NewNopContinueReadingExt()),
 	)
-	return &DetailCompiler{md: md, distDir: distDir}
+	return &DetailCompiler{md: md, epub: NewEPUBCompiler(distDir, cfg), distDir: distDir, mode: mode, repoURL: cfg.RepoURL}
 }
 
 // parseFile parses a single path into a markdown AST.
@@ -52,7 +58,10 @@ func (c *DetailCompiler) parseFile(path string) (*markdown.AST, error) {
 	}
 	ast, err := c.md.Parse(path, bytes.NewReader(src))
 	if err != nil {
-		return nil, fmt.Errorf("parseFile post markdown at path %s: %w", path, err)
+		return nil, fmt.Errorf("parse post markdown:\n%w", err)
+	}
+	for _, warning := range ast.Warnings {
+		slog.Warn("markdown warning", "warning", warning.Error())
 	}
 	return ast, nil
 }
@@ -75,16 +84,26 @@ func (c *DetailCompiler) createDestFile(ast *markdown.AST) (*os.File, error) {
 }
 
 // compileAST compiles a markdown AST into a writer.
-func (c *DetailCompiler) compileAST(ast *markdown.AST, w io.Writer) error {
+func (c *DetailCompiler) compileAST(ast *markdown.AST, hist postHistory, w io.Writer) error {
 	b := &bytes.Buffer{}
 	if err := c.md.Render(b, ast.Source, ast); err != nil {
 		return fmt.Errorf("failed to render markdown: %w", err)
 	}
 	ast.Features.Add(mdctx.FeatureComments)
+	addSocialCard(ast)
+	rev := hist.revision(ast)
+	social := socialMeta(ast.Meta)
+	social.Modified = rev.Updated
 	data := html.DetailParams{
-		Title:    ast.Meta.Title,
-		Content:  template.HTML(b.String()),
-		Features: ast.Features,
+		Title:       ast.Meta.Title,
+		Social:      social,
+		Content:     template.HTML(b.String()),
+		Features:    ast.Features,
+		DraftBanner: draftBanner(ast.Meta, time.Now()),
+		Revision:    rev,
+	}
+	if ast.Meta.EPUB {
+		data.EPUBPath = epubPath(ast.Meta)
 	}
 	if err := html.RenderDetail(w, data); err != nil {
 		return fmt.Errorf("failed to execute post template: %w", err)
@@ -93,7 +112,7 @@ func (c *DetailCompiler) compileAST(ast *markdown.AST, w io.Writer) error {
 	if err := assets.CopyAll(c.distDir, ast.Assets); err != nil {
 		return err
 	}
-	return nil
+	return writeHistory(c.distDir, c.repoURL, ast, hist)
 }
 
 func (c *DetailCompiler) Compile(glob string) error {
@@ -108,7 +127,7 @@ func (c *DetailCompiler) Compile(glob string) error {
 	return nil
 }
 
-func (c *DetailCompiler) compileDir(dir string, glob string) (mErr error) {
+func (c *DetailCompiler) compileDir(dir string, glob string) error {
 	err := paths.WalkConcurrent(dir, runtime.NumCPU(), func(path string, dirent *godirwalk.Dirent) error {
 		if !dirent.IsRegular() || filepath.Ext(path) != ".md" {
 			return nil
@@ -116,22 +135,39 @@ func (c *DetailCompiler) compileDir(dir string, glob string) (mErr error) {
 		if glob != "" && !strings.Contains(path, glob) {
 			return nil
 		}
+		_, err := c.CompilePath(path)
+		return err
+	})
+	return err
+}
 
-		ast, err := c.parseFile(path)
-		if err != nil {
-			return fmt.Errorf("parseFile TIL post into AST at path %s: %w", path, err)
-		}
+// CompilePath compiles the markdown file at path into the detail page in
+// distDir. Returns the parsed AST so callers can inspect the inputs used to
+// build the page, like bib files and assets. Returns a nil AST without writing
+// anything if the publish mode skips the post.
+func (c *DetailCompiler) CompilePath(path string) (_ *markdown.AST, mErr error) {
+	ast, err := c.parseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("parseFile post into AST at path %s: %w", path, err)
+	}
+	if !c.mode.shouldWrite(ast.Meta, time.Now()) {
+		slog.Debug("skip unpublished post", "path", path)
+		return nil, nil
+	}
 
-		dest, err := c.createDestFile(ast)
-		if err != nil {
-			return err
-		}
-		defer errs.Capture(&mErr, dest.Close, "close dest file")
+	dest, err := c.createDestFile(ast)
+	if err != nil {
+		return nil, err
+	}
+	defer errs.Capture(&mErr, dest.Close, "close dest file")
 
-		if err := c.compileAST(ast, dest); err != nil {
-			return fmt.Errorf("compileAST AST for path %s: %w", path, err)
+	if err := c.compileAST(ast, loadHistory(path), dest); err != nil {
+		return nil, fmt.Errorf("compileAST AST for path %s: %w", path, err)
+	}
+	if ast.Meta.EPUB {
+		if err := c.epub.CompilePost(path); err != nil {
+			return nil, err
 		}
-		return nil
-	})
-	return err
+	}
+	return ast, nil
 }
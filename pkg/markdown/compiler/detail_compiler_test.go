@@ -8,7 +8,7 @@ import (
 
 func BenchmarkNewDetailCompiler_Compile(b *testing.B) {
 	b.StopTimer()
-	c := NewDetailCompiler(dirs.Dist)
+	c := NewDetailCompiler(dirs.Dist, PublishModePreview, DefaultSiteConfig())
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
 		if err := c.Compile("procella"); err != nil {
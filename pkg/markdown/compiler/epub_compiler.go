@@ -0,0 +1,345 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/jsc/pkg/epub"
+	"github.com/jschaf/jsc/pkg/images"
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/attrs"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+	"github.com/yuin/goldmark/ast"
+)
+
+const (
+	// referencesChapter is the file name of the chapter with the references
+	// cited in every chapter.
+	referencesChapter = "references.xhtml"
+	// epubCover is the file name of the cover image.
+	epubCover = "cover.png"
+)
+
+// EPUBCompiler compiles EPUB books for reading offline from the book dir and
+// from posts that set epub = true in the frontmatter. Reuses the site
+// extensions but rewrites the AST for e-readers: sidenotes become endnotes at
+// the end of each chapter and citations link to a references chapter.
+type EPUBCompiler struct {
+	md      *markdown.Markdown
+	distDir string
+}
+
+func NewEPUBCompiler(distDir string, cfg SiteConfig) *EPUBCompiler {
+	md := markdown.New(
+		markdown.WithCiteStyle(cfg.CiteStyle),
+		markdown.WithExtender(mdext.NewNopContinueReadingExt()),
+		// The references chapter replaces per-chapter references.
+		markdown.WithCiteAttacher(mdext.NewCitationNopAttacher()),
+	)
+	return &EPUBCompiler{md: md, distDir: distDir}
+}
+
+// epubPath returns the URL path of the EPUB of a post, like
+// /foo/foo.epub.
+func epubPath(meta mdext.PostMeta) string {
+	return path.Join(meta.Path, meta.Slug+".epub")
+}
+
+// CompilePost writes the EPUB of the post at path to epubPath.
+func (ec *EPUBCompiler) CompilePost(path string) error {
+	a, err := ec.parse(path)
+	if err != nil {
+		return err
+	}
+	book, err := ec.buildBook(a.Meta.Title, SiteURL+a.Meta.Path, []*markdown.AST{a})
+	if err != nil {
+		return fmt.Errorf("build epub for %s: %w", path, err)
+	}
+	return ec.write(epubPath(a.Meta), book)
+}
+
+// CompileBook writes the EPUB of the book with the chapters at paths, in
+// reading order, to dest, a URL path.
+func (ec *EPUBCompiler) CompileBook(title, dest string, paths []string) error {
+	chapters := make([]*markdown.AST, 0, len(paths))
+	for _, p := range paths {
+		a, err := ec.parse(p)
+		if err != nil {
+			return err
+		}
+		chapters = append(chapters, a)
+	}
+	book, err := ec.buildBook(title, SiteURL+bookPath, chapters)
+	if err != nil {
+		return fmt.Errorf("build book epub: %w", err)
+	}
+	return ec.write(dest, book)
+}
+
+func (ec *EPUBCompiler) parse(path string) (*markdown.AST, error) {
+	slog.Debug("compiling epub", "path", path)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read epub chapter: %w", err)
+	}
+	a, err := ec.md.Parse(path, bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("parse epub chapter %s: %w", path, err)
+	}
+	return a, nil
+}
+
+func (ec *EPUBCompiler) write(dest string, book *epub.Book) error {
+	err := writeDistFile(ec.distDir, dest, book.Write)
+	if err != nil {
+		return fmt.Errorf("write epub %s: %w", dest, err)
+	}
+	return nil
+}
+
+// buildBook converts the chapter ASTs into an EPUB book with a chapter for
+// each AST, followed by a references chapter if any chapter cites a
+// reference.
+func (ec *EPUBCompiler) buildBook(title, id string, chapters []*markdown.AST) (*epub.Book, error) {
+	book := &epub.Book{
+		ID:       id,
+		Title:    title,
+		Author:   siteAuthor,
+		Language: "en",
+	}
+	res := newEPUBResources()
+	refs := make(map[bibtex.CiteKey]epubReference)
+	for i, a := range chapters {
+		if a.Meta.Date.After(book.Modified) {
+			book.Modified = a.Meta.Date
+		}
+		name := "chapter-" + fmt.Sprintf("%02d", i+1) + ".xhtml"
+		for _, c := range prepareEPUBChapter(a) {
+			if _, ok := refs[c.Key]; !ok {
+				refs[c.Key] = epubReference{citation: c, source: a.Source}
+			}
+		}
+		b := &bytes.Buffer{}
+		if err := ec.md.Render(b, a.Source, a); err != nil {
+			return nil, err
+		}
+		body, err := epub.ToXHTML(b.Bytes(), epub.XHTMLOptions{
+			ResolveLink:  func(href string) string { return resolveEPUBLink(a, href) },
+			ResolveImage: func(src string) (string, bool) { return res.add(a, src) },
+		})
+		if err != nil {
+			return nil, fmt.Errorf("convert %s to xhtml: %w", a.Path, err)
+		}
+		book.Chapters = append(book.Chapters, epub.Chapter{Name: name, Title: a.Meta.Title, Body: body})
+	}
+	if len(refs) > 0 {
+		body, err := ec.renderReferences(refs)
+		if err != nil {
+			return nil, err
+		}
+		book.Chapters = append(book.Chapters, epub.Chapter{Name: referencesChapter, Title: "References", Body: body})
+	}
+	if book.Modified.IsZero() {
+		book.Modified = time.Unix(0, 0)
+	}
+
+	cover, err := renderCover(images.Card{Title: title, Date: book.Modified, Site: siteTitle})
+	if err != nil {
+		return nil, err
+	}
+	book.Cover = epubCover
+	book.Resources = append([]epub.Resource{{Name: epubCover, Data: cover}}, res.resources...)
+	return book, nil
+}
+
+// prepareEPUBChapter rewrites the footnotes of the AST for an EPUB and
+// returns the citations in the AST:
+//
+//   - Sidenotes move to a notes section at the end of the chapter.
+//   - Citation links point to the references chapter, which replaces the
+//     citation bodies.
+//   - Margin notes stay in place as asides.
+func prepareEPUBChapter(a *markdown.AST) []*mdext.Citation {
+	var citations []*mdext.Citation
+	var citeBodies, sideBodies, marginBodies []*mdext.FootnoteBody
+	_ = ast.Walk(a.Node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *mdext.FootnoteLink:
+			n.SetAttributeString("epub:type", "noteref")
+			if n.Variant == mdext.FootnoteVariantCite {
+				if n.Citation != nil {
+					n.SetAttributeString("href", referencesChapter+"#"+n.Citation.ReferenceID())
+				}
+			}
+		case *mdext.FootnoteBody:
+			switch n.Variant {
+			case mdext.FootnoteVariantCite:
+				citeBodies = append(citeBodies, n)
+			case mdext.FootnoteVariantSide:
+				sideBodies = append(sideBodies, n)
+			default:
+				marginBodies = append(marginBodies, n)
+			}
+		case *mdext.Citation:
+			citations = append(citations, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, body := range citeBodies {
+		body.Parent().RemoveChild(body.Parent(), body)
+	}
+	for _, body := range marginBodies {
+		// The style positions the note next to the text in the margin.
+		attrs.Remove(body, "style")
+	}
+	if len(sideBodies) > 0 {
+		parent := a.Node
+		if article := findArticle(a.Node); article != nil {
+			parent = article
+		}
+		heading := ast.NewHeading(2)
+		heading.AppendChild(heading, ast.NewString([]byte("Notes")))
+		parent.AppendChild(parent, heading)
+		for _, body := range sideBodies {
+			attrs.Remove(body, "style")
+			body.SetAttributeString("epub:type", "endnote")
+			body.Parent().RemoveChild(body.Parent(), body)
+			parent.AppendChild(parent, body)
+		}
+	}
+	return citations
+}
+
+func findArticle(doc ast.Node) ast.Node {
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		if _, ok := c.(*mdext.Article); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// epubReference is a reference cited in the book.
+type epubReference struct {
+	citation *mdext.Citation
+	source   []byte
+}
+
+// renderReferences renders the references chapter with every reference
+// sorted by cite key.
+func (ec *EPUBCompiler) renderReferences(refs map[bibtex.CiteKey]epubReference) ([]byte, error) {
+	keys := make([]bibtex.CiteKey, 0, len(refs))
+	for k := range refs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	b := &bytes.Buffer{}
+	b.WriteString(`<section epub:type="bibliography"><h1>References</h1>`)
+	r := ec.md.Renderer()
+	for _, k := range keys {
+		ref := refs[k]
+		b.WriteString(`<p id="` + ref.citation.ReferenceID() + `" class="cite-reference">`)
+		if err := r.Render(b, ref.source, ref.citation); err != nil {
+			return nil, fmt.Errorf("render reference %s: %w", k, err)
+		}
+		b.WriteString(`</p>`)
+	}
+	b.WriteString(`</section>`)
+	body, err := epub.ToXHTML(b.Bytes(), epub.XHTMLOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("convert references to xhtml: %w", err)
+	}
+	return body, nil
+}
+
+// resolveEPUBLink returns the href in the EPUB for a link in the chapter.
+// Links to the chapter itself stay in the chapter and other links on the site
+// point to the site since the EPUB only contains the chapters.
+func resolveEPUBLink(a *markdown.AST, href string) string {
+	switch {
+	case strings.HasPrefix(href, a.Meta.Path+"#"):
+		return strings.TrimPrefix(href, a.Meta.Path)
+	case strings.HasPrefix(href, "/"):
+		return SiteURL + href
+	default:
+		return href
+	}
+}
+
+// epubResources are the images packaged in an EPUB.
+type epubResources struct {
+	// The package name of each image keyed by the absolute path of the source.
+	names     map[string]string
+	resources []epub.Resource
+}
+
+func newEPUBResources() *epubResources {
+	return &epubResources{names: make(map[string]string)}
+}
+
+// add packages the image with the src in the chapter and returns the src in
+// the EPUB. Only images copied from the source tree, found in the AST assets,
+// are packaged. Returns false for other images, like remote images, since
+// e-readers may be offline.
+func (er *epubResources) add(a *markdown.AST, src string) (string, bool) {
+	var local string
+	for _, blob := range a.Assets {
+		if blob.Dest == src && blob.GenFunc == nil && blob.Src != "" {
+			local = blob.Src
+			break
+		}
+	}
+	if local == "" || epub.MediaType(local) == "" {
+		slog.Warn("skip epub image", "src", src, "path", a.Path)
+		return "", false
+	}
+	if name, ok := er.names[local]; ok {
+		return name, true
+	}
+	data, err := os.ReadFile(local)
+	if err != nil {
+		slog.Warn("skip epub image", "src", src, "error", err)
+		return "", false
+	}
+	// Prefix the index to keep names unique across chapters.
+	name := "images/" + strconv.Itoa(len(er.resources)+1) + "-" + filepath.Base(local)
+	er.names[local] = name
+	er.resources = append(er.resources, epub.Resource{Name: name, Data: data})
+	return name, true
+}
+
+// renderCover renders the social card of the book as the cover image.
+func renderCover(card images.Card) (_ []byte, mErr error) {
+	dir, err := os.MkdirTemp("", "epub-cover")
+	if err != nil {
+		return nil, fmt.Errorf("make epub cover dir: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil && mErr == nil {
+			mErr = fmt.Errorf("remove epub cover dir: %w", err)
+		}
+	}()
+	dest := filepath.Join(dir, epubCover)
+	if err := card.RenderCached(dest); err != nil {
+		return nil, fmt.Errorf("render epub cover: %w", err)
+	}
+	b, err := os.ReadFile(dest)
+	if err != nil {
+		return nil, fmt.Errorf("read epub cover: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,126 @@
+package compiler
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/jsc/pkg/texts"
+)
+
+func TestEPUBCompiler_CompilePost(t *testing.T) {
+	// Absolute bib paths start from the root of the repo.
+	const bib = "/pkg/markdown/mdext/testdata/citation_test.bib"
+	path := filepath.Join(t.TempDir(), "foo.md")
+	src := texts.Dedent(`
+		+++
+		slug = "foo"
+		bib_paths = ["` + bib + `"]
+		epub = true
+		+++
+		# Foo post
+
+		Spanner. [^@corbett2012spanner] Math $x^2$.
+
+		Aside. [^side:bar]
+
+		::: footnote side:bar
+		side-text
+		:::
+
+		Last paragraph.
+	`)
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cover is a cached social card.
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	distDir := t.TempDir()
+	if err := NewEPUBCompiler(distDir, DefaultSiteConfig()).CompilePost(path); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readEPUB(t, filepath.Join(distDir, "foo", "foo.epub"))
+	for _, name := range []string{"mimetype", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/cover.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("epub doesn't include %s", name)
+		}
+	}
+
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{
+			"OEBPS/chapter-01.xhtml",
+			[]string{
+				`href="references.xhtml#cite_ref_corbett2012spanner"`,
+				`epub:type="noteref"`,
+				`<math xmlns="http://www.w3.org/1998/Math/MathML">`,
+				`<h2>Notes</h2>`,
+				`epub:type="endnote"`,
+			},
+			[]string{`class="katex"`, `margin-top`, `footnote-body-cite`},
+		},
+		{
+			"OEBPS/references.xhtml",
+			[]string{`<p id="cite_ref_corbett2012spanner" class="cite-reference">`},
+			nil,
+		},
+		{
+			"OEBPS/content.opf",
+			[]string{`properties="mathml"`, `properties="cover-image"`},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := files[tt.file]
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s doesn't include %q:\n\n%s", tt.file, want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("%s includes %q:\n\n%s", tt.file, notWant, got)
+				}
+			}
+		})
+	}
+
+	// The notes follow the last paragraph.
+	ch := files["OEBPS/chapter-01.xhtml"]
+	if strings.Index(ch, "side-text") < strings.Index(ch, "Last paragraph.") {
+		t.Errorf("sidenote body before last paragraph:\n\n%s", ch)
+	}
+}
+
+// readEPUB returns the contents of each file in the EPUB keyed by name.
+func readEPUB(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := make(map[string]string, len(zr.File))
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+	}
+	return files
+}
@@ -0,0 +1,262 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+// FeedContent determines how much of each post to include in a feed.
+type FeedContent int
+
+const (
+	// FeedContentSummary includes the post up to CONTINUE_READING, the same
+	// content as the index page.
+	FeedContentSummary FeedContent = iota
+	// FeedContentFull includes the entire post.
+	FeedContentFull
+)
+
+const (
+	atomFeedPath = "/feed.xml"
+	rssFeedPath  = "/rss.xml"
+)
+
+// FeedCompiler compiles the Atom feed at /feed.xml and the RSS feed at
+// /rss.xml for all published posts and TILs.
+type FeedCompiler struct {
+	// Renders the ASTs from IndexCompiler.ParseASTs.
+	md *markdown.Markdown
+	// Parses full posts for FeedContentFull.
+	fullMD  *markdown.Markdown
+	content FeedContent
+	distDir string
+}
+
+// NewFeedCompiler creates a feed compiler that renders ASTs parsed by md,
+// typically IndexCompiler.Markdown.
+func NewFeedCompiler(distDir string, md *markdown.Markdown, content FeedContent, cfg SiteConfig) *FeedCompiler {
+	return &FeedCompiler{
+		md: md,
+		fullMD: markdown.New(
+			markdown.WithCiteStyle(cfg.CiteStyle),
+			markdown.WithLinkPreviews(cfg.LinkPreviews),
+			markdown.WithExtender(mdext.NewNopContinueReadingExt()),
+		),
+		content: content,
+		distDir: distDir,
+	}
+}
+
+// feedEntry is a published post in a feed.
+type feedEntry struct {
+	Title   string
+	URL     string
+	Date    time.Time
+	Content string
+}
+
+// CompileASTs writes the feeds for the published ASTs. The ASTs must be sorted
+// newest first, as returned by IndexCompiler.ParseASTs.
+func (fc *FeedCompiler) CompileASTs(asts []*markdown.AST) error {
+	now := time.Now()
+	entries := make([]feedEntry, 0, len(asts))
+	for _, ast := range asts {
+		if !ast.Meta.IsPublished(now) {
+			continue
+		}
+		e, err := fc.buildEntry(ast)
+		if err != nil {
+			return fmt.Errorf("build feed entry for %s: %w", ast.Path, err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err := fc.writeFeed(atomFeedPath, entries, writeAtom); err != nil {
+		return fmt.Errorf("write atom feed: %w", err)
+	}
+	if err := fc.writeFeed(rssFeedPath, entries, writeRSS); err != nil {
+		return fmt.Errorf("write rss feed: %w", err)
+	}
+	return nil
+}
+
+func (fc *FeedCompiler) buildEntry(ast *markdown.AST) (feedEntry, error) {
+	md := fc.md
+	if fc.content == FeedContentFull {
+		full, err := fc.fullMD.Parse(ast.Path, bytes.NewReader(ast.Source))
+		if err != nil {
+			return feedEntry{}, fmt.Errorf("parse full post: %w", err)
+		}
+		ast, md = full, fc.fullMD
+	}
+
+	// Render the children of the article to skip the title and date, which are
+	// part of the feed entry.
+	b := &bytes.Buffer{}
+	r := md.Renderer()
+	for c := articleBody(ast); c != nil; c = c.NextSibling() {
+		if err := r.Render(b, ast.Source, c); err != nil {
+			return feedEntry{}, fmt.Errorf("render feed content: %w", err)
+		}
+	}
+	url := pageURL(ast.Meta)
+	content, err := feedHTML(b.Bytes(), url)
+	if err != nil {
+		return feedEntry{}, err
+	}
+	return feedEntry{
+		Title:   ast.Meta.Title,
+		URL:     url,
+		Date:    ast.Meta.Date,
+		Content: content,
+	}, nil
+}
+
+func (fc *FeedCompiler) writeFeed(path string, entries []feedEntry, write func(io.Writer, []feedEntry) error) error {
+	return writeDistFile(fc.distDir, path, func(w io.Writer) error {
+		return write(w, entries)
+	})
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// writeAtom writes an Atom feed per RFC 4287.
+func writeAtom(w io.Writer, entries []feedEntry) error {
+	feed := atomFeed{
+		Title: siteTitle,
+		ID:    SiteURL + "/",
+		Links: []atomLink{
+			{Href: SiteURL + atomFeedPath, Rel: "self", Type: "application/atom+xml"},
+			{Href: SiteURL + "/", Rel: "alternate", Type: "text/html"},
+		},
+		Updated: feedUpdated(entries).Format(time.RFC3339),
+		Author:  atomAuthor{Name: siteAuthor},
+	}
+	for _, e := range entries {
+		date := e.Date.UTC().Format(time.RFC3339)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     e.Title,
+			ID:        e.URL,
+			Link:      atomLink{Href: e.URL, Rel: "alternate", Type: "text/html"},
+			Published: date,
+			Updated:   date,
+			Content:   atomContent{Type: "html", Body: e.Content},
+		})
+	}
+	return writeXML(w, feed)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// writeRSS writes an RSS 2.0 feed.
+func writeRSS(w io.Writer, entries []feedEntry) error {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         siteTitle,
+			Link:          SiteURL + "/",
+			Description:   siteTitle,
+			LastBuildDate: feedUpdated(entries).Format(time.RFC1123Z),
+		},
+	}
+	for _, e := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       e.Title,
+			Link:        e.URL,
+			GUID:        rssGUID{IsPermaLink: true, Value: e.URL},
+			PubDate:     e.Date.UTC().Format(time.RFC1123Z),
+			Description: e.Content,
+		})
+	}
+	return writeXML(w, feed)
+}
+
+// feedUpdated returns the date of the newest entry so that the feed only
+// changes when the entries change.
+func feedUpdated(entries []feedEntry) time.Time {
+	var t time.Time
+	for _, e := range entries {
+		if e.Date.After(t) {
+			t = e.Date
+		}
+	}
+	return t.UTC()
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write xml header: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode xml: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("write xml trailing newline: %w", err)
+	}
+	return nil
+}
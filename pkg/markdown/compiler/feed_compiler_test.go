@@ -0,0 +1,122 @@
+package compiler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/htmls"
+)
+
+func TestFeedHTML(t *testing.T) {
+	const pageURL = SiteURL + "/foo/"
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			"relative links",
+			`<p><a href="/bar" class="x">bar</a> <img src="/foo/a.png" loading="lazy"></p>`,
+			`<p><a href="https://joe.schafer.dev/bar">bar</a> <img src="https://joe.schafer.dev/foo/a.png" loading="lazy"></p>`,
+		},
+		{
+			"fragment links",
+			`<p><a href="#footnote-body-side:a" data-link-type="x" role="doc-noteref">[1]</a></p>`,
+			`<p><a href="https://joe.schafer.dev/foo/#footnote-body-side:a">[1]</a></p>`,
+		},
+		{
+			"katex to mathml",
+			`<p><span class="katex"><span class="katex-mathml"><math><mi>x</mi></math></span><span class="katex-html">x</span></span></p>`,
+			`<p><math><mi>x</mi></math></p>`,
+		},
+		{
+			"sidenote to endnote",
+			`<p>a</p><aside class="footnote-body" id="fb" style="margin-top: -18px"><p>note</p></aside><p>b</p>`,
+			`<p>a</p><p>b</p><hr><div id="fb"><p>note</p></div>`,
+		},
+		{
+			"code block",
+			`<div class="code-block-container"><div class="code-block-lang">go</div><pre class="code-block"><code-kw>func</code-kw> foo()</pre></div>`,
+			`<div><pre>func foo()</pre></div>`,
+		},
+		{
+			"svg and script",
+			`<a class="continue-reading" href="/foo"><svg><path></path></svg><div>Continue reading</div></a><script>x()</script>`,
+			`<a href="https://joe.schafer.dev/foo"><div>Continue reading</div></a>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := feedHTML([]byte(tt.body), pageURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff, err := htmls.DiffStrings(tt.want, got); err != nil {
+				t.Fatal(err)
+			} else if diff != "" {
+				t.Errorf("feedHTML mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteFeeds(t *testing.T) {
+	entries := []feedEntry{
+		{
+			Title:   "Newer & better",
+			URL:     SiteURL + "/newer/",
+			Date:    time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC),
+			Content: "<p>newer</p>",
+		},
+		{
+			Title:   "Older",
+			URL:     SiteURL + "/older/",
+			Date:    time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC),
+			Content: "<p>older</p>",
+		},
+	}
+	tests := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		want  []string
+	}{
+		{
+			"atom",
+			func(b *bytes.Buffer) error { return writeAtom(b, entries) },
+			[]string{
+				`<feed xmlns="http://www.w3.org/2005/Atom">`,
+				`<updated>2021-03-04T00:00:00Z</updated>`,
+				`<title>Newer &amp; better</title>`,
+				`<id>https://joe.schafer.dev/older/</id>`,
+				`<published>2020-01-02T00:00:00Z</published>`,
+				`<content type="html">&lt;p&gt;newer&lt;/p&gt;</content>`,
+			},
+		},
+		{
+			"rss",
+			func(b *bytes.Buffer) error { return writeRSS(b, entries) },
+			[]string{
+				`<rss version="2.0">`,
+				`<lastBuildDate>Thu, 04 Mar 2021 00:00:00 +0000</lastBuildDate>`,
+				`<guid isPermaLink="true">https://joe.schafer.dev/newer/</guid>`,
+				`<pubDate>Thu, 02 Jan 2020 00:00:00 +0000</pubDate>`,
+				`<description>&lt;p&gt;older&lt;/p&gt;</description>`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			if err := tt.write(b); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("feed doesn't contain %q:\n%s", want, b.String())
+				}
+			}
+		})
+	}
+}
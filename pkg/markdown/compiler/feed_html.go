@@ -0,0 +1,142 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// feedHTML converts rendered post HTML into HTML that renders reasonably in
+// feed readers, which ignore our CSS and JavaScript.
+//
+//   - KaTeX math becomes the MathML KaTeX renders alongside the HTML.
+//   - Sidenotes and citation bodies move from the margin to the end of the
+//     post as endnotes.
+//   - Relative URLs become absolute URLs, and fragment-only URLs point to the
+//     fragment on the detail page at pageURL.
+//   - Custom elements, like the <code-kw> syntax highlighting tags, are
+//     replaced by their children.
+//   - Presentational attributes, scripts, and SVG icons are removed.
+func feedHTML(body []byte, pageURL string) (string, error) {
+	ctx := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(bytes.NewReader(body), ctx)
+	if err != nil {
+		return "", fmt.Errorf("parse feed html fragment: %w", err)
+	}
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+
+	var notes []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			switch {
+			case c.Type != html.ElementNode:
+			case c.DataAtom == atom.Script || c.DataAtom == atom.Svg || hasClass(c, "code-block-lang"):
+				n.RemoveChild(c)
+			case strings.Contains(c.Data, "-"):
+				walk(c)
+				for gc := c.FirstChild; gc != nil; gc = c.FirstChild {
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+				}
+				n.RemoveChild(c)
+			case hasClass(c, "katex-display") || hasClass(c, "katex"):
+				if math := findMath(c); math != nil {
+					math.Parent.RemoveChild(math)
+					n.InsertBefore(math, c)
+				}
+				n.RemoveChild(c)
+			case c.DataAtom == atom.Aside && hasClass(c, "footnote-body"):
+				n.RemoveChild(c)
+				c.DataAtom = atom.Div
+				c.Data = "div"
+				walk(c)
+				cleanAttrs(c, pageURL)
+				notes = append(notes, c)
+			default:
+				walk(c)
+				cleanAttrs(c, pageURL)
+			}
+			c = next
+		}
+	}
+	walk(root)
+
+	if len(notes) > 0 {
+		root.AppendChild(&html.Node{Type: html.ElementNode, Data: "hr", DataAtom: atom.Hr})
+		for _, note := range notes {
+			root.AppendChild(note)
+		}
+	}
+
+	b := &bytes.Buffer{}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(b, c); err != nil {
+			return "", fmt.Errorf("render feed html: %w", err)
+		}
+	}
+	return b.String(), nil
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, a := range n.Attr {
+		if a.Key == "class" {
+			for _, c := range strings.Fields(a.Val) {
+				if c == class {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// findMath returns the first MathML math element in n or nil.
+func findMath(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.Data == "math" {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if m := findMath(c); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// cleanAttrs removes attributes that only make sense with our CSS and
+// JavaScript and makes URLs absolute.
+func cleanAttrs(n *html.Node, pageURL string) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		switch {
+		case a.Key == "class", a.Key == "style", a.Key == "role",
+			strings.HasPrefix(a.Key, "data-"), strings.HasPrefix(a.Key, "aria-"):
+			continue
+		case a.Key == "href" || a.Key == "src":
+			a.Val = absURL(a.Val, pageURL)
+		}
+		attrs = append(attrs, a)
+	}
+	n.Attr = attrs
+}
+
+func absURL(u, pageURL string) string {
+	switch {
+	case strings.HasPrefix(u, "#"):
+		return pageURL + u
+	case strings.HasPrefix(u, "//"):
+		return u
+	case strings.HasPrefix(u, "/"):
+		return SiteURL + u
+	default:
+		return u
+	}
+}
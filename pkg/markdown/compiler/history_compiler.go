@@ -0,0 +1,154 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/html"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+)
+
+const (
+	// historyDir is the dir of the revision history page below the dir of a
+	// post.
+	historyDir = "history"
+	// minUpdateLines is the fewest lines a commit must add or delete in a post
+	// to count as an update. Smaller changes are usually typo fixes or
+	// frontmatter tweaks that readers don't need to know about.
+	minUpdateLines = 5
+)
+
+// postHistory is the git history of the markdown file of a post, newest
+// commit first.
+type postHistory []git.Commit
+
+// warnNoHistory logs why posts build without history once per process
+// instead of once per post.
+var warnNoHistory sync.Once
+
+// loadHistory returns the git history of the post at path. Builds without
+// history if git fails, like without git installed, since the history is
+// informational. Also builds without history in a shallow clone since the log
+// only has the newest commits, which would misdate the creation and updates.
+func loadHistory(path string) postHistory {
+	shallow, err := git.IsShallow()
+	if err != nil || shallow {
+		warnNoHistory.Do(func() {
+			if err != nil {
+				slog.Warn("skip post history", "error", err)
+				return
+			}
+			slog.Warn("skip post history in shallow clone; run git fetch --unshallow")
+		})
+		return nil
+	}
+	commits, err := git.FileLog(path)
+	if err != nil {
+		slog.Warn("load post history", "path", path, "error", err)
+		return nil
+	}
+	return commits
+}
+
+// created returns the date of the first commit of the post.
+func (h postHistory) created() time.Time {
+	if len(h) == 0 {
+		return time.Time{}
+	}
+	return h[len(h)-1].Date
+}
+
+// lastModified returns the date of the newest commit of the post.
+func (h postHistory) lastModified() time.Time {
+	if len(h) == 0 {
+		return time.Time{}
+	}
+	return h[0].Date
+}
+
+// updated returns the date of the newest commit that changed the post
+// meaningfully after the published date, or zero if none did. The first
+// commit creates the post, so it's never an update. A commit on the published
+// date is part of publishing, not an update.
+func (h postHistory) updated(published time.Time) time.Time {
+	if len(h) < 2 {
+		return time.Time{}
+	}
+	for _, c := range h[:len(h)-1] {
+		if c.Added+c.Deleted < minUpdateLines {
+			continue
+		}
+		if !c.Date.After(published.AddDate(0, 0, 1)) {
+			continue
+		}
+		return c.Date
+	}
+	return time.Time{}
+}
+
+// hasHistoryPage returns true if the post has revisions after the first
+// commit worth listing on a history page.
+func (h postHistory) hasHistoryPage() bool {
+	return len(h) > 1
+}
+
+// revision returns the revision shown on the detail page of the post.
+func (h postHistory) revision(a *markdown.AST) html.Revision {
+	r := html.Revision{Updated: h.updated(a.Meta.Date)}
+	if h.hasHistoryPage() {
+		r.HistoryPath = historyPath(a)
+	}
+	return r
+}
+
+// historyPath returns the URL path of the history page of the post.
+func historyPath(a *markdown.AST) string {
+	return path.Join("/", a.Meta.Slug, historyDir) + "/"
+}
+
+// writeHistory writes the history page of the post into distDir, linking
+// commits below repoURL unless empty. Removes the history page from a previous
+// build if the post no longer has one.
+func writeHistory(distDir, repoURL string, a *markdown.AST, h postHistory) error {
+	if !h.hasHistoryPage() {
+		if err := os.RemoveAll(filepath.Join(distDir, a.Meta.Slug, historyDir)); err != nil {
+			return fmt.Errorf("remove history page: %w", err)
+		}
+		return nil
+	}
+	data := html.HistoryParams{
+		Title:        "Revision history - " + a.Meta.Title,
+		Features:     mdctx.NewFeatureSet(),
+		PostTitle:    a.Meta.Title,
+		PostPath:     a.Meta.Path,
+		Created:      h.created(),
+		LastModified: h.lastModified(),
+		Commits:      make([]html.HistoryCommit, len(h)),
+	}
+	for i, c := range h {
+		data.Commits[i] = html.HistoryCommit{
+			Hash:      c.Hash,
+			ShortHash: c.ShortHash(),
+			Subject:   c.Subject,
+			Date:      c.Date,
+		}
+		if repoURL != "" {
+			data.Commits[i].URL = repoURL + "/commit/" + c.Hash
+		}
+	}
+	err := writeDistFile(distDir, filepath.Join(historyPath(a), "index.html"), func(w io.Writer) error {
+		return html.RenderHistory(w, data)
+	})
+	if err != nil {
+		return fmt.Errorf("write history page: %w", err)
+	}
+	return nil
+}
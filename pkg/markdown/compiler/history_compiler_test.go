@@ -0,0 +1,83 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+func TestPostHistory_Updated(t *testing.T) {
+	published := time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return published.AddDate(0, 0, d).Add(time.Hour) }
+	tests := []struct {
+		name string
+		hist postHistory
+		want time.Time
+	}{
+		{"no history", nil, time.Time{}},
+		{"only first commit", postHistory{{Date: day(-2), Added: 100}}, time.Time{}},
+		{
+			"large change after publishing",
+			postHistory{{Date: day(30), Added: 4, Deleted: 3}, {Date: day(-2), Added: 100}},
+			day(30),
+		},
+		{
+			"skips small changes",
+			postHistory{{Date: day(40), Added: 1, Deleted: 1}, {Date: day(30), Added: 10}, {Date: day(-2), Added: 100}},
+			day(30),
+		},
+		{
+			"skips changes on the published date",
+			postHistory{{Date: day(0), Added: 50}, {Date: day(-2), Added: 100}},
+			time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hist.updated(published); !got.Equal(tt.want) {
+				t.Errorf("updated() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	distDir := t.TempDir()
+	a := &markdown.AST{Meta: mdext.PostMeta{Slug: "foo", Path: "/foo/", Title: "Foo"}}
+	hist := postHistory{
+		{Hash: "bbbbbbbbbb", Subject: "Revise foo", Date: time.Date(2022, time.May, 6, 0, 0, 0, 0, time.UTC)},
+		{Hash: "aaaaaaaaaa", Subject: "Add foo", Date: time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)},
+	}
+	if got := hist.revision(a).HistoryPath; got != "/foo/history/" {
+		t.Errorf("history path = %q; want /foo/history/", got)
+	}
+	if err := writeHistory(distDir, "https://github.com/example/site", a, hist); err != nil {
+		t.Fatal(err)
+	}
+	page := filepath.Join(distDir, "foo", "history", "index.html")
+	b, err := os.ReadFile(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Revise foo", "Add foo", "https://github.com/example/site/commit/bbbbbbbbbb", "bbbbbbb"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("history page doesn't include %q:\n\n%s", want, b)
+		}
+	}
+
+	// A post with only the first commit has no history page.
+	if err := writeHistory(distDir, "https://github.com/example/site", a, hist[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(page); !os.IsNotExist(err) {
+		t.Errorf("want history page removed, got stat error %v", err)
+	}
+	if got := postHistory(hist[1:]).revision(a).HistoryPath; got != "" {
+		t.Errorf("history path = %q; want empty", got)
+	}
+}
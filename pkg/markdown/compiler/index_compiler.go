@@ -4,13 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/git"
 	"github.com/jschaf/jsc/pkg/markdown"
 	"github.com/jschaf/jsc/pkg/markdown/html"
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
@@ -18,15 +23,31 @@ import (
 	"github.com/jschaf/jsc/pkg/paths"
 )
 
-// IndexCompiler compiles the / path, the main homepage.
+const (
+	// defaultPageSize is the number of posts on each page of the main index.
+	defaultPageSize = 10
+	// indexPagesDir is the dist dir that contains all but the first page of the
+	// main index.
+	indexPagesDir = "page"
+	// tilIndexPath is the URL path of the TIL index.
+	tilIndexPath = "/" + dirs.TIL + "/"
+)
+
+// IndexCompiler compiles the / path, the main homepage, paginated as /page/2/
+// and so on, and the /til/ index of all TILs.
 type IndexCompiler struct {
-	md      *markdown.Markdown
-	distDir string
+	md       *markdown.Markdown
+	distDir  string
+	pageSize int
 }
 
-func NewIndexCompiler(distDir string) *IndexCompiler {
-	md := markdown.New(markdown.WithExtender(mdext.NewContinueReadingExt()))
-	return &IndexCompiler{md: md, distDir: distDir}
+func NewIndexCompiler(distDir string, cfg SiteConfig) *IndexCompiler {
+	md := markdown.New(
+		markdown.WithCiteStyle(cfg.CiteStyle),
+		markdown.WithLinkPreviews(cfg.LinkPreviews),
+		markdown.WithExtender(mdext.NewContinueReadingExt()),
+	)
+	return &IndexCompiler{md: md, distDir: distDir, pageSize: cfg.PageSize}
 }
 
 func (ic *IndexCompiler) parseDirs(dirs ...string) ([]*markdown.AST, error) {
@@ -49,31 +70,43 @@ func (ic *IndexCompiler) collectASTs(dir string) ([]*markdown.AST, error) {
 		if !dirent.Type().IsRegular() || filepath.Ext(path) != ".md" {
 			return nil, nil
 		}
-		slog.Debug("compiling for index", "path", path)
-		bs, err := os.ReadFile(path)
-		if err != nil {
-			return nil, fmt.Errorf("read post at path %s: %w", path, err)
-		}
-		ast, err := ic.md.Parse(path, bytes.NewReader(bs))
+		ast, err := ic.ParsePath(path)
 		if err != nil {
-			return nil, fmt.Errorf("parseFile markdown for root index: %w", err)
+			return nil, err
 		}
 		return []*markdown.AST{ast}, nil
 	})
 	return asts, err
 }
 
+// ParsePath parses the post at the absolute path into an index AST. Callers
+// that cache ASTs, like the dev server, use ParsePath to re-parse a single
+// changed post instead of calling ParseASTs.
+func (ic *IndexCompiler) ParsePath(path string) (*markdown.AST, error) {
+	slog.Debug("compiling for index", "path", path)
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read post at path %s: %w", path, err)
+	}
+	ast, err := ic.md.Parse(path, bytes.NewReader(bs))
+	if err != nil {
+		return nil, fmt.Errorf("parseFile markdown for root index: %w", err)
+	}
+	return ast, nil
+}
+
 func (ic *IndexCompiler) renderASTs(asts []*markdown.AST) ([]html.IndexPostParams, error) {
+	now := time.Now()
 	posts := make([]html.IndexPostParams, 0, len(asts))
 	for _, ast := range asts {
-		if ast.Meta.Visibility != mdext.VisibilityPublished {
+		if !ast.Meta.IsPublished(now) {
 			continue
 		}
 		b := new(bytes.Buffer)
 		if err := ic.md.Render(b, ast.Source, ast); err != nil {
 			return nil, fmt.Errorf("render markdown for index: %w", err)
 		}
-		titleHTML, err := ic.renderTitle(ast)
+		titleHTML, err := renderTitle(ic.md, ast)
 		if err != nil {
 			return nil, fmt.Errorf("render index title: %w", err)
 		}
@@ -89,9 +122,11 @@ func (ic *IndexCompiler) renderASTs(asts []*markdown.AST) ([]html.IndexPostParam
 	return posts, nil
 }
 
-func (ic *IndexCompiler) renderTitle(ast *markdown.AST) (template.HTML, error) {
+// renderTitle renders the title of the AST parsed by md without the wrapping
+// link.
+func renderTitle(md *markdown.Markdown, ast *markdown.AST) (template.HTML, error) {
 	b := new(bytes.Buffer)
-	r := ic.md.Renderer()
+	r := md.Renderer()
 
 	// Don't render the element, which is a link. The gohtml chooses how to
 	// build the link.
@@ -105,40 +140,141 @@ func (ic *IndexCompiler) renderTitle(ast *markdown.AST) (template.HTML, error) {
 	return template.HTML(b.String()), nil
 }
 
+// ParseASTs parses all posts and TILs into ASTs sorted by date, newest first.
+// Other compilers, like the FeedCompiler, reuse the ASTs to avoid parsing the
+// same files again.
+func (ic *IndexCompiler) ParseASTs() ([]*markdown.AST, error) {
+	// Use absolute paths so isTIL and the TOML parser see the same file paths
+	// as the detail compiler.
+	root := git.RootDir()
+	return ic.parseDirs(filepath.Join(root, dirs.Posts), filepath.Join(root, dirs.TIL))
+}
+
+// Markdown returns the markdown instance used to parse and render index ASTs.
+func (ic *IndexCompiler) Markdown() *markdown.Markdown {
+	return ic.md
+}
+
 func (ic *IndexCompiler) Compile() error {
-	asts, err := ic.parseDirs(dirs.Posts, dirs.TIL)
+	asts, err := ic.ParseASTs()
 	if err != nil {
 		return err
 	}
+	return ic.CompileASTs(asts)
+}
 
-	featureSet := mdctx.NewFeatureSet()
+// CompileASTs compiles the paginated main index of posts and the TIL index
+// from ASTs returned by ParseASTs.
+func (ic *IndexCompiler) CompileASTs(asts []*markdown.AST) error {
+	if ic.pageSize < 1 {
+		return fmt.Errorf("index page size must be positive; got %d", ic.pageSize)
+	}
+	posts, tils := splitTILs(asts)
+	if err := ic.compilePages(posts); err != nil {
+		return err
+	}
+	if err := ic.compileTILIndex(tils); err != nil {
+		return err
+	}
+	return nil
+}
+
+// splitTILs splits the ASTs into long-form posts and TILs, preserving order.
+func splitTILs(asts []*markdown.AST) (posts, tils []*markdown.AST) {
 	for _, ast := range asts {
-		featureSet.AddAll(ast.Features)
+		if isTIL(ast) {
+			tils = append(tils, ast)
+		} else {
+			posts = append(posts, ast)
+		}
 	}
-	featureSet.Add(mdctx.FeatureKatex)
+	return posts, tils
+}
+
+// isTIL returns true if the AST was parsed from a file in the TIL dir.
+func isTIL(ast *markdown.AST) bool {
+	return strings.Contains(ast.Path, "/"+dirs.TIL+"/")
+}
+
+// indexPagePath returns the URL path of the 1-based page of the main index.
+func indexPagePath(page int) string {
+	if page == 1 {
+		return "/"
+	}
+	return "/" + indexPagesDir + "/" + strconv.Itoa(page) + "/"
+}
 
+// compilePages compiles the main index of posts split into pages of
+// ic.pageSize posts. The first page is the homepage.
+func (ic *IndexCompiler) compilePages(asts []*markdown.AST) error {
 	posts, err := ic.renderASTs(asts)
 	if err != nil {
 		return fmt.Errorf("compileAST asts for index: %w", err)
 	}
+	numPages := max(1, (len(posts)+ic.pageSize-1)/ic.pageSize)
 
-	if err := os.MkdirAll(ic.distDir, 0o755); err != nil {
-		return fmt.Errorf("make dir for index: %w", err)
+	// Remove pages from previous builds so the dev server doesn't serve stale
+	// pages after the number of pages shrinks.
+	if err := os.RemoveAll(filepath.Join(ic.distDir, indexPagesDir)); err != nil {
+		return fmt.Errorf("remove old index pages: %w", err)
+	}
+	for page := 1; page <= numPages; page++ {
+		start := (page - 1) * ic.pageSize
+		end := min(start+ic.pageSize, len(posts))
+		data := html.IndexParams{
+			Title:    siteTitle,
+			Posts:    posts[start:end],
+			Features: indexFeatures(asts),
+			Page:     page,
+			NumPages: numPages,
+		}
+		if page > 1 {
+			data.Title = siteTitle + " - Page " + strconv.Itoa(page)
+			data.PrevPath = indexPagePath(page - 1)
+		}
+		if page < numPages {
+			data.NextPath = indexPagePath(page + 1)
+		}
+		path := filepath.Join(indexPagePath(page), "index.html")
+		err := writeDistFile(ic.distDir, path, func(w io.Writer) error {
+			return html.RenderIndex(w, data)
+		})
+		if err != nil {
+			return fmt.Errorf("write index page %d: %w", page, err)
+		}
 	}
-	dest := filepath.Join(ic.distDir, "index.html")
+	return nil
+}
 
-	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+// compileTILIndex compiles the /til/ index listing every TIL.
+func (ic *IndexCompiler) compileTILIndex(asts []*markdown.AST) error {
+	tils, err := ic.renderASTs(asts)
 	if err != nil {
-		return fmt.Errorf("open index.html file for write: %w", err)
+		return fmt.Errorf("compileAST asts for TIL index: %w", err)
 	}
 	data := html.IndexParams{
-		Title:    "Joe Schafer's Blog",
-		Posts:    posts,
-		Features: featureSet,
+		Title:    "Today I learned - " + siteTitle,
+		Heading:  "Today I learned",
+		Posts:    tils,
+		Features: indexFeatures(asts),
 	}
-	if err := html.RenderIndex(destFile, data); err != nil {
-		return fmt.Errorf("execute index template: %w", err)
+	path := filepath.Join(tilIndexPath, "index.html")
+	err = writeDistFile(ic.distDir, path, func(w io.Writer) error {
+		return html.RenderIndex(w, data)
+	})
+	if err != nil {
+		return fmt.Errorf("write TIL index: %w", err)
 	}
-
 	return nil
 }
+
+// indexFeatures returns the features needed to render titles of the ASTs on an
+// index page.
+func indexFeatures(asts []*markdown.AST) *mdctx.FeatureSet {
+	featureSet := mdctx.NewFeatureSet()
+	for _, ast := range asts {
+		featureSet.AddAll(ast.Features)
+	}
+	featureSet.Add(mdctx.FeatureKatex)
+	return featureSet
+}
@@ -0,0 +1,89 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/jsc/pkg/markdown"
+)
+
+func TestIndexCompiler_CompileASTs(t *testing.T) {
+	distDir := t.TempDir()
+	cfg := DefaultSiteConfig()
+	cfg.PageSize = 2
+	ic := NewIndexCompiler(distDir, cfg)
+	var asts []*markdown.AST
+	// Newest first, as returned by ParseASTs.
+	for i, path := range []string{
+		"/md/test/posts/post5.md",
+		"/md/test/til/til2.md",
+		"/md/test/posts/post4.md",
+		"/md/test/posts/post3.md",
+		"/md/test/til/til1.md",
+		"/md/test/posts/post2.md",
+		"/md/test/posts/post1.md",
+	} {
+		slug := strings.TrimSuffix(filepath.Base(path), ".md")
+		src := fmt.Sprintf("+++\nslug = %q\ndate = 2020-01-%02d\nvisibility = \"published\"\n+++\n# Title %s\n", slug, 20-i, slug)
+		ast, err := ic.Markdown().Parse(path, strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		asts = append(asts, ast)
+	}
+	if err := ic.CompileASTs(asts); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{
+			"index.html",
+			[]string{`href="/post5"`, `href="/post4"`, `href="/page/2/" rel="next"`, "Page 1 of 3"},
+			[]string{`href="/post3"`, `href="/til2"`, `rel="prev"`},
+		},
+		{
+			"page/2/index.html",
+			[]string{`href="/post3"`, `href="/post2"`, `href="/" rel="prev"`, `href="/page/3/" rel="next"`},
+			[]string{`href="/post4"`, `href="/post1"`},
+		},
+		{
+			"page/3/index.html",
+			[]string{`href="/post1"`, `href="/page/2/" rel="prev"`},
+			[]string{`href="/post2"`, `rel="next"`},
+		},
+		{
+			"til/index.html",
+			[]string{`<h1 class="title">Today I learned</h1>`, `href="/til2"`, `href="/til1"`},
+			[]string{`href="/post`, "index-pager"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(distDir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(b)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s missing %q; got:\n%s", tt.file, want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("%s contains %q; got:\n%s", tt.file, notWant, got)
+				}
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(distDir, "page", "4")); !os.IsNotExist(err) {
+		t.Errorf("want no page 4; got stat err %v", err)
+	}
+}
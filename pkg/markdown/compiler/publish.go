@@ -0,0 +1,38 @@
+package compiler
+
+import (
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+// PublishMode determines which posts a compiler writes to the dist dir.
+type PublishMode int
+
+const (
+	// PublishModeProd only writes published posts. Drafts and posts dated in the
+	// future are skipped so they never reach the deployed site.
+	PublishModeProd PublishMode = iota
+	// PublishModePreview writes every post and marks unpublished posts with a
+	// draft banner. Used by the dev server.
+	PublishModePreview
+)
+
+// shouldWrite returns true if the post with meta should be written in the
+// publish mode.
+func (m PublishMode) shouldWrite(meta mdext.PostMeta, now time.Time) bool {
+	return m == PublishModePreview || meta.IsPublished(now)
+}
+
+// draftBanner returns the text of the banner shown on an unpublished post in
+// preview mode. Returns the empty string for published posts.
+func draftBanner(meta mdext.PostMeta, now time.Time) string {
+	switch {
+	case meta.IsPublished(now):
+		return ""
+	case meta.Visibility != mdext.VisibilityPublished:
+		return "Draft"
+	default:
+		return "Draft: scheduled for " + meta.Date.Format("January 2, 2006")
+	}
+}
@@ -0,0 +1,61 @@
+package compiler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+func TestDraftBanner(t *testing.T) {
+	now := time.Date(2021, time.March, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		meta        mdext.PostMeta
+		wantBanner  string
+		wantProd    bool
+		wantPreview bool
+	}{
+		{
+			"published",
+			mdext.PostMeta{Visibility: mdext.VisibilityPublished, Date: now.AddDate(0, 0, -1)},
+			"",
+			true,
+			true,
+		},
+		{
+			"published today",
+			mdext.PostMeta{Visibility: mdext.VisibilityPublished, Date: time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)},
+			"",
+			true,
+			true,
+		},
+		{
+			"draft",
+			mdext.PostMeta{Visibility: mdext.VisibilityDraft, Date: now.AddDate(0, 0, -1)},
+			"Draft",
+			false,
+			true,
+		},
+		{
+			"scheduled",
+			mdext.PostMeta{Visibility: mdext.VisibilityPublished, Date: time.Date(2021, time.March, 5, 0, 0, 0, 0, time.UTC)},
+			"Draft: scheduled for March 5, 2021",
+			false,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := draftBanner(tt.meta, now); got != tt.wantBanner {
+				t.Errorf("draftBanner() = %q; want %q", got, tt.wantBanner)
+			}
+			if got := PublishModeProd.shouldWrite(tt.meta, now); got != tt.wantProd {
+				t.Errorf("PublishModeProd.shouldWrite() = %t; want %t", got, tt.wantProd)
+			}
+			if got := PublishModePreview.shouldWrite(tt.meta, now); got != tt.wantPreview {
+				t.Errorf("PublishModePreview.shouldWrite() = %t; want %t", got, tt.wantPreview)
+			}
+		})
+	}
+}
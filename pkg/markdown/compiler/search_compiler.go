@@ -0,0 +1,75 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jschaf/jsc/pkg/markdown/html"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/search"
+)
+
+const (
+	searchIndexPath = "/search-index.json"
+	searchPagePath  = "/search/"
+)
+
+// SearchCompiler compiles the full-text search index, /search-index.json
+// with shards below /search-index/, and the /search/ page that queries the
+// index in the browser.
+type SearchCompiler struct {
+	distDir string
+}
+
+func NewSearchCompiler(distDir string) *SearchCompiler {
+	return &SearchCompiler{distDir: distDir}
+}
+
+// CompileDocs writes the search index for the docs, typically from
+// search.Index.Docs, and the search page.
+func (sc *SearchCompiler) CompileDocs(docs []search.Doc) error {
+	manifest, shards := search.Build(docs)
+
+	// Remove shards from previous builds so a shard for terms that no longer
+	// exist doesn't linger in the dev server.
+	if err := os.RemoveAll(filepath.Join(sc.distDir, filepath.Dir(search.ShardPath("_")))); err != nil {
+		return fmt.Errorf("remove old search shards: %w", err)
+	}
+	for key, shard := range shards {
+		if err := writeDistFile(sc.distDir, search.ShardPath(key), func(w io.Writer) error {
+			return writeJSON(w, shard)
+		}); err != nil {
+			return fmt.Errorf("write search shard %q: %w", key, err)
+		}
+	}
+	if err := writeDistFile(sc.distDir, searchIndexPath, func(w io.Writer) error {
+		return writeJSON(w, manifest)
+	}); err != nil {
+		return fmt.Errorf("write search index: %w", err)
+	}
+
+	data := html.SearchParams{
+		Title:    "Search - " + siteTitle,
+		Features: mdctx.NewFeatureSet(),
+	}
+	if err := writeDistFile(sc.distDir, filepath.Join(searchPagePath, "index.html"), func(w io.Writer) error {
+		return html.RenderSearch(w, data)
+	}); err != nil {
+		return fmt.Errorf("write search page: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes v as compact JSON. The search index is fetched by the
+// browser, so skip indentation to keep it small.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown/search"
+)
+
+func TestSearchCompiler_CompileDocs(t *testing.T) {
+	distDir := t.TempDir()
+	stale := filepath.Join(distDir, "search-index", "z.json")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	docs := []search.Doc{{
+		Path:  "/queries/",
+		Title: "Fast <queries>",
+		Date:  time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC),
+		Terms: map[string]int{"fast": 11, "query": 10},
+	}}
+	if err := NewSearchCompiler(distDir).CompileDocs(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"search-index.json", `{"docs":[{"p":"/queries/","t":"Fast <queries>","d":"2021-03-04"}],"shards":["f","q"]}` + "\n"},
+		{"search-index/f.json", `{"fast":[0,11]}` + "\n"},
+		{"search-index/q.json", `{"query":[0,10]}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(distDir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tt.want {
+				t.Errorf("%s mismatch:\nwant: %s\ngot:  %s", tt.file, tt.want, got)
+			}
+		})
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("want stale shard removed; got err %v", err)
+	}
+	page, err := os.ReadFile(filepath.Join(distDir, "search", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `class="search-input"`) {
+		t.Errorf("search page missing search input; got:\n%s", page)
+	}
+}
@@ -0,0 +1,85 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jschaf/jsc/pkg/errs"
+	"github.com/jschaf/jsc/pkg/images"
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/assets"
+	"github.com/jschaf/jsc/pkg/markdown/html"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+const (
+	// SiteURL is the origin of the published site without a trailing slash.
+	SiteURL    = "https://joe.schafer.dev"
+	siteTitle  = "Joe Schafer's Blog"
+	siteAuthor = "Joe Schafer"
+)
+
+// pageURL returns the absolute, canonical URL of the page for a post or book
+// chapter. The path of the post has a trailing slash, like /foo/, so the
+// sitemap, the feeds, and the canonical link all agree.
+func pageURL(meta mdext.PostMeta) string {
+	return SiteURL + meta.Path
+}
+
+// socialMeta returns the metadata for search engines and shared links of the
+// page for a post.
+func socialMeta(meta mdext.PostMeta) html.SocialMeta {
+	return html.SocialMeta{
+		URL:         pageURL(meta),
+		Title:       meta.Title,
+		Description: meta.Description,
+		Image:       absoluteURL(meta.Image),
+		Date:        meta.Date,
+		Author:      siteAuthor,
+		SiteName:    siteTitle,
+	}
+}
+
+// socialCardName is the file name of the generated social preview image in
+// the dir of the post.
+const socialCardName = "social.png"
+
+// addSocialCard sets the social preview image of a post without an image to
+// a card generated from the title and date. Must be called before socialMeta.
+func addSocialCard(a *markdown.AST) {
+	if a.Meta.Image != "" {
+		return
+	}
+	card := images.Card{Title: a.Meta.Title, Date: a.Meta.Date, Site: siteTitle}
+	dest := path.Join(a.Meta.Path, socialCardName)
+	a.Assets = append(a.Assets, assets.Blob{Dest: dest, GenFunc: card.RenderCached})
+	a.Meta.Image = dest
+}
+
+// absoluteURL returns the absolute URL of a URL path on the site. Returns
+// URLs and the empty string unchanged.
+func absoluteURL(p string) string {
+	if strings.HasPrefix(p, "/") {
+		return SiteURL + p
+	}
+	return p
+}
+
+// writeDistFile writes the file at path, relative to distDir, with write.
+// Creates parent dirs as needed and truncates existing files.
+func writeDistFile(distDir, path string, write func(io.Writer) error) (mErr error) {
+	dest := filepath.Join(distDir, path)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("make dir for %s: %w", path, err)
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("open %s for write: %w", path, err)
+	}
+	defer errs.Capture(&mErr, f.Close, "close "+path)
+	return write(f)
+}
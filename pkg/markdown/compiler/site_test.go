@@ -0,0 +1,67 @@
+package compiler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/html"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+func TestSocialMeta(t *testing.T) {
+	date := time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{"site image", "/foo/cover.png", SiteURL + "/foo/cover.png"},
+		{"remote image", "https://example.com/cover.png", "https://example.com/cover.png"},
+		{"no image", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := socialMeta(mdext.PostMeta{
+				Path:        "/foo/",
+				Title:       "Foo",
+				Description: "A foo.",
+				Image:       tt.image,
+				Date:        date,
+			})
+			want := html.SocialMeta{
+				URL:         SiteURL + "/foo/",
+				Title:       "Foo",
+				Description: "A foo.",
+				Image:       tt.want,
+				Date:        date,
+				Author:      siteAuthor,
+				SiteName:    siteTitle,
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("socialMeta mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAddSocialCard(t *testing.T) {
+	t.Run("no image", func(t *testing.T) {
+		a := &markdown.AST{Meta: mdext.PostMeta{Path: "/foo/", Title: "Foo"}}
+		addSocialCard(a)
+		if a.Meta.Image != "/foo/social.png" {
+			t.Errorf("image = %q; want /foo/social.png", a.Meta.Image)
+		}
+		if len(a.Assets) != 1 || a.Assets[0].Dest != "/foo/social.png" || a.Assets[0].GenFunc == nil {
+			t.Errorf("want one generated social card asset, got %+v", a.Assets)
+		}
+	})
+	t.Run("image", func(t *testing.T) {
+		a := &markdown.AST{Meta: mdext.PostMeta{Path: "/foo/", Title: "Foo", Image: "/foo/cover.png"}}
+		addSocialCard(a)
+		if a.Meta.Image != "/foo/cover.png" || len(a.Assets) != 0 {
+			t.Errorf("want image unchanged without assets, got %q and %+v", a.Meta.Image, a.Assets)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package compiler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+const (
+	sitemapPath = "/sitemap.xml"
+	robotsPath  = "/robots.txt"
+)
+
+// SitemapCompiler compiles /sitemap.xml, listing every published page, and
+// /robots.txt, which points crawlers at the sitemap.
+type SitemapCompiler struct {
+	distDir string
+}
+
+func NewSitemapCompiler(distDir string) *SitemapCompiler {
+	return &SitemapCompiler{distDir: distDir}
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// CompileASTs writes the sitemap and robots.txt for the ASTs returned by
+// IndexCompiler.ParseASTs and the book chapters returned by
+// BookCompiler.Compile. Drafts are omitted.
+func (sc *SitemapCompiler) CompileASTs(asts, chapters []*markdown.AST) error {
+	if err := writeDistFile(sc.distDir, sitemapPath, func(w io.Writer) error {
+		return writeXML(w, buildSitemap(asts, chapters))
+	}); err != nil {
+		return fmt.Errorf("write sitemap: %w", err)
+	}
+	if err := writeDistFile(sc.distDir, robotsPath, writeRobots); err != nil {
+		return fmt.Errorf("write robots.txt: %w", err)
+	}
+	return nil
+}
+
+// staticPages are the URL paths of the pages compiled once for the site
+// rather than once per post, in sitemap order.
+var staticPages = []string{"/", tilIndexPath, tagsIndexPath, bookPath, searchPagePath}
+
+// buildSitemap creates sitemap entries for the staticPages, tag pages, and all
+// published posts and chapters. Listing pages change whenever a listed post
+// changes, so the lastmod of a listing page is the newest listed post date.
+// The TIL index, tags index, and book are omitted when they list nothing.
+func buildSitemap(asts, chapters []*markdown.AST) sitemapURLSet {
+	now := time.Now()
+	var newest, tilNewest, bookNewest time.Time
+	tagNewest := make(map[string]time.Time)
+	posts := make([]sitemapURL, 0, len(asts)+len(chapters))
+	for _, ast := range asts {
+		if !ast.Meta.IsPublished(now) {
+			continue
+		}
+		if ast.Meta.Date.After(newest) {
+			newest = ast.Meta.Date
+		}
+		if isTIL(ast) && ast.Meta.Date.After(tilNewest) {
+			tilNewest = ast.Meta.Date
+		}
+		for _, tag := range ast.Meta.Tags {
+			if ast.Meta.Date.After(tagNewest[tag]) {
+				tagNewest[tag] = ast.Meta.Date
+			}
+		}
+		posts = append(posts, sitemapURL{
+			Loc:     pageURL(ast.Meta),
+			LastMod: sitemapDate(ast.Meta.Date),
+		})
+	}
+	for _, ch := range chapters {
+		if !ch.Meta.IsPublished(now) {
+			continue
+		}
+		if ch.Meta.Date.After(bookNewest) {
+			bookNewest = ch.Meta.Date
+		}
+		posts = append(posts, sitemapURL{
+			Loc:     pageURL(ch.Meta),
+			LastMod: sitemapDate(ch.Meta.Date),
+		})
+	}
+
+	lastMods := map[string]time.Time{
+		"/":            newest,
+		searchPagePath: newest,
+	}
+	if !tilNewest.IsZero() {
+		lastMods[tilIndexPath] = tilNewest
+	}
+	if len(tagNewest) > 0 {
+		lastMods[tagsIndexPath] = newest
+	}
+	if !bookNewest.IsZero() {
+		lastMods[bookPath] = bookNewest
+	}
+	urls := make([]sitemapURL, 0, len(staticPages)+len(tagNewest)+len(posts))
+	for _, path := range staticPages {
+		lastMod, ok := lastMods[path]
+		if !ok {
+			continue
+		}
+		urls = append(urls, sitemapURL{Loc: SiteURL + path, LastMod: sitemapDate(lastMod)})
+	}
+
+	tags := make([]string, 0, len(tagNewest))
+	for tag := range tagNewest {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		urls = append(urls, sitemapURL{
+			Loc:     SiteURL + mdext.Tag{Slug: tag}.Path(),
+			LastMod: sitemapDate(tagNewest[tag]),
+		})
+	}
+	return sitemapURLSet{URLs: append(urls, posts...)}
+}
+
+// sitemapDate formats t in the W3C date format used by sitemaps. Returns an
+// empty string, omitting lastmod, for the zero time.
+func sitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
+
+func writeRobots(w io.Writer) error {
+	_, err := io.WriteString(w, "User-agent: *\nAllow: /\n\nSitemap: "+SiteURL+sitemapPath+"\n")
+	return err
+}
@@ -0,0 +1,132 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+func TestSitemapCompiler_CompileASTs(t *testing.T) {
+	asts := []*markdown.AST{
+		{Meta: mdext.PostMeta{
+			Slug:       "draft",
+			Path:       "/draft/",
+			Date:       time.Date(2022, time.May, 6, 0, 0, 0, 0, time.UTC),
+			Visibility: mdext.VisibilityDraft,
+		}},
+		{Meta: mdext.PostMeta{
+			Slug:       "newer",
+			Path:       "/newer/",
+			Date:       time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC),
+			Visibility: mdext.VisibilityPublished,
+			Tags:       []string{"go"},
+		}},
+		{Meta: mdext.PostMeta{
+			Slug:       "older",
+			Path:       "/older/",
+			Date:       time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC),
+			Visibility: mdext.VisibilityPublished,
+			Tags:       []string{"go", "statistics"},
+		}},
+	}
+	chapters := []*markdown.AST{
+		{Meta: mdext.PostMeta{
+			Slug:       "chapter",
+			Path:       "/book/chapter/",
+			Date:       time.Date(2020, time.June, 7, 0, 0, 0, 0, time.UTC),
+			Visibility: mdext.VisibilityPublished,
+		}},
+		{Meta: mdext.PostMeta{
+			Slug:       "draft-chapter",
+			Path:       "/book/draft-chapter/",
+			Date:       time.Date(2020, time.July, 8, 0, 0, 0, 0, time.UTC),
+			Visibility: mdext.VisibilityDraft,
+		}},
+	}
+	distDir := t.TempDir()
+	if err := NewSitemapCompiler(distDir).CompileASTs(asts, chapters); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{
+			"sitemap.xml",
+			[]string{
+				`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`,
+				"<loc>https://joe.schafer.dev/</loc>\n    <lastmod>2021-03-04</lastmod>",
+				"<loc>https://joe.schafer.dev/tags/</loc>\n    <lastmod>2021-03-04</lastmod>",
+				"<loc>https://joe.schafer.dev/search/</loc>\n    <lastmod>2021-03-04</lastmod>",
+				"<loc>https://joe.schafer.dev/tags/go/</loc>\n    <lastmod>2021-03-04</lastmod>",
+				"<loc>https://joe.schafer.dev/tags/statistics/</loc>\n    <lastmod>2020-01-02</lastmod>",
+				"<loc>https://joe.schafer.dev/newer/</loc>\n    <lastmod>2021-03-04</lastmod>",
+				"<loc>https://joe.schafer.dev/older/</loc>\n    <lastmod>2020-01-02</lastmod>",
+				"<loc>https://joe.schafer.dev/book/</loc>\n    <lastmod>2020-06-07</lastmod>",
+				"<loc>https://joe.schafer.dev/book/chapter/</loc>\n    <lastmod>2020-06-07</lastmod>",
+			},
+			[]string{"draft", "2022-05-06", "2020-07-08"},
+		},
+		{
+			"robots.txt",
+			[]string{"User-agent: *\n", "Sitemap: https://joe.schafer.dev/sitemap.xml\n"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(distDir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(b)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s missing %q; got:\n%s", tt.file, want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("%s contains %q; got:\n%s", tt.file, notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildSitemap_StaticPages(t *testing.T) {
+	date := time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)
+	asts := []*markdown.AST{{
+		Path: filepath.Join("posts", "til", "til.md"),
+		Meta: mdext.PostMeta{
+			Slug:       "til",
+			Path:       "/til/til/",
+			Date:       date,
+			Visibility: mdext.VisibilityPublished,
+			Tags:       []string{"go"},
+		},
+	}}
+	chapters := []*markdown.AST{{Meta: mdext.PostMeta{
+		Slug:       "chapter",
+		Path:       "/book/chapter/",
+		Date:       date,
+		Visibility: mdext.VisibilityPublished,
+	}}}
+
+	got := make(map[string]bool)
+	for _, u := range buildSitemap(asts, chapters).URLs {
+		got[u.Loc] = true
+	}
+	for _, path := range staticPages {
+		if !got[SiteURL+path] {
+			t.Errorf("buildSitemap missing static page %s", path)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/html"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+)
+
+const (
+	tagsDir = "tags"
+	// tagsIndexPath is the URL path of the page listing all tags.
+	tagsIndexPath = "/" + tagsDir + "/"
+)
+
+// TagCompiler compiles the /tags/ page, listing all tags, and a /tags/<tag>/
+// page for each tag, listing the posts with the tag.
+type TagCompiler struct {
+	// Renders the ASTs from IndexCompiler.ParseASTs.
+	md      *markdown.Markdown
+	distDir string
+	mode    PublishMode
+}
+
+// NewTagCompiler creates a tag compiler that renders ASTs parsed by md,
+// typically IndexCompiler.Markdown. In preview mode, tag pages also list
+// unpublished posts so the tag links on a draft resolve.
+func NewTagCompiler(distDir string, md *markdown.Markdown, mode PublishMode) *TagCompiler {
+	return &TagCompiler{md: md, distDir: distDir, mode: mode}
+}
+
+// taggedPosts is a tag and the posts with the tag, newest first.
+type taggedPosts struct {
+	tag   mdext.Tag
+	posts []html.IndexPostParams
+	feats *mdctx.FeatureSet
+}
+
+// CompileASTs writes the tag pages for the ASTs written in the publish mode.
+// The ASTs must be sorted newest first, as returned by IndexCompiler.ParseASTs.
+func (tc *TagCompiler) CompileASTs(asts []*markdown.AST) error {
+	now := time.Now()
+	byTag := make(map[string]*taggedPosts)
+	for _, ast := range asts {
+		if !tc.mode.shouldWrite(ast.Meta, now) {
+			continue
+		}
+		titleHTML, err := renderTitle(tc.md, ast)
+		if err != nil {
+			return fmt.Errorf("render tag page title for %s: %w", ast.Path, err)
+		}
+		for _, slug := range ast.Meta.Tags {
+			tag, err := mdext.LookupTag(slug)
+			if err != nil {
+				return fmt.Errorf("tag for %s: %w", ast.Path, err)
+			}
+			tp, ok := byTag[slug]
+			if !ok {
+				tp = &taggedPosts{tag: tag, feats: mdctx.NewFeatureSet()}
+				byTag[slug] = tp
+			}
+			// Only load features, like KaTeX, used by a listed post.
+			tp.feats.AddAll(ast.Features)
+			tp.posts = append(tp.posts, html.IndexPostParams{
+				Title:     ast.Meta.Title,
+				TitleHTML: titleHTML,
+				Slug:      ast.Meta.Slug,
+				Date:      ast.Meta.Date,
+			})
+		}
+	}
+
+	tags := make([]*taggedPosts, 0, len(byTag))
+	for _, tp := range byTag {
+		tags = append(tags, tp)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].tag.Name < tags[j].tag.Name })
+
+	// Remove tag pages from previous builds so a tag without posts doesn't
+	// linger in the dev server.
+	if err := os.RemoveAll(filepath.Join(tc.distDir, tagsDir)); err != nil {
+		return fmt.Errorf("remove old tag pages: %w", err)
+	}
+	if err := tc.writeTagIndex(tags); err != nil {
+		return err
+	}
+	for _, tp := range tags {
+		if err := tc.writeTagPage(tp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tc *TagCompiler) writeTagIndex(tags []*taggedPosts) error {
+	data := html.TagsParams{
+		Title:    "Tags - " + siteTitle,
+		Features: mdctx.NewFeatureSet(),
+		Tags:     make([]html.TagParams, 0, len(tags)),
+	}
+	for _, tp := range tags {
+		data.Tags = append(data.Tags, html.TagParams{
+			Name:  tp.tag.Name,
+			Path:  tp.tag.Path(),
+			Count: len(tp.posts),
+		})
+	}
+	return writeDistFile(tc.distDir, filepath.Join(tagsDir, "index.html"), func(w io.Writer) error {
+		return html.RenderTags(w, data)
+	})
+}
+
+func (tc *TagCompiler) writeTagPage(tp *taggedPosts) error {
+	data := html.IndexParams{
+		Title:    tp.tag.Name + " - " + siteTitle,
+		Heading:  tp.tag.Name,
+		Posts:    tp.posts,
+		Features: tp.feats,
+	}
+	return writeDistFile(tc.distDir, filepath.Join(tagsDir, tp.tag.Slug, "index.html"), func(w io.Writer) error {
+		return html.RenderIndex(w, data)
+	})
+}
@@ -0,0 +1,114 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/texts"
+)
+
+func TestTagCompiler_CompileASTs(t *testing.T) {
+	distDir := t.TempDir()
+	md := NewIndexCompiler(distDir, DefaultSiteConfig()).Markdown()
+	srcs := []string{
+		texts.Dedent(`
+			+++
+			slug = "newer"
+			date = 2021-03-04
+			visibility = "published"
+			tags = ["go", "statistics"]
+			+++
+			# Newer post
+		`),
+		texts.Dedent(`
+			+++
+			slug = "older"
+			date = 2020-01-02
+			visibility = "published"
+			tags = ["go"]
+			+++
+			# Older post
+
+			Math $x^2$.
+		`),
+		texts.Dedent(`
+			+++
+			slug = "draft"
+			date = 2022-05-06
+			visibility = "draft"
+			tags = ["go", "databases"]
+			+++
+			# Draft post
+		`),
+	}
+	asts := make([]*markdown.AST, 0, len(srcs))
+	for _, src := range srcs {
+		ast, err := md.Parse("/md/test/path.md", strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		asts = append(asts, ast)
+	}
+	if err := NewTagCompiler(distDir, md, PublishModeProd).CompileASTs(asts); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{
+			"tags/index.html",
+			[]string{
+				`<a href="/tags/go/">Go</a> <span class="tag-count">2</span>`,
+				`<a href="/tags/statistics/">Statistics</a> <span class="tag-count">1</span>`,
+			},
+			[]string{"Databases"},
+		},
+		{
+			"tags/go/index.html",
+			[]string{`<h1 class="title">Go</h1>`, `href="/newer"`, `href="/older"`, `katex.min.css`},
+			[]string{"Draft post"},
+		},
+		{
+			"tags/statistics/index.html",
+			[]string{`href="/newer"`},
+			// No listed post uses math.
+			[]string{`href="/older"`, `katex.min.css`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(distDir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(b)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s missing %q; got:\n%s", tt.file, want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("%s contains %q; got:\n%s", tt.file, notWant, got)
+				}
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(distDir, "tags", "databases")); !os.IsNotExist(err) {
+		t.Errorf("want no tag page for tag only used by drafts; got stat err %v", err)
+	}
+
+	previewDir := t.TempDir()
+	if err := NewTagCompiler(previewDir, md, PublishModePreview).CompileASTs(asts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(previewDir, "tags", "databases", "index.html")); err != nil {
+		t.Errorf("want preview tag page for tag only used by drafts; got stat err %v", err)
+	}
+}
@@ -15,26 +15,84 @@ import (
 )
 
 var (
-	layoutDir = filepath.Join(git.RootDir(), dirs.Pkg, "markdown", "html")
-	baseTmpl  = filepath.Join(layoutDir, "base.gohtml")
-
-	indexTmpl = sync.OnceValue(func() *template.Template {
-		tmpl := filepath.Join(layoutDir, "index.gohtml")
-		return template.Must(
-			template.New("index").Funcs(TemplateFuncs()).ParseFiles(tmpl, baseTmpl))
-	})
-
-	detailTmpl = sync.OnceValue(func() *template.Template {
-		tmpl := filepath.Join(layoutDir, "detail.gohtml")
-		return template.Must(
-			template.New("detail").Funcs(TemplateFuncs()).ParseFiles(tmpl, baseTmpl))
-	})
+	LayoutDir = filepath.Join(git.RootDir(), dirs.Pkg, "markdown", "html")
+	baseTmpl  = filepath.Join(LayoutDir, "base.gohtml")
+
+	indexTmpl   = newLazyTemplate("index", "index.gohtml")
+	detailTmpl  = newLazyTemplate("detail", "detail.gohtml")
+	tagsTmpl    = newLazyTemplate("tags", "tags.gohtml")
+	bookTmpl    = newLazyTemplate("book", "book.gohtml")
+	chapterTmpl = newLazyTemplate("chapter", "chapter.gohtml")
+	searchTmpl  = newLazyTemplate("search", "search.gohtml")
+	historyTmpl = newLazyTemplate("history", "history.gohtml")
+
+	allTmpls = []*lazyTemplate{indexTmpl, detailTmpl, tagsTmpl, bookTmpl, chapterTmpl, searchTmpl, historyTmpl}
 )
 
+// lazyTemplate parses a template along with the base template on first use.
+// Unlike sync.OnceValue, the template can be reset so the dev server picks up
+// template changes without restarting.
+type lazyTemplate struct {
+	name string
+	file string
+	mu   sync.Mutex
+	tmpl *template.Template
+}
+
+func newLazyTemplate(name, file string) *lazyTemplate {
+	return &lazyTemplate{name: name, file: filepath.Join(LayoutDir, file)}
+}
+
+// get returns the parsed template. Returns the parse error instead of
+// panicking, like for a typo saved while editing a template in the dev server,
+// and reparses on the next call.
+func (lt *lazyTemplate) get() (*template.Template, error) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	if lt.tmpl == nil {
+		tmpl, err := template.New(lt.name).Funcs(TemplateFuncs()).ParseFiles(lt.file, baseTmpl)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s template: %w", lt.name, err)
+		}
+		lt.tmpl = tmpl
+	}
+	return lt.tmpl, nil
+}
+
+func (lt *lazyTemplate) reset() {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.tmpl = nil
+}
+
+// ResetTemplates discards all parsed templates so the next render reparses
+// the template files from disk.
+func ResetTemplates() {
+	for _, t := range allTmpls {
+		t.reset()
+	}
+}
+
+// IsTemplate returns true if path is a template file used to render pages.
+func IsTemplate(path string) bool {
+	return filepath.Dir(path) == LayoutDir && filepath.Ext(path) == ".gohtml"
+}
+
 type IndexParams struct {
-	Title    string
+	Title string
+	// Heading is shown above the posts if not empty, like the tag name on a tag
+	// page.
+	Heading  string
 	Features *mdctx.FeatureSet
 	Posts    []IndexPostParams
+	// The 1-based page number and number of pages of a paginated index. Zero
+	// for indexes without pagination.
+	Page     int
+	NumPages int
+	// URL paths of the previous (newer) and next (older) pages. Empty if there's
+	// no such page.
+	PrevPath string
+	NextPath string
 }
 
 type IndexPostParams struct {
@@ -46,23 +104,230 @@ type IndexPostParams struct {
 }
 
 func RenderIndex(w io.Writer, p IndexParams) error {
-	err := indexTmpl().ExecuteTemplate(w, "base", p)
+	tmpl, err := indexTmpl.get()
 	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(w, "base", p); err != nil {
 		return fmt.Errorf("execute index template: %w", err)
 	}
 	return nil
 }
 
+// SocialMeta describes a page for search engines and for previews of shared
+// links, rendered as OpenGraph, Twitter card, and JSON-LD metadata.
+type SocialMeta struct {
+	// The absolute canonical URL of the page.
+	URL         string
+	Title       string
+	Description string
+	// The absolute URL of the image shown on shared links. Empty for no image.
+	Image string
+	Date  time.Time
+	// The date of the last meaningful change, or zero if never updated.
+	Modified time.Time
+	Author   string
+	SiteName string
+}
+
+// JSONLD returns the BlogPosting structured data of the page. The template
+// encodes the value as JSON in a script tag.
+func (s SocialMeta) JSONLD() map[string]any {
+	ld := map[string]any{
+		"@context":         "https://schema.org",
+		"@type":            "BlogPosting",
+		"headline":         s.Title,
+		"url":              s.URL,
+		"mainEntityOfPage": s.URL,
+		"author":           map[string]string{"@type": "Person", "name": s.Author},
+	}
+	if s.Description != "" {
+		ld["description"] = s.Description
+	}
+	if s.Image != "" {
+		ld["image"] = s.Image
+	}
+	if !s.Date.IsZero() {
+		ld["datePublished"] = s.Date.Format(time.DateOnly)
+	}
+	if !s.Modified.IsZero() {
+		ld["dateModified"] = s.Modified.Format(time.DateOnly)
+	}
+	return ld
+}
+
 type DetailParams struct {
 	Title    string
 	Features *mdctx.FeatureSet
+	Social   SocialMeta
 	Content  template.HTML
+	// DraftBanner is the text of the banner shown on unpublished posts in
+	// preview builds. Empty hides the banner.
+	DraftBanner string
+	Revision    Revision
+	// The path of the EPUB of the post. Empty if the post has no EPUB.
+	EPUBPath string
+}
+
+// Revision is the revision history of a post from git, shown below the post
+// header.
+type Revision struct {
+	// The date of the last meaningful change after publishing. Zero hides the
+	// updated date.
+	Updated time.Time
+	// The path of the history page. Empty if the post has no history page.
+	HistoryPath string
 }
 
 func RenderDetail(w io.Writer, p DetailParams) error {
-	err := detailTmpl().ExecuteTemplate(w, "base", p)
+	tmpl, err := detailTmpl.get()
 	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(w, "base", p); err != nil {
 		return fmt.Errorf("execute detail template: %w", err)
 	}
 	return nil
 }
+
+type TagsParams struct {
+	Title    string
+	Features *mdctx.FeatureSet
+	Tags     []TagParams
+}
+
+type TagParams struct {
+	Name  string
+	Path  string
+	Count int
+}
+
+func RenderTags(w io.Writer, p TagsParams) error {
+	tmpl, err := tagsTmpl.get()
+	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(w, "base", p); err != nil {
+		return fmt.Errorf("execute tags template: %w", err)
+	}
+	return nil
+}
+
+// ChapterLink links to a chapter of a book.
+type ChapterLink struct {
+	// The 1-based number of the chapter in the book.
+	Num       int
+	Title     string
+	TitleHTML template.HTML
+	Path      string
+}
+
+type BookParams struct {
+	Title    string
+	Path     string
+	Features *mdctx.FeatureSet
+	Chapters []ChapterLink
+	// Bibliography contains every reference cited in any chapter.
+	Bibliography []BibEntryParams
+	// The path of the EPUB of the book. Empty if the book has no EPUB.
+	EPUBPath string
+}
+
+// BibEntryParams is a single reference in the bibliography of a book.
+type BibEntryParams struct {
+	ID      string
+	Content template.HTML
+	// The chapters that cite the reference in book order.
+	CitedIn []ChapterLink
+}
+
+func RenderBook(w io.Writer, p BookParams) error {
+	tmpl, err := bookTmpl.get()
+	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(w, "base", p); err != nil {
+		return fmt.Errorf("execute book template: %w", err)
+	}
+	return nil
+}
+
+type ChapterParams struct {
+	Title    string
+	Features *mdctx.FeatureSet
+	Social   SocialMeta
+	Content  template.HTML
+	// DraftBanner is the text of the banner shown on unpublished chapters in
+	// preview builds. Empty hides the banner.
+	DraftBanner string
+	BookTitle   string
+	BookPath    string
+	// All chapters in the book for the book table of contents.
+	Chapters []ChapterLink
+	// The number of the current chapter.
+	Num int
+	// Prev and Next are nil for the first and last chapter, respectively.
+	Prev *ChapterLink
+	Next *ChapterLink
+}
+
+func RenderChapter(w io.Writer, p ChapterParams) error {
+	tmpl, err := chapterTmpl.get()
+	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(w, "base", p); err != nil {
+		return fmt.Errorf("execute chapter template: %w", err)
+	}
+	return nil
+}
+
+type SearchParams struct {
+	Title    string
+	Features *mdctx.FeatureSet
+}
+
+func RenderSearch(w io.Writer, p SearchParams) error {
+	tmpl, err := searchTmpl.get()
+	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(w, "base", p); err != nil {
+		return fmt.Errorf("execute search template: %w", err)
+	}
+	return nil
+}
+
+// HistoryParams is the revision history page of a post, listing the commits
+// that touched the markdown file of the post.
+type HistoryParams struct {
+	Title     string
+	Features  *mdctx.FeatureSet
+	PostTitle string
+	PostPath  string
+	// The dates of the first and the newest commit.
+	Created      time.Time
+	LastModified time.Time
+	// Newest first.
+	Commits []HistoryCommit
+}
+
+type HistoryCommit struct {
+	Hash      string
+	ShortHash string
+	// The URL of the commit on GitHub, or empty if the site has no repo URL.
+	URL     string
+	Subject string
+	Date    time.Time
+}
+
+func RenderHistory(w io.Writer, p HistoryParams) error {
+	tmpl, err := historyTmpl.get()
+	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(w, "base", p); err != nil {
+		return fmt.Errorf("execute history template: %w", err)
+	}
+	return nil
+}
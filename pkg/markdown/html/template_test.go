@@ -3,8 +3,11 @@ package html
 import (
 	"bytes"
 	"html/template"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 )
@@ -29,6 +32,136 @@ func TestRenderPost(t *testing.T) {
 	}
 }
 
+func TestRenderPost_SocialMeta(t *testing.T) {
+	w := &bytes.Buffer{}
+	err := RenderDetail(w, DetailParams{
+		Title:    "foo_title",
+		Features: mdctx.NewFeatureSet(),
+		Social: SocialMeta{
+			URL:         "https://example.com/foo/",
+			Title:       "Foo & bar",
+			Description: "A </script> description.",
+			Image:       "https://example.com/foo/cover.png",
+			Date:        time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC),
+			Modified:    time.Date(2022, time.May, 6, 0, 0, 0, 0, time.UTC),
+			Author:      "Joe",
+			SiteName:    "Blog",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<link rel="canonical" href="https://example.com/foo/">`,
+		`<meta name="description" content="A &lt;/script&gt; description.">`,
+		`<meta property="og:title" content="Foo &amp; bar">`,
+		`<meta property="og:image" content="https://example.com/foo/cover.png">`,
+		`<meta property="article:published_time" content="2021-03-04">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+		`"@type":"BlogPosting"`,
+		`"datePublished":"2021-03-04"`,
+		`<meta property="article:modified_time" content="2022-05-06">`,
+		`"dateModified":"2022-05-06"`,
+		`"description":"A \u003c/script\u003e description."`,
+	} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("rendered content doesn't include %q:\n\n%s", want, w.String())
+		}
+	}
+}
+
+func TestRenderPost_Revision(t *testing.T) {
+	updated := time.Date(2022, time.May, 6, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		rev  Revision
+		want string
+	}{
+		{
+			"updated with history",
+			Revision{Updated: updated, HistoryPath: "/foo/history/"},
+			`<p class="post-revision">Updated <time datetime="2022-05-06">May 6, 2022</time> · <a href="/foo/history/">Revision history</a></p>`,
+		},
+		{
+			"history only",
+			Revision{HistoryPath: "/foo/history/"},
+			`<p class="post-revision"><a href="/foo/history/">Revision history</a></p>`,
+		},
+		{"none", Revision{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			err := RenderDetail(w, DetailParams{Title: "foo_title", Features: mdctx.NewFeatureSet(), Revision: tt.rev})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if strings.Contains(w.String(), "post-revision") {
+					t.Errorf("rendered content includes revision:\n\n%s", w.String())
+				}
+				return
+			}
+			if !strings.Contains(w.String(), tt.want) {
+				t.Errorf("rendered content doesn't include %q:\n\n%s", tt.want, w.String())
+			}
+		})
+	}
+}
+
+func TestRenderPost_EPUB(t *testing.T) {
+	w := &bytes.Buffer{}
+	err := RenderDetail(w, DetailParams{Title: "foo_title", Features: mdctx.NewFeatureSet(), EPUBPath: "/foo/foo.epub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<p class="epub-download"><a href="/foo/foo.epub" download>Download EPUB</a> for e-readers</p>`
+	if !strings.Contains(w.String(), want) {
+		t.Errorf("rendered content doesn't include %q:\n\n%s", want, w.String())
+	}
+}
+
+func TestRenderHistory(t *testing.T) {
+	w := &bytes.Buffer{}
+	date := time.Date(2022, time.May, 6, 12, 0, 0, 0, time.UTC)
+	err := RenderHistory(w, HistoryParams{
+		Title:        "Revision history - Foo",
+		Features:     mdctx.NewFeatureSet(),
+		PostTitle:    "Foo",
+		PostPath:     "/foo/",
+		Created:      date.AddDate(-1, 0, 0),
+		LastModified: date,
+		Commits: []HistoryCommit{
+			{Hash: "abcdef123", ShortHash: "abcdef1", URL: "https://example.com/commit/abcdef123", Subject: "Fix <typo>", Date: date},
+			{Hash: "012345678", ShortHash: "0123456", Subject: "Add foo", Date: date.AddDate(-1, 0, 0)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<a href="/foo/">Foo</a>`,
+		`<time datetime="2021-05-06">May 6, 2021</time>`,
+		`<a class="history-hash" href="https://example.com/commit/abcdef123"><code>abcdef1</code></a>`,
+		`<code class="history-hash">0123456</code>`,
+		`Fix &lt;typo&gt;`,
+	} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("rendered content doesn't include %q:\n\n%s", want, w.String())
+		}
+	}
+}
+
+func TestRenderIndex_NoSocialMeta(t *testing.T) {
+	w := &bytes.Buffer{}
+	if err := RenderIndex(w, IndexParams{Title: "foo_title", Features: mdctx.NewFeatureSet()}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(w.String(), "og:title") {
+		t.Errorf("index page includes social metadata:\n\n%s", w.String())
+	}
+}
+
 func TestRenderIndex(t *testing.T) {
 	w := &bytes.Buffer{}
 	title := "foo_title"
@@ -54,3 +187,22 @@ func TestRenderIndex(t *testing.T) {
 		t.Errorf("rendered content doesn't include %q:\n\n%s", "post2", w.String())
 	}
 }
+
+func TestLazyTemplate_ParseError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bad.gohtml")
+	if err := os.WriteFile(file, []byte(`{{ define "content" }}{{ .Title }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lt := &lazyTemplate{name: "bad", file: file}
+	if _, err := lt.get(); err == nil || !strings.Contains(err.Error(), "parse bad template") {
+		t.Fatalf("get() error = %v; want parse error", err)
+	}
+
+	// Fixing the typo parses the template on the next call.
+	if err := os.WriteFile(file, []byte(`{{ define "content" }}{{ .Title }}{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lt.get(); err != nil {
+		t.Fatalf("get() after fix: %v", err)
+	}
+}
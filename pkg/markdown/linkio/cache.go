@@ -0,0 +1,101 @@
+package linkio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jschaf/jsc/pkg/git"
+)
+
+// CacheFile is the name of the committed cache of link previews in the repo
+// root. Builds only read the cache so they're offline and deterministic. Run
+// cmd/preview to fetch previews for new links.
+const CacheFile = "link_previews.json"
+
+// CachePath returns the absolute path of the link preview cache.
+func CachePath() string {
+	return filepath.Join(git.RootDir(), CacheFile)
+}
+
+// Cache is an on-disk cache of link previews keyed by URL. Safe for concurrent
+// use.
+type Cache struct {
+	path     string
+	mu       sync.RWMutex
+	previews map[string]Preview
+}
+
+// NewCache returns an empty cache saved to path.
+func NewCache(path string) *Cache {
+	return &Cache{path: path, previews: make(map[string]Preview)}
+}
+
+// LoadCache reads the cache at path. A missing file is an empty cache.
+func LoadCache(path string) (*Cache, error) {
+	c := NewCache(path)
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read link preview cache: %w", err)
+	}
+	var previews []Preview
+	if err := json.Unmarshal(bs, &previews); err != nil {
+		return nil, fmt.Errorf("parse link preview cache %s: %w", path, err)
+	}
+	for _, p := range previews {
+		c.previews[p.URL] = p
+	}
+	return c, nil
+}
+
+// Get returns the cached preview for link. A nil cache is empty.
+func (c *Cache) Get(link string) (Preview, bool) {
+	if c == nil {
+		return Preview{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p, ok := c.previews[link]
+	return p, ok
+}
+
+// Put adds or replaces the preview for p.URL.
+func (c *Cache) Put(p Preview) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.previews[p.URL] = p
+}
+
+// Save writes the cache to its path, sorted by URL so that diffs of the
+// committed file stay small.
+func (c *Cache) Save() error {
+	c.mu.RLock()
+	previews := make([]Preview, 0, len(c.previews))
+	for _, p := range c.previews {
+		previews = append(previews, p)
+	}
+	c.mu.RUnlock()
+	slices.SortFunc(previews, func(a, b Preview) int {
+		return strings.Compare(a.URL, b.URL)
+	})
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // keep snippets readable
+	if err := enc.Encode(previews); err != nil {
+		return fmt.Errorf("marshal link preview cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write link preview cache: %w", err)
+	}
+	return nil
+}
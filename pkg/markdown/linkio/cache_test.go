@@ -0,0 +1,60 @@
+package linkio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCache_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), CacheFile)
+	c, err := LoadCache(path)
+	if err != nil {
+		t.Fatalf("load missing cache: %s", err)
+	}
+	if _, ok := c.Get("https://example.com/b"); ok {
+		t.Fatal("empty cache has preview")
+	}
+	fetched := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	b := Preview{URL: "https://example.com/b", Title: "B", SnippetHTML: "<p>b</p>", FetchTime: fetched}
+	a := Preview{URL: "https://example.com/a", Title: "A", FetchTime: fetched}
+	c.Put(b)
+	c.Put(a)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sorted by URL for stable diffs.
+	want := `[
+  {
+    "url": "https://example.com/a",
+    "title": "A",
+    "fetch_time": "2024-03-04T05:06:07Z"
+  },
+  {
+    "url": "https://example.com/b",
+    "title": "B",
+    "snippet_html": "<p>b</p>",
+    "fetch_time": "2024-03-04T05:06:07Z"
+  }
+]
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("saved cache mismatch (-want +got):\n%s", diff)
+	}
+
+	loaded, err := LoadCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := loaded.Get(b.URL); !cmp.Equal(b, p) {
+		t.Errorf("loaded preview mismatch (-want +got):\n%s", cmp.Diff(b, p))
+	}
+}
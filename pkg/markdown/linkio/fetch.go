@@ -2,9 +2,15 @@ package linkio
 
 import (
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"path"
+	"strings"
 	"time"
+
+	"github.com/jschaf/jsc/pkg/errs"
 )
 
 type FetchResult struct {
@@ -22,20 +28,93 @@ type Fetcher interface {
 	Fetch(link string) (FetchResult, error)
 }
 
+const (
+	// wikiSummaryURL is the Wikipedia REST endpoint for page summaries.
+	wikiSummaryURL = "https://en.wikipedia.org/api/rest_v1/page/summary/"
+	// userAgent identifies the fetcher. Wikipedia blocks requests without a
+	// descriptive user agent.
+	userAgent = "jsc-link-preview/1.0 (https://joe.schafer.dev)"
+	// maxDocSize is the most bytes read from a fetched doc. OpenGraph tags live
+	// in the head, so a prefix of large pages is enough.
+	maxDocSize = 2 << 20
+)
+
 // WikiSummaryFetcher fetches link summaries from Wikipedia.
-type WikiSummaryFetcher struct{}
+type WikiSummaryFetcher struct {
+	// The client for requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// The URL of the summary endpoint with a trailing slash. Defaults to the
+	// English Wikipedia summary endpoint.
+	BaseURL string
+}
 
 func (w WikiSummaryFetcher) Fetch(link string) (FetchResult, error) {
-	const url = "https://en.wikipedia.org/api/rest_v1/page/summary/"
-	name := path.Base(link)
-	if name == "" {
+	u, err := url.Parse(link)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("parse wiki link: %w", err)
+	}
+	name := path.Base(u.Path)
+	if name == "" || name == "." || name == "/" {
 		return FetchResult{}, fmt.Errorf("no base name for link %s", link)
 	}
-	resp, err := http.Get(path.Join(url, name))
+	base := w.BaseURL
+	if base == "" {
+		base = wikiSummaryURL
+	}
+	r, err := get(w.Client, base+url.PathEscape(name))
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("wiki summary fetcher: %w", err)
+	}
+	r.Path = link
+	return r, nil
+}
+
+// OpenGraphFetcher fetches the HTML page of a link to read the OpenGraph meta
+// tags.
+type OpenGraphFetcher struct {
+	// The client for requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+func (o OpenGraphFetcher) Fetch(link string) (FetchResult, error) {
+	r, err := get(o.Client, link)
 	if err != nil {
-		return FetchResult{}, fmt.Errorf("wiki summary fetcher GET: %w", err)
+		return FetchResult{}, fmt.Errorf("open graph fetcher: %w", err)
 	}
-	defer resp.Body.Close()
+	r.Path = link
+	return r, nil
+}
 
-	panic("implement me")
+// get fetches the doc at rawURL, failing on non-2xx responses.
+func get(client *http.Client, rawURL string) (r FetchResult, mErr error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("GET %s: %w", rawURL, err)
+	}
+	defer errs.Capture(&mErr, resp.Body.Close, "close response body")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return FetchResult{}, fmt.Errorf("GET %s: status %s", rawURL, resp.Status)
+	}
+	doc, err := io.ReadAll(io.LimitReader(resp.Body, maxDocSize))
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("read body %s: %w", rawURL, err)
+	}
+	mimeType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		mimeType = http.DetectContentType(doc)
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+	}
+	return FetchResult{
+		Time:     time.Now().UTC(),
+		MimeType: mimeType,
+		Doc:      doc,
+	}, nil
 }
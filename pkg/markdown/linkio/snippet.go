@@ -1,4 +1,225 @@
 package linkio
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Preview is the title and summary of linked content, shown when hovering over
+// a link.
+type Preview struct {
+	// The link that the preview summarizes.
+	URL string `json:"url"`
+	// The plain text title of the linked content.
+	Title string `json:"title"`
+	// The HTML summary of the linked content, like a paragraph.
+	SnippetHTML string `json:"snippet_html,omitempty"`
+	// The time the preview was fetched.
+	FetchTime time.Time `json:"fetch_time"`
+}
+
 // Snippeter fetches snippets for linked content in a Markdown source file.
-type Snippeter struct{}
+type Snippeter struct {
+	// Fetches summaries for Wikipedia article links.
+	Wiki Fetcher
+	// Fetches the pages of all other links to read OpenGraph tags.
+	Page Fetcher
+}
+
+// NewSnippeter returns a snippeter that fetches with client.
+func NewSnippeter(client *http.Client) Snippeter {
+	return Snippeter{
+		Wiki: WikiSummaryFetcher{Client: client},
+		Page: OpenGraphFetcher{Client: client},
+	}
+}
+
+// Snippet fetches the preview for link.
+func (s Snippeter) Snippet(link string) (Preview, error) {
+	if IsWikiLink(link) {
+		r, err := s.Wiki.Fetch(link)
+		if err != nil {
+			return Preview{}, fmt.Errorf("fetch wiki snippet: %w", err)
+		}
+		return ParseWikiSummary(r)
+	}
+	r, err := s.Page.Fetch(link)
+	if err != nil {
+		return Preview{}, fmt.Errorf("fetch page snippet: %w", err)
+	}
+	return ParseOpenGraph(r)
+}
+
+// IsWikiLink returns true if link is an English Wikipedia article.
+func IsWikiLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return u.Host == "en.wikipedia.org" && strings.HasPrefix(u.Path, "/wiki/")
+}
+
+// IsPreviewable returns true if link is an external page that might have a
+// preview. PDFs and other documents don't have titles or summaries we can
+// read.
+func IsPreviewable(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	return path.Ext(u.Path) != ".pdf"
+}
+
+// wikiSummary is the subset of the Wikipedia summary response for previews.
+type wikiSummary struct {
+	Title       string `json:"title"`
+	ExtractHTML string `json:"extract_html"`
+}
+
+// ParseWikiSummary parses a preview from a Wikipedia summary fetch.
+func ParseWikiSummary(r FetchResult) (Preview, error) {
+	if r.MimeType != "application/json" {
+		return Preview{}, fmt.Errorf("parse wiki summary %s: unsupported mime type %q", r.Path, r.MimeType)
+	}
+	s := wikiSummary{}
+	if err := json.Unmarshal(r.Doc, &s); err != nil {
+		return Preview{}, fmt.Errorf("parse wiki summary %s: %w", r.Path, err)
+	}
+	if s.Title == "" {
+		return Preview{}, fmt.Errorf("parse wiki summary %s: no title", r.Path)
+	}
+	return Preview{
+		URL:         r.Path,
+		Title:       s.Title,
+		SnippetHTML: strings.TrimSpace(s.ExtractHTML),
+		FetchTime:   r.Time,
+	}, nil
+}
+
+// ParseOpenGraph parses a preview from the OpenGraph meta tags of an HTML
+// page. Falls back to the title element and the description meta tag.
+func ParseOpenGraph(r FetchResult) (Preview, error) {
+	if r.MimeType != "text/html" {
+		return Preview{}, fmt.Errorf("parse open graph %s: unsupported mime type %q", r.Path, r.MimeType)
+	}
+	var ogTitle, ogDesc, title, desc string
+	z := xhtml.NewTokenizer(bytes.NewReader(r.Doc))
+	inTitle := false
+loop:
+	for {
+		switch z.Next() {
+		case xhtml.ErrorToken:
+			break loop
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.DataAtom {
+			case atom.Body:
+				break loop
+			case atom.Title:
+				inTitle = true
+			case atom.Meta:
+				content := attrVal(tok, "content")
+				switch {
+				case attrVal(tok, "property") == "og:title":
+					ogTitle = content
+				case attrVal(tok, "property") == "og:description":
+					ogDesc = content
+				case attrVal(tok, "name") == "description":
+					desc = content
+				}
+			}
+		case xhtml.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case xhtml.EndTagToken:
+			tok := z.Token()
+			switch tok.DataAtom {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				break loop
+			}
+		}
+	}
+	if err := z.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return Preview{}, fmt.Errorf("parse open graph %s: %w", r.Path, err)
+	}
+	p := Preview{
+		URL:       r.Path,
+		Title:     firstNonBlank(ogTitle, title),
+		FetchTime: r.Time,
+	}
+	if p.Title == "" {
+		return Preview{}, fmt.Errorf("parse open graph %s: no title", r.Path)
+	}
+	if d := firstNonBlank(ogDesc, desc); d != "" {
+		p.SnippetHTML = "<p>" + html.EscapeString(d) + "</p>"
+	}
+	return p, nil
+}
+
+// attrVal returns the value of the attribute key on tok or the empty string.
+func attrVal(tok xhtml.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// firstNonBlank returns the first of ss that's not blank after trimming space.
+func firstNonBlank(ss ...string) string {
+	for _, s := range ss {
+		if s = strings.Join(strings.Fields(s), " "); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// ExternalLinks returns the previewable links in a Markdown source file, in
+// order of first appearance. See IsPreviewable.
+func ExternalLinks(src []byte) []string {
+	doc := goldmark.New().Parser().Parse(text.NewReader(src))
+	var links []string
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest string
+		switch n := n.(type) {
+		case *ast.Link:
+			dest = string(n.Destination)
+		case *ast.AutoLink:
+			dest = string(n.URL(src))
+		default:
+			return ast.WalkContinue, nil
+		}
+		if IsPreviewable(dest) && !slices.Contains(links, dest) {
+			links = append(links, dest)
+		}
+		return ast.WalkContinue, nil
+	})
+	return links
+}
@@ -0,0 +1,128 @@
+package linkio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// newTestSnippeter returns a snippeter that fetches Wikipedia summaries and
+// pages from a local stand-in server with handlers keyed by path.
+func newTestSnippeter(t *testing.T, handlers map[string]string) (Snippeter, *httptest.Server) {
+	t.Helper()
+	mux := http.NewServeMux()
+	for p, body := range handlers {
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("User-Agent") == "" {
+				http.Error(w, "no user agent", http.StatusForbidden)
+				return
+			}
+			if strings.HasPrefix(p, "/summary/") {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			}
+			_, _ = w.Write([]byte(body))
+		})
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return Snippeter{
+		Wiki: WikiSummaryFetcher{Client: srv.Client(), BaseURL: srv.URL + "/summary/"},
+		Page: OpenGraphFetcher{Client: srv.Client()},
+	}, srv
+}
+
+func TestSnippeter_Snippet(t *testing.T) {
+	s, srv := newTestSnippeter(t, map[string]string{
+		"/summary/Isolation_(database_systems)": `{
+			"title": "Isolation (database systems)",
+			"extract_html": "<p>In database systems, <b>isolation</b> is a property.</p>\n"
+		}`,
+		"/og": `<!doctype html><html><head>
+			<title>Fallback title</title>
+			<meta property="og:title" content="OpenGraph &amp; title">
+			<meta property="og:description" content="A <great> page.">
+			</head><body><meta property="og:title" content="ignored"></body></html>`,
+		"/plain": `<html><head><title>
+			Plain   title </title><meta name="description" content="Plain description"></head></html>`,
+		"/untitled": `<html><head></head><body>no title</body></html>`,
+	})
+	tests := []struct {
+		link    string
+		want    Preview
+		wantErr bool
+	}{
+		{
+			link: "https://en.wikipedia.org/wiki/Isolation_(database_systems)#Read_uncommitted",
+			want: Preview{
+				URL:         "https://en.wikipedia.org/wiki/Isolation_(database_systems)#Read_uncommitted",
+				Title:       "Isolation (database systems)",
+				SnippetHTML: "<p>In database systems, <b>isolation</b> is a property.</p>",
+			},
+		},
+		{
+			link: srv.URL + "/og",
+			want: Preview{
+				URL:         srv.URL + "/og",
+				Title:       "OpenGraph & title",
+				SnippetHTML: "<p>A &lt;great&gt; page.</p>",
+			},
+		},
+		{
+			link: srv.URL + "/plain",
+			want: Preview{
+				URL:         srv.URL + "/plain",
+				Title:       "Plain title",
+				SnippetHTML: "<p>Plain description</p>",
+			},
+		},
+		{link: srv.URL + "/untitled", wantErr: true},
+		{link: srv.URL + "/missing", wantErr: true},
+		{link: "https://en.wikipedia.org/wiki/Missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			got, err := s.Snippet(tt.link)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Snippet(%q) want error, got %+v", tt.link, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.FetchTime.IsZero() {
+				t.Errorf("Snippet(%q) has zero fetch time", tt.link)
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(Preview{}, "FetchTime")); diff != "" {
+				t.Errorf("Snippet(%q) mismatch (-want +got):\n%s", tt.link, diff)
+			}
+		})
+	}
+}
+
+func TestExternalLinks(t *testing.T) {
+	src := `
+# Title
+
+See [wiki](https://en.wikipedia.org/wiki/Foo) and [paper](https://example.com/paper.pdf),
+[local](./local.md), [again](https://en.wikipedia.org/wiki/Foo), and
+<https://example.com/auto>.
+
+[ref]: https://example.com/ref
+[used ref][ref]
+`
+	got := ExternalLinks([]byte(src))
+	want := []string{
+		"https://en.wikipedia.org/wiki/Foo",
+		"https://example.com/auto",
+		"https://example.com/ref",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ExternalLinks mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -1,11 +1,13 @@
 package markdown
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/jschaf/jsc/pkg/cite"
 	"github.com/jschaf/jsc/pkg/markdown/assets"
+	"github.com/jschaf/jsc/pkg/markdown/linkio"
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 	"github.com/jschaf/jsc/pkg/markdown/mdext"
 	"github.com/yuin/goldmark"
@@ -20,9 +22,14 @@ type AST struct {
 	Meta   mdext.PostMeta
 	Source []byte
 	Assets []assets.Blob
+	// The absolute paths of other files read while parsing, like included
+	// source files.
+	Dependencies []string
 	// The full path to the Markdown file that this AST represents.
 	Path     string
 	Features *mdctx.FeatureSet
+	// Warnings found while parsing. Errors fail the parse instead.
+	Warnings mdctx.Diagnostics
 }
 
 // Options are global configuration options for parsing and rendering Markdown.
@@ -33,6 +40,9 @@ type Options struct {
 	TOCStyle           mdext.TOCStyle
 	Extenders          []goldmark.Extender
 	HeadingAnchorStyle mdext.HeadingAnchorStyle
+	// LinkPreviews are the fetched previews for links without a hand-written
+	// preview. Defaults to no fetched previews.
+	LinkPreviews *linkio.Cache
 }
 
 type Markdown struct {
@@ -58,6 +68,31 @@ func WithHeadingAnchorStyle(s mdext.HeadingAnchorStyle) Option {
 	}
 }
 
+// WithCiteStyle overrides the default IEEE citation style for posts that don't
+// set cite_style in the frontmatter.
+func WithCiteStyle(s cite.Style) Option {
+	return func(m *Markdown) {
+		m.opts.CiteStyle = s
+	}
+}
+
+// WithCiteAttacher overrides where the citation references are attached. The
+// default attaches references to the end of the article. A nil attacher skips
+// the references.
+func WithCiteAttacher(a mdext.CitationReferencesAttacher) Option {
+	return func(m *Markdown) {
+		m.opts.CiteAttacher = a
+	}
+}
+
+// WithLinkPreviews adds fetched previews to links without a hand-written
+// preview.
+func WithLinkPreviews(c *linkio.Cache) Option {
+	return func(m *Markdown) {
+		m.opts.LinkPreviews = c
+	}
+}
+
 func WithExtender(e goldmark.Extender) Option {
 	parser.WithAutoHeadingID()
 	return func(m *Markdown) {
@@ -71,6 +106,7 @@ func defaultExtensions(opts Options) []goldmark.Extender {
 		mdext.NewCodeBlockExt(),
 		mdext.NewColonBlockExt(),
 		mdext.NewColonLineExt(),
+		mdext.NewCrossRefExt(),
 		mdext.NewCustomExt(),
 		mdext.NewFootnoteExt(opts.CiteStyle, opts.CiteAttacher),
 		mdext.NewHeaderExt(),
@@ -78,10 +114,11 @@ func defaultExtensions(opts Options) []goldmark.Extender {
 		mdext.NewHeadingIDExt(),
 		mdext.NewImageExt(),
 		mdext.NewKatexExt(),
-		mdext.NewLinkExt(),
+		mdext.NewLinkExt(opts.LinkPreviews),
 		mdext.NewParagraphExt(),
 		mdext.NewSmallCapsExt(),
 		mdext.NewTableExt(),
+		mdext.NewTagListExt(),
 		mdext.NewTOCExt(opts.TOCStyle),
 		mdext.NewTOMLExt(),
 		mdext.NewTimeExt(),
@@ -111,6 +148,8 @@ func New(opts ...Option) *Markdown {
 	return m
 }
 
+// Parse parses the markdown file at path from r. If parsing finds any
+// errors, Parse returns every diagnostic as an mdctx.Diagnostics error.
 func (m *Markdown) Parse(path string, r io.Reader) (*AST, error) {
 	bs, err := io.ReadAll(r)
 	if err != nil {
@@ -121,10 +160,9 @@ func (m *Markdown) Parse(path string, r io.Reader) (*AST, error) {
 	mdctx.SetRenderer(ctx, m.gm.Renderer())
 
 	node := m.gm.Parser().Parse(text.NewReader(bs), parser.WithContext(ctx))
-	if parseErrs := mdctx.PopErrors(ctx); len(parseErrs) == 1 {
-		return nil, fmt.Errorf("parse errors in context: %w", parseErrs[0])
-	} else if len(parseErrs) > 1 {
-		return nil, fmt.Errorf("parse errors in context: %v", parseErrs)
+	diags := mdctx.PopDiagnostics(ctx)
+	if len(diags.Errors()) > 0 {
+		return nil, diags
 	}
 	meta := mdext.GetTOMLMeta(ctx)
 	meta.Title = mdctx.GetTitle(ctx).Text
@@ -132,12 +170,14 @@ func (m *Markdown) Parse(path string, r io.Reader) (*AST, error) {
 	mdAssets := mdctx.GetAssets(ctx)
 	mdFeats := mdctx.GetFeatures(ctx)
 	return &AST{
-		Node:     node,
-		Meta:     meta,
-		Assets:   mdAssets,
-		Path:     path,
-		Source:   bs,
-		Features: mdFeats,
+		Node:         node,
+		Meta:         meta,
+		Assets:       mdAssets,
+		Dependencies: mdctx.GetDependencies(ctx),
+		Path:         path,
+		Source:       bs,
+		Features:     mdFeats,
+		Warnings:     diags.Warnings(),
 	}, nil
 }
 
@@ -146,6 +186,18 @@ func (m *Markdown) Renderer() renderer.Renderer {
 	return m.gm.Renderer()
 }
 
+// Render renders the AST as HTML into w. Render errors positioned by a
+// renderer are returned as an mdctx.Diagnostic with the AST path.
 func (m *Markdown) Render(w io.Writer, source []byte, p *AST) error {
-	return m.Renderer().Render(w, source, p.Node)
+	if err := m.Renderer().Render(w, source, p.Node); err != nil {
+		var d mdctx.Diagnostic
+		if errors.As(err, &d) {
+			if d.Path == "" {
+				d.Path = p.Path
+			}
+			return d
+		}
+		return fmt.Errorf("render %s: %w", p.Path, err)
+	}
+	return nil
 }
@@ -2,11 +2,13 @@ package markdown
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/jschaf/jsc/pkg/htmls"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 	"github.com/jschaf/jsc/pkg/markdown/mdext"
 	"github.com/jschaf/jsc/pkg/texts"
 )
@@ -34,7 +36,7 @@ func TestParse(t *testing.T) {
         ![Alt text](./foo_bar)
       `),
 			articleHTML(mdext.PostMeta{Slug: "foo"}, "title",
-				`<figure><picture><img src="/foo/foo_bar" loading=lazy alt="Alt text"></picture></figure>`,
+				`<figure><picture><img src="/foo/foo_bar" alt="Alt text"></picture></figure>`,
 			),
 		},
 	}
@@ -59,6 +61,56 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParse_Diagnostics(t *testing.T) {
+	src := texts.Dedent(`
+		+++
+		slug = "foo"
+		date = 2020-01-02
+		tags = ["no-such-tag"]
+		+++
+		# Title
+
+		::: unknown-block
+		Body
+		:::
+	`)
+	_, err := New().Parse("posts/foo.md", strings.NewReader(src))
+	var diags mdctx.Diagnostics
+	if !errors.As(err, &diags) {
+		t.Fatalf("want mdctx.Diagnostics error; got %T: %v", err, err)
+	}
+	want := []string{
+		`posts/foo.md:2:1: unknown tag "no-such-tag"`,
+		`posts/foo.md:9:1: unknown colon block name "unknown-block"`,
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("want %d diagnostics; got %d:\n%v", len(want), len(diags), diags)
+	}
+	for i, d := range diags {
+		if !strings.HasPrefix(d.Error(), want[i]) {
+			t.Errorf("diagnostic %d: want prefix %q; got %q", i, want[i], d.Error())
+		}
+	}
+}
+
+func TestParse_InvalidTOML(t *testing.T) {
+	src := texts.Dedent(`
+		+++
+		slug = "foo"
+		date = 2020-01-02
+		title = "unterminated
+		+++
+		# Title
+	`)
+	_, err := New().Parse("posts/foo.md", strings.NewReader(src))
+	if err == nil {
+		t.Fatal("want error for invalid TOML; got nil")
+	}
+	if want := "posts/foo.md:4:"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("want error with position prefix %q; got %q", want, err.Error())
+	}
+}
+
 func withFrontmatter(meta mdext.PostMeta, md string) string {
 	b := new(bytes.Buffer)
 	b.WriteString("+++\n")
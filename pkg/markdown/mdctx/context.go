@@ -7,26 +7,6 @@ import (
 	"github.com/yuin/goldmark/renderer"
 )
 
-var errorsCtxKey = parser.NewContextKey()
-
-func PushError(pc parser.Context, err error) {
-	var errs []error
-	if e := pc.Get(errorsCtxKey); e != nil {
-		errs = e.([]error)
-	}
-	errs = append(errs, err)
-	pc.Set(errorsCtxKey, errs)
-}
-
-func PopErrors(pc parser.Context) []error {
-	var errs []error
-	if e := pc.Get(errorsCtxKey); e != nil {
-		errs = e.([]error)
-	}
-	pc.Set(errorsCtxKey, nil)
-	return errs
-}
-
 var assetsCtxKey = parser.NewContextKey()
 
 // GetAssets returns all blobs associated with a post.
@@ -47,6 +27,22 @@ func AddAsset(pc parser.Context, b assets.Blob) {
 	pc.Set(assetsCtxKey, append(m.([]assets.Blob), b))
 }
 
+var dependenciesCtxKey = parser.NewContextKey()
+
+// GetDependencies returns the absolute paths of files read to parse a post,
+// other than the post and its assets, like included source files.
+func GetDependencies(pc parser.Context) []string {
+	deps, _ := pc.Get(dependenciesCtxKey).([]string)
+	return deps
+}
+
+// AddDependency records that the post depends on the file at the absolute
+// path so the dev server rebuilds the post when the file changes.
+func AddDependency(pc parser.Context, path string) {
+	deps, _ := pc.Get(dependenciesCtxKey).([]string)
+	pc.Set(dependenciesCtxKey, append(deps, path))
+}
+
 var featuresCtxKey = parser.NewContextKey()
 
 func GetFeatures(pc parser.Context) *FeatureSet {
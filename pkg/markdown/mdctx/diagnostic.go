@@ -0,0 +1,206 @@
+package mdctx
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+)
+
+// Severity is how bad a diagnostic is.
+type Severity int
+
+const (
+	// SeverityError fails the parse.
+	SeverityError Severity = iota
+	// SeverityWarning is reported but doesn't fail the parse.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "Severity(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// Diagnostic is a problem in a markdown file found while parsing or
+// rendering, positioned at the offending source if known.
+type Diagnostic struct {
+	// The markdown file path.
+	Path string
+	// The 1-based line and column of the problem or 0 if unknown. The column
+	// counts runes.
+	Line, Col int
+	Severity  Severity
+	Err       error
+}
+
+// Error formats the diagnostic like a compiler error:
+//
+//	posts/foo.md:3:7: warning: message
+func (d Diagnostic) Error() string {
+	var loc []string
+	if d.Path != "" {
+		loc = append(loc, d.Path)
+	}
+	if d.Line > 0 {
+		loc = append(loc, strconv.Itoa(d.Line))
+		if d.Col > 0 {
+			loc = append(loc, strconv.Itoa(d.Col))
+		}
+	}
+	b := &strings.Builder{}
+	if len(loc) > 0 {
+		b.WriteString(strings.Join(loc, ":"))
+		b.WriteString(": ")
+	}
+	if d.Severity != SeverityError {
+		b.WriteString(d.Severity.String())
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Err.Error())
+	return b.String()
+}
+
+func (d Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Diagnostics is a list of diagnostics usable as a single error.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, len(ds))
+	for i, d := range ds {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Errors returns the diagnostics with SeverityError.
+func (ds Diagnostics) Errors() Diagnostics {
+	var errs Diagnostics
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
+
+// Warnings returns the diagnostics with SeverityWarning.
+func (ds Diagnostics) Warnings() Diagnostics {
+	var warns Diagnostics
+	for _, d := range ds {
+		if d.Severity == SeverityWarning {
+			warns = append(warns, d)
+		}
+	}
+	return warns
+}
+
+var diagnosticsCtxKey = parser.NewContextKey()
+
+// PushDiagnostic adds a diagnostic to the context. Sets the diagnostic path to
+// the file path of the context if empty.
+func PushDiagnostic(pc parser.Context, d Diagnostic) {
+	if d.Path == "" {
+		d.Path = GetFilePath(pc)
+	}
+	ds, _ := pc.Get(diagnosticsCtxKey).(Diagnostics)
+	pc.Set(diagnosticsCtxKey, append(ds, d))
+}
+
+// PushError adds an error without a source position to the context. Prefer
+// PushErrorAt when the error belongs to a node.
+func PushError(pc parser.Context, err error) {
+	PushDiagnostic(pc, Diagnostic{Severity: SeverityError, Err: err})
+}
+
+// PushErrorAt adds an error positioned at node to the context.
+func PushErrorAt(pc parser.Context, source []byte, node ast.Node, err error) {
+	line, col := NodePosition(source, node)
+	PushDiagnostic(pc, Diagnostic{Line: line, Col: col, Severity: SeverityError, Err: err})
+}
+
+// PushWarningAt adds a warning positioned at node to the context.
+func PushWarningAt(pc parser.Context, source []byte, node ast.Node, err error) {
+	line, col := NodePosition(source, node)
+	PushDiagnostic(pc, Diagnostic{Line: line, Col: col, Severity: SeverityWarning, Err: err})
+}
+
+// PopDiagnostics removes and returns all diagnostics from the context.
+func PopDiagnostics(pc parser.Context) Diagnostics {
+	ds, _ := pc.Get(diagnosticsCtxKey).(Diagnostics)
+	pc.Set(diagnosticsCtxKey, nil)
+	return ds
+}
+
+// PopErrors removes all diagnostics from the context and returns the errors.
+func PopErrors(pc parser.Context) []error {
+	var errs []error
+	for _, d := range PopDiagnostics(pc).Errors() {
+		errs = append(errs, d)
+	}
+	return errs
+}
+
+// NodePosition returns the 1-based line and column of the start of node in
+// source. Falls back to the closest ancestor with a source position for nodes
+// without one, like nodes created by a transformer. Returns 0, 0 if no
+// position exists.
+func NodePosition(source []byte, node ast.Node) (line, col int) {
+	for n := node; n != nil && n.Kind() != ast.KindDocument; n = n.Parent() {
+		if offset := nodeOffset(n); offset >= 0 {
+			return OffsetPosition(source, offset)
+		}
+	}
+	return 0, 0
+}
+
+// OffsetPosition returns the 1-based line and column of the byte offset in
+// source.
+func OffsetPosition(source []byte, offset int) (line, col int) {
+	offset = min(max(offset, 0), len(source))
+	before := source[:offset]
+	line = bytes.Count(before, []byte{'\n'}) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	col = utf8.RuneCount(before[lineStart:]) + 1
+	return line, col
+}
+
+// nodeOffset returns the byte offset of the first source segment of node or
+// its descendants, or -1 if none exists.
+func nodeOffset(node ast.Node) int {
+	if node == nil {
+		return -1
+	}
+	switch n := node.(type) {
+	case *ast.Text:
+		return n.Segment.Start
+	case *ast.FencedCodeBlock:
+		if n.Info != nil {
+			return n.Info.Segment.Start
+		}
+	}
+	// Inline nodes panic on Lines.
+	if node.Type() == ast.TypeBlock {
+		if lines := node.Lines(); lines != nil && lines.Len() > 0 {
+			return lines.At(0).Start
+		}
+	}
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		if offset := nodeOffset(c); offset >= 0 {
+			return offset
+		}
+	}
+	return -1
+}
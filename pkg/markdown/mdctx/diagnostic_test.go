@@ -0,0 +1,62 @@
+package mdctx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestDiagnostic_Error(t *testing.T) {
+	err := errors.New("boom")
+	tests := []struct {
+		name string
+		d    Diagnostic
+		want string
+	}{
+		{"bare", Diagnostic{Err: err}, "boom"},
+		{"path", Diagnostic{Path: "a.md", Err: err}, "a.md: boom"},
+		{"line", Diagnostic{Path: "a.md", Line: 3, Err: err}, "a.md:3: boom"},
+		{"line col", Diagnostic{Path: "a.md", Line: 3, Col: 7, Err: err}, "a.md:3:7: boom"},
+		{"warning", Diagnostic{Path: "a.md", Line: 3, Col: 7, Severity: SeverityWarning, Err: err}, "a.md:3:7: warning: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.Error(); got != tt.want {
+				t.Errorf("Error() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodePosition(t *testing.T) {
+	source := []byte("first\nsecond ✓ line\n")
+	para := ast.NewParagraph()
+	para.Lines().Append(text.NewSegment(6, 21))
+	txt := ast.NewTextSegment(text.NewSegment(17, 21))
+	para.AppendChild(para, txt)
+	// Nodes created by transformers have no segment, so use the parent.
+	created := ast.NewEmphasis(1)
+	para.AppendChild(para, created)
+
+	tests := []struct {
+		name     string
+		node     ast.Node
+		wantLine int
+		wantCol  int
+	}{
+		{"paragraph", para, 2, 1},
+		{"text after multibyte rune", txt, 2, 10},
+		{"created node", created, 2, 1},
+		{"detached node", ast.NewEmphasis(1), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, col := NodePosition(source, tt.node)
+			if line != tt.wantLine || col != tt.wantCol {
+				t.Errorf("NodePosition() = %d:%d; want %d:%d", line, col, tt.wantLine, tt.wantCol)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package mdext
+
+import (
+	"strings"
+
+	"github.com/jschaf/jsc/pkg/markdown/asts"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	KindNote      = ast.NewNodeKind("Note")
+	KindWarning   = ast.NewNodeKind("Warning")
+	KindTip       = ast.NewNodeKind("Tip")
+	KindEpigraph  = ast.NewNodeKind("Epigraph")
+	KindPullQuote = ast.NewNodeKind("PullQuote")
+)
+
+// Admonition is a note, warning, or tip set apart from the main text, created
+// from a colon block like:
+//
+//	::: warning Optional title
+//	Some *content*
+//	:::
+type Admonition struct {
+	ast.BaseBlock
+	kind ast.NodeKind
+	// The title shown above the content. Defaults to the kind, like "Note".
+	Title string
+}
+
+// NewAdmonition returns an admonition of kind, one of KindNote, KindWarning,
+// or KindTip.
+func NewAdmonition(kind ast.NodeKind) *Admonition {
+	return &Admonition{kind: kind}
+}
+
+func (a *Admonition) Kind() ast.NodeKind {
+	return a.kind
+}
+
+func (a *Admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(a, source, level, map[string]string{"Title": a.Title}, nil)
+}
+
+// Epigraph is a quotation at the start of a post or section, created from a
+// colon block like:
+//
+//	::: epigraph Optional attribution
+//	Some *content*
+//	:::
+type Epigraph struct {
+	ast.BaseBlock
+	// The source of the quotation, like the author. Optional.
+	Attribution string
+}
+
+func NewEpigraph() *Epigraph {
+	return &Epigraph{}
+}
+
+func (e *Epigraph) Kind() ast.NodeKind {
+	return KindEpigraph
+}
+
+func (e *Epigraph) Dump(source []byte, level int) {
+	ast.DumpHelper(e, source, level, map[string]string{"Attribution": e.Attribution}, nil)
+}
+
+// PullQuote is an enlarged excerpt of the post to draw attention, created from
+// a colon block like:
+//
+//	::: pullquote Optional attribution
+//	Some *content*
+//	:::
+type PullQuote struct {
+	ast.BaseBlock
+	// The source of the quotation, like the author. Optional.
+	Attribution string
+}
+
+func NewPullQuote() *PullQuote {
+	return &PullQuote{}
+}
+
+func (p *PullQuote) Kind() ast.NodeKind {
+	return KindPullQuote
+}
+
+func (p *PullQuote) Dump(source []byte, level int) {
+	ast.DumpHelper(p, source, level, map[string]string{"Attribution": p.Attribution}, nil)
+}
+
+// admonitionKinds maps colon block names to the kind of admonition.
+var admonitionKinds = map[ColonBlockName]ast.NodeKind{
+	ColonBlockNote:    KindNote,
+	ColonBlockWarning: KindWarning,
+	ColonBlockTip:     KindTip,
+}
+
+// replaceColonBlock replaces the colon block with an admonition, epigraph, or
+// pull quote based on the block name. Ignores other names.
+func replaceColonBlock(block *ColonBlock) {
+	var n ast.Node
+	switch block.Name {
+	case ColonBlockNote, ColonBlockWarning, ColonBlockTip:
+		a := NewAdmonition(admonitionKinds[block.Name])
+		a.Title = block.Args
+		n = a
+	case ColonBlockEpigraph:
+		e := NewEpigraph()
+		e.Attribution = block.Args
+		n = e
+	case ColonBlockPullQuote:
+		p := NewPullQuote()
+		p.Attribution = block.Args
+		n = p
+	default:
+		return
+	}
+	asts.Reparent(n, block)
+	parent := block.Parent()
+	parent.ReplaceChild(parent, block, n)
+}
+
+func (cbr colonBlockRenderer) renderAdmonition(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</aside>\n")
+		return ast.WalkContinue, nil
+	}
+	a := n.(*Admonition)
+	// Kind names are capitalized, like "Note".
+	kindName := a.Kind().String()
+	title := a.Title
+	if title == "" {
+		title = kindName
+	}
+	_, _ = w.WriteString(`<aside class="admonition admonition-`)
+	_, _ = w.WriteString(strings.ToLower(kindName))
+	_, _ = w.WriteString(`">`)
+	_, _ = w.WriteString(`<p class=admonition-title>`)
+	_, _ = w.Write(util.EscapeHTML([]byte(title)))
+	_, _ = w.WriteString("</p>\n")
+	return ast.WalkContinue, nil
+}
+
+func (cbr colonBlockRenderer) renderEpigraph(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	e := n.(*Epigraph)
+	renderQuoteFigure(w, "epigraph", e.Attribution, entering)
+	return ast.WalkContinue, nil
+}
+
+func (cbr colonBlockRenderer) renderPullQuote(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	p := n.(*PullQuote)
+	renderQuoteFigure(w, "pullquote", p.Attribution, entering)
+	return ast.WalkContinue, nil
+}
+
+// renderQuoteFigure renders a figure with class containing a blockquote of
+// the children and an optional attribution caption.
+func renderQuoteFigure(w util.BufWriter, class, attribution string, entering bool) {
+	if entering {
+		_, _ = w.WriteString("<figure class=")
+		_, _ = w.WriteString(class)
+		_, _ = w.WriteString(">\n<blockquote>\n")
+		return
+	}
+	_, _ = w.WriteString("</blockquote>\n")
+	if attribution != "" {
+		_, _ = w.WriteString("<figcaption>")
+		_, _ = w.Write(util.EscapeHTML([]byte(attribution)))
+		_, _ = w.WriteString("</figcaption>\n")
+	}
+	_, _ = w.WriteString("</figure>\n")
+}
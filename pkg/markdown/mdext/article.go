@@ -44,7 +44,7 @@ func (at *articleTransformer) Transform(doc *ast.Document, reader text.Reader, p
 	meta := GetTOMLMeta(pc)
 	heading := firstHeading(doc)
 	if heading == nil {
-		mdctx.PushError(pc, errors.New("no main heading in file: "+mdctx.GetFilePath(pc)))
+		mdctx.PushError(pc, errors.New("no main heading"))
 		return
 	}
 	titleText := renderTextTitle(reader, heading)
@@ -69,6 +69,9 @@ func (at *articleTransformer) Transform(doc *ast.Document, reader text.Reader, p
 	newHeading.AppendChild(newHeading, link)
 	header.AppendChild(header, NewTime(meta.Date))
 	header.AppendChild(header, newHeading)
+	if tags := lookupTags(meta.Tags); len(tags) > 0 {
+		header.AppendChild(header, NewTagList(tags))
+	}
 	article.AppendChild(article, header)
 
 	cur := heading.NextSibling()
@@ -83,6 +86,18 @@ func (at *articleTransformer) Transform(doc *ast.Document, reader text.Reader, p
 	parent.ReplaceChild(parent, heading, article)
 }
 
+// lookupTags returns the declared tags for the slugs, skipping unknown tags,
+// which the TOML parser already reported.
+func lookupTags(slugs []string) []Tag {
+	tags := make([]Tag, 0, len(slugs))
+	for _, slug := range slugs {
+		if t, err := LookupTag(slug); err == nil {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
 func firstHeading(doc *ast.Document) *ast.Heading {
 	var hNode ast.Node
 
@@ -70,10 +70,30 @@ func TestArticleExt(t *testing.T) {
 					</article>`),
 			wantTitle: "header",
 		},
+		{
+			name: "tags",
+			src: texts.Dedent(`
+				+++
+				slug = "a_slug"
+				tags = ["go", "statistics"]
+				+++
+				# header
+				foo`),
+			want: texts.Dedent(`
+					<article>
+            <header>
+							<time datetime="0001-01-01">January  1, 0001</time>
+							<h1 class="title"><a href="/a_slug/" title="header">header</a></h1>
+							<ul class="tag-list"><li><a href="/tags/go/">Go</a></li><li><a href="/tags/statistics/">Statistics</a></li></ul>
+            </header>
+						<p>foo</p>
+					</article>`),
+			wantTitle: "header",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			md, ctx := mdtest.NewTester(t, NewArticleExt(), NewTimeExt(), NewHeaderExt())
+			md, ctx := mdtest.NewTester(t, NewArticleExt(), NewTimeExt(), NewHeaderExt(), NewTOMLExt(), NewTagListExt())
 			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
 			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
 			got := mdctx.GetTitle(ctx)
@@ -88,6 +88,10 @@ func newNode(src ast.Node) ast.Node {
 		return h
 
 	// Custom AST types.
+	case *Admonition:
+		a := NewAdmonition(n.Kind())
+		a.Title = n.Title
+		return a
 	case *Article:
 		return NewArticle()
 	case *Citation:
@@ -96,6 +100,7 @@ func newNode(src ast.Node) ast.Node {
 		c.Bibtex = n.Bibtex
 		c.Prefix = n.Prefix
 		c.Suffix = n.Suffix
+		c.Style = n.Style
 		return c
 	case *CitationRef:
 		cr := NewCitationRef()
@@ -109,11 +114,31 @@ func newNode(src ast.Node) ast.Node {
 		for i, ref := range n.Refs {
 			cr.Refs[i] = newNode(ref).(*CitationRef)
 		}
+		cr.Style = n.Style
+		cr.BibTeXPath = n.BibTeXPath
+		cr.CSLJSONPath = n.CSLJSONPath
 		return cr
+	case *CodeCallout:
+		c := NewCodeCallout()
+		c.Block = n.Block
+		c.Number = n.Number
+		return c
+	case *CodeCallouts:
+		c := NewCodeCallouts()
+		c.Block = n.Block
+		return c
+	case *CodeInclude:
+		ci := NewCodeInclude()
+		ci.Path = n.Path
+		ci.Lang = n.Lang
+		ci.Attrs = n.Attrs
+		ci.Code = n.Code
+		return ci
 	case *ColonBlock:
 		cb := NewColonBlock()
 		cb.Name = n.Name
 		cb.Args = n.Args
+		cb.fenceLen = n.fenceLen
 		return cb
 	case *ColonLine:
 		cl := NewColonLine()
@@ -122,16 +147,27 @@ func newNode(src ast.Node) ast.Node {
 		return cl
 	case *ContinueReading:
 		return NewContinueReading(n.Link)
+	case *Epigraph:
+		e := NewEpigraph()
+		e.Attribution = n.Attribution
+		return e
 	case *Figure:
 		f := NewFigure()
 		f.Destination = n.Destination
 		f.Title = n.Title
 		f.AltText = n.AltText
+		f.Label = n.Label
 		return f
 	case *FigCaption:
-		return NewFigCaption()
+		fc := NewFigCaption()
+		fc.Order = n.Order
+		return fc
 	case *Header:
 		return NewHeader()
+	case *PullQuote:
+		p := NewPullQuote()
+		p.Attribution = n.Attribution
+		return p
 	case *SmallCaps:
 		sc := NewSmallCaps()
 		sc.Segment = n.Segment
@@ -2,10 +2,15 @@ package mdext
 
 import (
 	"bytes"
-	"github.com/jschaf/jsc/pkg/markdown/extenders"
-	"github.com/jschaf/jsc/pkg/markdown/ord"
+	"fmt"
 	"html"
 	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/jsc/pkg/markdown/extenders"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/ord"
 
 	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/lexers"
@@ -20,28 +25,122 @@ type codeBlockRenderer struct{}
 
 func (c codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindFencedCodeBlock, c.render)
+	reg.Register(KindCodeInclude, c.renderInclude)
+	reg.Register(KindCodeCallouts, c.renderCallouts)
+	reg.Register(KindCodeCallout, c.renderCallout)
 }
 
 func (c codeBlockRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (status ast.WalkStatus, err error) {
 	n := node.(*ast.FencedCodeBlock)
 
 	if entering {
-		lang := string(n.Language(source))
-
-		lexer := getLexer(lang)
-
-		tokenIter, err := lexer.Tokenise(nil, readAllCodeBlockLines(n, source))
+		info := ""
+		if n.Info != nil {
+			info = string(n.Info.Segment.Value(source))
+		}
+		lang, attrs, err := parseCodeBlockInfo(info)
 		if err != nil {
-			panic(err)
+			return ast.WalkStop, codeBlockError(source, n, err)
 		}
-		if err := formatCodeBlock(w, tokenIter, lang); err != nil {
-			panic(err)
+		code := readAllCodeBlockLines(n, source)
+		if err := renderCode(w, lang, attrs, code, calloutBlock(n)); err != nil {
+			return ast.WalkStop, codeBlockError(source, n, err)
 		}
+	}
+	return ast.WalkContinue, nil
+}
 
+// renderInclude renders an included source file like a fenced code block.
+func (c codeBlockRenderer) renderInclude(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*CodeInclude)
+	lang, attrs, err := parseCodeBlockInfo(codeIncludeInfo(n))
+	if err != nil {
+		return ast.WalkStop, codeBlockError(source, n, fmt.Errorf("include %s: %w", n.Path, err))
+	}
+	if attrs.title == "" {
+		attrs.title, _, _ = strings.Cut(n.Path, "#")
+	}
+	if err := renderCode(w, lang, attrs, n.Code, calloutBlock(n)); err != nil {
+		return ast.WalkStop, codeBlockError(source, n, fmt.Errorf("include %s: %w", n.Path, err))
 	}
 	return ast.WalkContinue, nil
 }
 
+// calloutBlock returns the number of the code block among code blocks with
+// callout explanations, or 0 if the code block has no explanations.
+func calloutBlock(n ast.Node) int {
+	block, _ := n.AttributeString(calloutBlockAttr)
+	b, _ := block.(int)
+	return b
+}
+
+// codeMarks are the marks removed from the lines of code before highlighting.
+type codeMarks struct {
+	// The diff mark of each line, if the block is a diff.
+	diff []byte
+	// The callout number of each line, or 0 for lines without a callout.
+	callouts []int
+	// The number of the code block used to link callouts to explanations, or 0
+	// if the callouts have no explanations.
+	calloutBlock int
+	// The prompt of each line in a terminal session, or empty for lines
+	// without a prompt.
+	prompts []string
+	// Whether each line in a terminal session is command output.
+	output []bool
+	// The text copied by the copy button, like the commands of a terminal
+	// session. Empty means no copy button.
+	copyText string
+}
+
+// renderCode highlights the code in lang and writes the HTML.
+func renderCode(w util.BufWriter, lang string, attrs codeBlockAttrs, code string, calloutBlock int) error {
+	lexer := getLexer(lang)
+	if err := attrs.validate(strings.Count(code, "\n")); err != nil {
+		return fmt.Errorf("%s code block: %w", lang, err)
+	}
+	marks := codeMarks{calloutBlock: calloutBlock}
+	if attrs.diff {
+		code, marks.diff = splitDiffMarks(code)
+	}
+	code, marks.callouts = splitCallouts(code)
+	var lines [][]chroma.Token
+	if isSessionLang(lang) {
+		prompts := defaultSessionPrompts
+		if attrs.prompt != "" {
+			prompts = []string{attrs.prompt}
+		}
+		s, err := tokenizeSession(lexer, code, prompts)
+		if err != nil {
+			return fmt.Errorf("tokenize %s code block: %w", lang, err)
+		}
+		lines = s.lines
+		marks.prompts = s.prompts
+		marks.output = s.output
+		marks.copyText = s.commands
+	} else {
+		tokenIter, err := lexer.Tokenise(nil, code)
+		if err != nil {
+			return fmt.Errorf("tokenize %s code block: %w", lang, err)
+		}
+		lines = chroma.SplitTokensIntoLines(tokenIter.Tokens())
+	}
+	if err := formatCodeBlock(w, lines, lang, attrs, marks); err != nil {
+		return fmt.Errorf("format %s code block: %w", lang, err)
+	}
+	return nil
+}
+
+// codeBlockError positions err at the code block. The renderer has no parser
+// context, so Markdown.Render fills in the file path.
+func codeBlockError(source []byte, n ast.Node, err error) error {
+	line, col := mdctx.NodePosition(source, n)
+	return mdctx.Diagnostic{Line: line, Col: col, Severity: mdctx.SeverityError, Err: err}
+}
+
 func readAllCodeBlockLines(n *ast.FencedCodeBlock, source []byte) string {
 	var b bytes.Buffer
 	l := n.Lines().Len()
@@ -52,25 +151,65 @@ func readAllCodeBlockLines(n *ast.FencedCodeBlock, source []byte) string {
 	return b.String()
 }
 
+// getLexer returns the lexer for the language, or the fallback lexer for
+// unknown languages. Terminal sessions use the shell lexer for commands.
 func getLexer(language string) chroma.Lexer {
 	lexer := lexers.Fallback
-	if language != "" {
-		lexer = lexers.Get(language)
+	switch {
+	case isSessionLang(language):
+		lexer = lexers.Get("bash")
+	case language != "":
+		if l := lookupLexer(language); l != nil {
+			lexer = l
+		}
 	}
 	lexer = chroma.Coalesce(lexer)
 	return lexer
 }
 
-func formatCodeBlock(w io.Writer, iterator chroma.Iterator, lang string) error {
+// formatCodeBlock writes the lines of tokens as HTML. Wraps each line in a
+// span if any attribute affects individual lines. Writes a badge at the end of
+// each line with a callout.
+func formatCodeBlock(w io.Writer, lines [][]chroma.Token, lang string, attrs codeBlockAttrs, marks codeMarks) error {
 	writeStrings(w, "<div class='code-block-container'>")
-	lines := chroma.SplitTokensIntoLines(iterator.Tokens())
+	if attrs.title != "" {
+		writeStrings(w, "<div class='code-block-title'>", html.EscapeString(attrs.title), "</div>")
+	}
+	if marks.copyText != "" {
+		writeStrings(w, "<button class='code-copy' type='button' data-copy='", html.EscapeString(marks.copyText), "'>Copy</button>")
+	}
 	minLineLegend := 3
 	if lang != "" && lang != "text" && len(lines) > minLineLegend {
 		writeStrings(w, "<div class='code-block-lang'>", lang, "</div>")
 	}
 	writeStrings(w, "<pre class='code-block'>")
 
-	for _, tokens := range lines {
+	wrapLines := attrs.wrapsLines()
+	for lineIdx, tokens := range lines {
+		if wrapLines {
+			var mark byte
+			if lineIdx < len(marks.diff) {
+				mark = marks.diff[lineIdx]
+			}
+			writeLineStart(w, attrs, lineIdx, mark)
+		}
+		callout := 0
+		if lineIdx < len(marks.callouts) {
+			callout = marks.callouts[lineIdx]
+		}
+		isOutput := lineIdx < len(marks.output) && marks.output[lineIdx]
+		lineEnd := ""
+		if last := len(tokens) - 1; (callout > 0 || isOutput) && last >= 0 {
+			// Write the badge and close the output span before the newline.
+			lineEnd = "\n"
+			tokens[last].Value = strings.TrimSuffix(tokens[last].Value, "\n")
+		}
+		if lineIdx < len(marks.prompts) && marks.prompts[lineIdx] != "" {
+			writeStrings(w, "<span class='code-prompt'>", html.EscapeString(marks.prompts[lineIdx]), "</span>")
+		}
+		if isOutput {
+			writeStrings(w, "<span class='code-output'>")
+		}
 		for i, token := range tokens {
 			h := html.EscapeString(token.String())
 			switch token.Type {
@@ -157,6 +296,16 @@ func formatCodeBlock(w io.Writer, iterator chroma.Iterator, lang string) error {
 				writeStrings(w, h)
 			}
 		}
+		if isOutput {
+			writeStrings(w, "</span>")
+		}
+		if callout > 0 {
+			writeCalloutBadge(w, marks.calloutBlock, callout)
+		}
+		writeStrings(w, lineEnd)
+		if wrapLines {
+			writeStrings(w, "</span>")
+		}
 	}
 
 	writeStrings(w, "</pre>")
@@ -164,6 +313,31 @@ func formatCodeBlock(w io.Writer, iterator chroma.Iterator, lang string) error {
 	return nil
 }
 
+// writeLineStart writes the opening span of the line at the 0-based lineIdx,
+// with the line number and diff mark, if any.
+func writeLineStart(w io.Writer, attrs codeBlockAttrs, lineIdx int, mark byte) {
+	class := "code-line"
+	if attrs.isHighlighted(lineIdx + 1) {
+		class += " code-line-hl"
+	}
+	switch mark {
+	case '+':
+		class += " code-line-add"
+	case '-':
+		class += " code-line-del"
+	}
+	writeStrings(w, "<span class='", class, "'>")
+	if attrs.lineNumbers {
+		writeStrings(w, "<span class='code-ln' aria-hidden='true'>", strconv.Itoa(attrs.start+lineIdx), "</span>")
+	}
+	if attrs.diff {
+		if mark == 0 {
+			mark = ' '
+		}
+		writeStrings(w, "<span class='code-diff-mark' aria-hidden='true'>", string(mark), "</span>")
+	}
+}
+
 func writeStrings(w io.Writer, ss ...string) {
 	for _, s := range ss {
 		_, _ = w.Write([]byte(s))
@@ -179,5 +353,7 @@ func NewCodeBlockExt() CodeBlockExt {
 }
 
 func (c CodeBlockExt) Extend(m goldmark.Markdown) {
+	extenders.AddASTTransform(m, codeCalloutTransformer{}, ord.CodeCalloutTransformer)
+	extenders.AddASTTransform(m, codeLangTransformer{}, ord.CodeLangTransformer)
 	extenders.AddRenderer(m, codeBlockRenderer{}, ord.CodeBlockRenderer)
 }
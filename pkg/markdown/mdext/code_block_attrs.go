@@ -0,0 +1,236 @@
+package mdext
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// codeBlockAttrs are the attributes of a fenced code block from the info
+// string after the language, like:
+//
+//	```go {hl=3-5,8 linenos start=40 title="server.go" diff}
+//	```console {prompt="user@host:~$ "}
+type codeBlockAttrs struct {
+	// The 1-based lines of the block to highlight, from the hl attribute.
+	// Lines count from the first line of the block, regardless of start.
+	highlights []lineRange
+	// Whether to show line numbers, from the linenos attribute.
+	lineNumbers bool
+	// The number of the first line, from the start attribute. Implies
+	// lineNumbers.
+	start int
+	// The caption of the block, like a filename, from the title attribute.
+	title string
+	// Whether the block is a diff, from the diff attribute. A diff marks lines
+	// starting with '+' as added and lines starting with '-' as removed, and
+	// highlights the rest of the line with the block language.
+	diff bool
+	// The prompt that starts a command in a terminal session code block, like
+	// "user@host:~$ ", from the prompt attribute. Empty means the default
+	// prompts.
+	prompt string
+}
+
+// lineRange is an inclusive range of 1-based line numbers.
+type lineRange struct {
+	start, end int
+}
+
+// parseCodeBlockInfo parses the fenced code block info string into the
+// language and the attributes in braces, if any. Ignores other text.
+func parseCodeBlockInfo(info string) (string, codeBlockAttrs, error) {
+	attrs := codeBlockAttrs{start: 1}
+	info = strings.TrimSpace(info)
+	langEnd := strings.IndexAny(info, " \t{")
+	if langEnd == -1 {
+		return info, attrs, nil
+	}
+	lang, rest := info[:langEnd], strings.TrimSpace(info[langEnd:])
+	// Ignore other words in the info string, like "shell script".
+	braceStart := strings.IndexByte(rest, '{')
+	if braceStart == -1 {
+		return lang, attrs, nil
+	}
+	rest = rest[braceStart:]
+	if !strings.HasSuffix(rest, "}") {
+		return "", attrs, fmt.Errorf("code block info %q: want attributes to end with a closing brace", info)
+	}
+	rest = rest[1 : len(rest)-1]
+	seen := make(map[string]bool)
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		key, value, hasValue, remaining, err := nextCodeBlockAttr(rest)
+		if err != nil {
+			return "", attrs, fmt.Errorf("code block info %q: %w", info, err)
+		}
+		rest = remaining
+		if seen[key] {
+			return "", attrs, fmt.Errorf("code block info %q: duplicate attribute %q", info, key)
+		}
+		seen[key] = true
+		if err := attrs.set(key, value, hasValue); err != nil {
+			return "", attrs, fmt.Errorf("code block info %q: %w", info, err)
+		}
+	}
+	return lang, attrs, nil
+}
+
+// nextCodeBlockAttr parses the next key or key=value attribute from s and
+// returns the remaining string. Values are either double-quoted or end at the
+// next space.
+func nextCodeBlockAttr(s string) (key, value string, hasValue bool, rest string, err error) {
+	keyEnd := strings.IndexAny(s, "= \t")
+	if keyEnd == -1 {
+		return s, "", false, "", nil
+	}
+	key, rest = s[:keyEnd], s[keyEnd:]
+	if key == "" {
+		return "", "", false, "", errors.New("attribute without a name")
+	}
+	if rest[0] != '=' {
+		return key, "", false, rest, nil
+	}
+	rest = rest[1:]
+	if strings.HasPrefix(rest, `"`) {
+		end := 1
+		for ; end < len(rest); end++ {
+			if rest[end] == '\\' {
+				end++
+				continue
+			}
+			if rest[end] == '"' {
+				break
+			}
+		}
+		if end >= len(rest) {
+			return "", "", false, "", fmt.Errorf("attribute %q: unterminated quoted value", key)
+		}
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return "", "", false, "", fmt.Errorf("attribute %q: unquote value: %w", key, err)
+		}
+		return key, value, true, rest[end+1:], nil
+	}
+	valueEnd := strings.IndexAny(rest, " \t")
+	if valueEnd == -1 {
+		valueEnd = len(rest)
+	}
+	return key, rest[:valueEnd], true, rest[valueEnd:], nil
+}
+
+// set sets the attribute key to value.
+func (a *codeBlockAttrs) set(key, value string, hasValue bool) error {
+	switch key {
+	case "linenos", "diff":
+		if hasValue {
+			return fmt.Errorf("attribute %q takes no value", key)
+		}
+		if key == "linenos" {
+			a.lineNumbers = true
+		} else {
+			a.diff = true
+		}
+		return nil
+	}
+	if !hasValue {
+		return fmt.Errorf("attribute %q needs a value", key)
+	}
+	switch key {
+	case "hl":
+		rs, err := parseLineRanges(value)
+		if err != nil {
+			return fmt.Errorf("attribute hl: %w", err)
+		}
+		a.highlights = rs
+	case "start":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("attribute start: want a non-negative line number, got %q", value)
+		}
+		a.start = n
+		a.lineNumbers = true
+	case "title":
+		a.title = value
+	case "prompt":
+		if value == "" {
+			return errors.New("attribute prompt: want a non-empty prompt")
+		}
+		a.prompt = value
+	default:
+		return fmt.Errorf("unknown attribute %q; want one of hl, linenos, start, title, diff, prompt", key)
+	}
+	return nil
+}
+
+// parseLineRanges parses comma-separated line numbers and ranges, like
+// "3-5,8".
+func parseLineRanges(s string) ([]lineRange, error) {
+	var rs []lineRange
+	for _, part := range strings.Split(s, ",") {
+		startStr, endStr, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(startStr)
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("want a line number or range like 3-5, got %q", part)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(endStr)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("want a line range like 3-5, got %q", part)
+			}
+		}
+		rs = append(rs, lineRange{start: start, end: end})
+	}
+	return rs, nil
+}
+
+// validate checks the attributes against the number of lines in the block.
+func (a codeBlockAttrs) validate(numLines int) error {
+	for _, r := range a.highlights {
+		if r.end > numLines {
+			return fmt.Errorf("highlighted line %d is after the last line %d", r.end, numLines)
+		}
+	}
+	return nil
+}
+
+// wrapsLines returns true if any attribute needs a span for each line.
+func (a codeBlockAttrs) wrapsLines() bool {
+	return len(a.highlights) > 0 || a.lineNumbers || a.diff
+}
+
+// isHighlighted returns true if the 1-based line is highlighted.
+func (a codeBlockAttrs) isHighlighted(line int) bool {
+	for _, r := range a.highlights {
+		if r.start <= line && line <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// splitDiffMarks removes the leading diff mark, '+', '-', or ' ', from each
+// line of code. Returns the code without marks and the mark of each line, or 0
+// for lines without a mark.
+func splitDiffMarks(code string) (string, []byte) {
+	lines := strings.SplitAfter(code, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	marks := make([]byte, len(lines))
+	b := strings.Builder{}
+	b.Grow(len(code))
+	for i, line := range lines {
+		if line != "" && strings.IndexByte("+- ", line[0]) >= 0 {
+			marks[i] = line[0]
+			line = line[1:]
+		}
+		b.WriteString(line)
+	}
+	return b.String(), marks
+}
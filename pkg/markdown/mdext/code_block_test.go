@@ -1,8 +1,12 @@
 package mdext
 
 import (
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/jschaf/jsc/pkg/htmls/tags"
+
 	"github.com/jschaf/jsc/pkg/markdown/mdtest"
 
 	"github.com/jschaf/jsc/pkg/texts"
@@ -20,11 +24,11 @@ func TestCodeBlockExt(t *testing.T) {
 				"func foo() {}\n" +
 				"```\n"),
 			texts.Dedent(`
-					<fieldset class="code-block-container">
+					<div class="code-block-container">
 						<pre class="code-block">
 							<code-kw>func</code-kw> <code-fn>foo</code-fn>() {}
 						</pre>
-					</fieldset>
+					</div>
     `),
 		},
 		{
@@ -33,11 +37,11 @@ func TestCodeBlockExt(t *testing.T) {
 				"Foo 28%\n" +
 				"```\n"),
 			texts.Dedent(`
-					<fieldset class="code-block-container">
+					<div class="code-block-container">
 						<pre class="code-block">
 							Foo 28%
 						</pre>
-					</fieldset>
+					</div>
      `),
 		},
 		{
@@ -46,11 +50,11 @@ func TestCodeBlockExt(t *testing.T) {
 				"func (t *T) foo() {}\n" +
 				"```\n"),
 			texts.Dedent(`
-					<fieldset class="code-block-container">
+					<div class="code-block-container">
 						<pre class="code-block">
 							<code-kw>func</code-kw> (t *T) <code-fn>foo</code-fn>() {}
 						</pre>
-					</fieldset>
+					</div>
      `),
 		},
 	}
@@ -62,3 +66,134 @@ func TestCodeBlockExt(t *testing.T) {
 		})
 	}
 }
+
+func TestCodeBlockExt_Attrs(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"highlight lines",
+			"```go {hl=1,3}\nx := 1\ny := 2\nz := 3\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("pre", `class="code-block"`,
+					tags.SpanAttrs(`class="code-line code-line-hl"`, "x := 1\n"),
+					tags.SpanAttrs(`class="code-line"`, "y := 2\n"),
+					tags.SpanAttrs(`class="code-line code-line-hl"`, "z := 3\n"))),
+		},
+		{
+			"line numbers from start with title",
+			"```go {title=\"main.go\" start=40}\nx := 1\ny := 2\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.DivAttrs(`class="code-block-title"`, "main.go"),
+				tags.WrapAttrs("pre", `class="code-block"`,
+					tags.SpanAttrs(`class="code-line"`, lineNum(40), "x := 1\n"),
+					tags.SpanAttrs(`class="code-line"`, lineNum(41), "y := 2\n"))),
+		},
+		{
+			"ignores words outside braces",
+			"```shell script\nfoo\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("pre", `class="code-block"`, "foo\n")),
+		},
+		{
+			"linenos flag",
+			"```text {linenos}\nfoo\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("pre", `class="code-block"`,
+					tags.SpanAttrs(`class="code-line"`, lineNum(1), "foo\n"))),
+		},
+		{
+			"diff keeps language highlighting",
+			"```go {diff}\n func f() {\n-\treturn \"a\"\n+\treturn \"b\"\n }\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.DivAttrs(`class="code-block-lang"`, "go"),
+				tags.WrapAttrs("pre", `class="code-block"`,
+					tags.SpanAttrs(`class="code-line"`, diffMark(" "), "<code-kw>func</code-kw> <code-fn>f</code-fn>() {\n"),
+					tags.SpanAttrs(`class="code-line code-line-del"`, diffMark("-"), "\t<code-kw>return</code-kw> <code-str>&#34;a&#34;</code-str>\n"),
+					tags.SpanAttrs(`class="code-line code-line-add"`, diffMark("+"), "\t<code-kw>return</code-kw> <code-str>&#34;b&#34;</code-str>\n"),
+					tags.SpanAttrs(`class="code-line"`, diffMark(" "), "}\n"))),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewCodeBlockExt())
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
+		})
+	}
+}
+
+func lineNum(n int) string {
+	return tags.SpanAttrs(`class="code-ln" aria-hidden="true"`, strconv.Itoa(n))
+}
+
+func diffMark(m string) string {
+	return tags.SpanAttrs(`class="code-diff-mark" aria-hidden="true"`, m)
+}
+
+func TestCodeBlockExt_AttrErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"unknown attr", "```go {foo=1}\nx\n```\n", `unknown attribute "foo"`},
+		{"no closing brace", "```go {hl=1\nx\n```\n", "want attributes to end with a closing brace"},
+		{"bad range", "```go {hl=3-1}\nx\n```\n", `want a line range like 3-5, got "3-1"`},
+		{"hl after last line", "```go {hl=2}\nx\n```\n", "highlighted line 2 is after the last line 1"},
+		{"flag with value", "```go {linenos=2}\nx\n```\n", `attribute "linenos" takes no value`},
+		{"unterminated title", "```go {title=\"foo}\nx\n```\n", "unterminated quoted value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewCodeBlockExt())
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			err := md.Renderer().Render(&strings.Builder{}, []byte(tt.src), doc)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("render error mismatch: want %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCodeBlockExt_Session(t *testing.T) {
+	prompt := func(p string) string { return tags.SpanAttrs(`class="code-prompt"`, p) }
+	output := func(s string) string { return tags.SpanAttrs(`class="code-output"`, s) }
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"commands and output",
+			"```console\n$ echo hi\nhi\n# ls \\\n  /tmp\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("button", `class="code-copy" type="button" data-copy="echo hi&#10;ls \&#10;  /tmp&#10;"`, "Copy"),
+				tags.DivAttrs(`class="code-block-lang"`, "console"),
+				tags.WrapAttrs("pre", `class="code-block"`,
+					prompt("$ "), "echo hi\n",
+					output("hi"), "\n",
+					prompt("# "), "ls <code-str>\\\n</code-str><code-str></code-str>",
+					"  /tmp\n")),
+		},
+		{
+			"custom prompt",
+			"```shell-session {prompt=\"~> \"}\n~> pwd\n/home\n$ not a command\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("button", `class="code-copy" type="button" data-copy="pwd&#10;"`, "Copy"),
+				tags.WrapAttrs("pre", `class="code-block"`,
+					prompt("~&gt; "), "pwd\n",
+					output("/home"), "\n",
+					output("$ not a command"), "\n")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewCodeBlockExt())
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package mdext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/jsc/pkg/markdown/asts"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	KindCodeCallouts = ast.NewNodeKind("CodeCallouts")
+	KindCodeCallout  = ast.NewNodeKind("CodeCallout")
+)
+
+// calloutBlockAttr is the code block attribute with the 1-based number of the
+// code block among code blocks with explained callouts. Set by
+// codeCalloutTransformer and used to link callout badges to explanations.
+const calloutBlockAttr = "callout-block"
+
+// CodeCallouts is the list of explanations for the callout markers, like
+// "// <1>", in the preceding code block. Created from an ordered list directly
+// after the code block or from a colon block like:
+//
+//	::: callouts
+//	1. Explains the line marked with <1>.
+//	:::
+type CodeCallouts struct {
+	ast.BaseBlock
+	// The 1-based number of the code block, from calloutBlockAttr.
+	Block int
+}
+
+func NewCodeCallouts() *CodeCallouts {
+	return &CodeCallouts{}
+}
+
+func (c *CodeCallouts) Kind() ast.NodeKind {
+	return KindCodeCallouts
+}
+
+func (c *CodeCallouts) Dump(source []byte, level int) {
+	ast.DumpHelper(c, source, level, map[string]string{"Block": strconv.Itoa(c.Block)}, nil)
+}
+
+// CodeCallout is the explanation of a single callout marker.
+type CodeCallout struct {
+	ast.BaseBlock
+	// The 1-based number of the code block, from calloutBlockAttr.
+	Block int
+	// The number in the callout marker, like 2 for "// <2>".
+	Number int
+}
+
+func NewCodeCallout() *CodeCallout {
+	return &CodeCallout{}
+}
+
+func (c *CodeCallout) Kind() ast.NodeKind {
+	return KindCodeCallout
+}
+
+func (c *CodeCallout) Dump(source []byte, level int) {
+	ast.DumpHelper(c, source, level, map[string]string{"Number": strconv.Itoa(c.Number)}, nil)
+}
+
+// calloutMarkerRegexp matches a callout marker in a comment at the end of a
+// line, like "// <1>", "# <1>", "-- <1>", "/* <1> */", or "<!-- <1> -->".
+var calloutMarkerRegexp = regexp.MustCompile(`[ \t]*(?://|#|--|;|/\*|<!--)[ \t]*<(\d+)>[ \t]*(?:\*/|-->)?[ \t]*$`)
+
+// splitCallouts removes the callout marker from each line of code. Returns the
+// code without markers and the callout number of each line, or 0 for lines
+// without a marker. Returns nil callouts if no line has a marker.
+func splitCallouts(code string) (string, []int) {
+	if !strings.Contains(code, ">") {
+		return code, nil
+	}
+	lines := strings.SplitAfter(code, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var callouts []int
+	b := strings.Builder{}
+	b.Grow(len(code))
+	for i, line := range lines {
+		content := strings.TrimSuffix(line, "\n")
+		loc := calloutMarkerRegexp.FindStringSubmatchIndex(content)
+		if loc == nil {
+			b.WriteString(line)
+			continue
+		}
+		num, err := strconv.Atoi(content[loc[2]:loc[3]])
+		if err != nil || num < 1 {
+			b.WriteString(line)
+			continue
+		}
+		if callouts == nil {
+			callouts = make([]int, len(lines))
+		}
+		callouts[i] = num
+		b.WriteString(content[:loc[0]])
+		b.WriteString(line[len(content):])
+	}
+	return b.String(), callouts
+}
+
+// calloutID returns the ID of the explanation for the callout num in the code
+// block.
+func calloutID(block, num int) string {
+	return "callout-" + strconv.Itoa(block) + "-" + strconv.Itoa(num)
+}
+
+// calloutRefID returns the ID of the badge for the callout num in the code
+// block.
+func calloutRefID(block, num int) string {
+	return calloutID(block, num) + "-ref"
+}
+
+// codeCalloutTransformer pairs code blocks that have callout markers with the
+// explanations that follow the code block.
+type codeCalloutTransformer struct{}
+
+func (c codeCalloutTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	src := reader.Source()
+	block := 0
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *CodeInclude:
+			code := ""
+			if fenced, ok := n.(*ast.FencedCodeBlock); ok {
+				code = readAllCodeBlockLines(fenced, src)
+			} else {
+				code = n.(*CodeInclude).Code
+			}
+			_, callouts := splitCallouts(code)
+			if callouts == nil {
+				return ast.WalkSkipChildren, nil
+			}
+			list := calloutList(n.NextSibling())
+			if list == nil {
+				return ast.WalkSkipChildren, nil
+			}
+			block++
+			if err := validateCallouts(callouts, list); err != nil {
+				mdctx.PushErrorAt(pc, src, n, err)
+				return ast.WalkSkipChildren, nil
+			}
+			n.SetAttributeString(calloutBlockAttr, block)
+			replaceCalloutList(list, block)
+			return ast.WalkSkipChildren, nil
+		case *CodeCallouts:
+			if n.Block == 0 {
+				mdctx.PushErrorAt(pc, src, n, errors.New("callouts block must follow a code block with callouts like // <1> and contain only an ordered list"))
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// calloutList returns the ordered list of explanations in n, either the list
+// itself or the only child of a callouts colon block. Returns nil otherwise.
+func calloutList(n ast.Node) *ast.List {
+	if cs, ok := n.(*CodeCallouts); ok {
+		n = cs.FirstChild()
+		if cs.ChildCount() != 1 {
+			return nil
+		}
+	}
+	list, ok := n.(*ast.List)
+	if !ok || !list.IsOrdered() {
+		return nil
+	}
+	return list
+}
+
+// validateCallouts checks that each list item explains exactly one callout
+// marker.
+func validateCallouts(callouts []int, list *ast.List) error {
+	seen := make(map[int]bool, len(callouts))
+	for i, num := range callouts {
+		if num == 0 {
+			continue
+		}
+		if seen[num] {
+			return fmt.Errorf("code block line %d: duplicate callout <%d>", i+1, num)
+		}
+		seen[num] = true
+		if num < list.Start || num >= list.Start+list.ChildCount() {
+			return fmt.Errorf("code block line %d: callout <%d> has no explanation", i+1, num)
+		}
+	}
+	for num := list.Start; num < list.Start+list.ChildCount(); num++ {
+		if !seen[num] {
+			return fmt.Errorf("callout explanation %d has no callout <%d> in the code block", num, num)
+		}
+	}
+	return nil
+}
+
+// replaceCalloutList replaces the list, or the callouts colon block holding
+// the list, with a CodeCallouts node for the code block.
+func replaceCalloutList(list *ast.List, block int) {
+	callouts, ok := list.Parent().(*CodeCallouts)
+	if !ok {
+		callouts = NewCodeCallouts()
+		parent := list.Parent()
+		parent.ReplaceChild(parent, list, callouts)
+	} else {
+		callouts.RemoveChild(callouts, list)
+	}
+	callouts.Block = block
+	num := list.Start
+	for item := list.FirstChild(); item != nil; {
+		next := item.NextSibling()
+		c := NewCodeCallout()
+		c.Block = block
+		c.Number = num
+		asts.Reparent(c, item)
+		callouts.AppendChild(callouts, c)
+		num++
+		item = next
+	}
+}
+
+func (c codeBlockRenderer) renderCallouts(w util.BufWriter, _ []byte, _ ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<ol class='code-callouts'>\n")
+	} else {
+		_, _ = w.WriteString("</ol>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (c codeBlockRenderer) renderCallout(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</li>\n")
+		return ast.WalkContinue, nil
+	}
+	cn := n.(*CodeCallout)
+	num := strconv.Itoa(cn.Number)
+	writeStrings(w,
+		`<li id='`, calloutID(cn.Block, cn.Number), `'>`,
+		`<a class='code-callout' href='#`, calloutRefID(cn.Block, cn.Number), `' aria-label='Back to callout `, num, `'>`, num, "</a> ")
+	return ast.WalkContinue, nil
+}
+
+// writeCalloutBadge writes the numbered badge for a callout marker. Links to
+// the explanation if the code block has explanations, identified by a block
+// number greater than 0.
+func writeCalloutBadge(w io.Writer, block, num int) {
+	n := strconv.Itoa(num)
+	if block == 0 {
+		writeStrings(w, "<span class='code-callout'>", n, "</span>")
+		return
+	}
+	writeStrings(w,
+		`<a class='code-callout' id='`, calloutRefID(block, num), `' href='#`, calloutID(block, num),
+		`' aria-label='Callout `, n, `'>`, n, "</a>")
+}
@@ -0,0 +1,112 @@
+package mdext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/jsc/pkg/htmls/tags"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/mdtest"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func calloutBadge(block, num string) string {
+	return tags.AAttrs(`class="code-callout" id="callout-`+block+`-`+num+`-ref" href="#callout-`+block+`-`+num+`" aria-label="Callout `+num+`"`, num)
+}
+
+func calloutItem(block, num string, content ...string) string {
+	backlink := tags.AAttrs(`class="code-callout" href="#callout-`+block+`-`+num+`-ref" aria-label="Back to callout `+num+`"`, num)
+	return tags.WrapAttrs("li", `id="callout-`+block+`-`+num+`"`, backlink+" "+tags.Join(content...))
+}
+
+func TestCodeBlockExt_Callouts(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"ordered list after code",
+			"```text\nx := 1 // <1>\ny := 2\nz := 3 /* <2> */\n```\n\n1. First *one*.\n2. Second.\n\nafter\n",
+			tags.Join(
+				tags.DivAttrs(`class="code-block-container"`,
+					tags.WrapAttrs("pre", `class="code-block"`,
+						"x := 1", calloutBadge("1", "1"), "\n",
+						"y := 2\n",
+						"z := 3", calloutBadge("1", "2"), "\n")),
+				tags.WrapAttrs("ol", `class="code-callouts"`,
+					calloutItem("1", "1", "First <em>one</em>."),
+					calloutItem("1", "2", "Second.")),
+				tags.P("after")),
+		},
+		{
+			"colon block after code",
+			"```text\nx = 1 # <1>\n```\n\n::: callouts\n1. First.\n:::\n\n```text\ny = 2 -- <1>\n```\n\n1. Again.\n",
+			tags.Join(
+				tags.DivAttrs(`class="code-block-container"`,
+					tags.WrapAttrs("pre", `class="code-block"`, "x = 1", calloutBadge("1", "1"), "\n")),
+				tags.WrapAttrs("ol", `class="code-callouts"`, calloutItem("1", "1", "First.")),
+				tags.DivAttrs(`class="code-block-container"`,
+					tags.WrapAttrs("pre", `class="code-block"`, "y = 2", calloutBadge("2", "1"), "\n")),
+				tags.WrapAttrs("ol", `class="code-callouts"`, calloutItem("2", "1", "Again."))),
+		},
+		{
+			"callout without explanations",
+			"```text\n<p>x</p> <!-- <1> -->\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("pre", `class="code-block"`,
+					"&lt;p&gt;x&lt;/p&gt;", tags.SpanAttrs(`class="code-callout"`, "1"), "\n")),
+		},
+		{
+			"callout with highlighted line",
+			"```text {hl=1}\nx // <1>\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("pre", `class="code-block"`,
+					tags.SpanAttrs(`class="code-line code-line-hl"`, "x", tags.SpanAttrs(`class="code-callout"`, "1"), "\n"))),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewColonBlockExt(), NewCodeBlockExt())
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
+		})
+	}
+}
+
+func TestCodeBlockExt_CalloutErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"missing explanation", "```text\nx // <1>\ny // <2>\n```\n\n1. One.\n", "code block line 2: callout <2> has no explanation"},
+		{"missing callout", "```text\nx // <1>\n```\n\n1. One.\n2. Two.\n", "callout explanation 2 has no callout <2>"},
+		{"duplicate callout", "```text\nx // <1>\ny // <1>\n```\n\n1. One.\n", "code block line 2: duplicate callout <1>"},
+		{"callouts without code", "::: callouts\n1. One.\n:::\n", "callouts block must follow a code block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewColonBlockExt(), NewCodeBlockExt())
+			md.Parser().Parse(text.NewReader([]byte(tt.src)), parser.WithContext(ctx))
+			errs := mdctx.PopErrors(ctx)
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+				t.Fatalf("parse errors mismatch: want %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestSplitCallouts(t *testing.T) {
+	code := "a := 1 // <1>\nb-- // <12>\nc // not <3> a callout\nd <4>\n; <5>\n"
+	gotCode, gotCallouts := splitCallouts(code)
+	wantCode := "a := 1\nb--\nc // not <3> a callout\nd <4>\n\n"
+	if diff := cmp.Diff(wantCode, gotCode); diff != "" {
+		t.Errorf("splitCallouts code mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{1, 12, 0, 0, 5}, gotCallouts); diff != "" {
+		t.Errorf("splitCallouts callouts mismatch (-want +got):\n%s", diff)
+	}
+}
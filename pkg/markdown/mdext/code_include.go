@@ -0,0 +1,256 @@
+package mdext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/texts"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+var KindCodeInclude = ast.NewNodeKind("CodeInclude")
+
+// CodeInclude is a code block with the contents of a source file, created
+// from a single-line colon block without a closing fence, like:
+//
+//	::: include pkg/errs/testdata/capture.go#Capture {hl=3}
+//
+// The path is relative to the repo root or, if the path starts with "./" or
+// "../", relative to the directory of the Markdown file. The optional fragment
+// selects part of the file:
+//
+//   - #Name selects the lines between comment markers "region Name" and
+//     "endregion", like "// region Capture" and "// endregion".
+//   - #L10-L30 selects lines 10 through 30, inclusive. #L10 selects line 10.
+//
+// The attributes in braces are the same as fenced code block attributes. The
+// title defaults to the file path.
+type CodeInclude struct {
+	ast.BaseBlock
+	// The path and fragment as written in the directive.
+	Path string
+	// The language of the included file, from the file extension.
+	Lang string
+	// The code block attributes, like "{hl=3}".
+	Attrs string
+	// The dedented source code.
+	Code string
+}
+
+func NewCodeInclude() *CodeInclude {
+	return &CodeInclude{}
+}
+
+func (c *CodeInclude) Kind() ast.NodeKind {
+	return KindCodeInclude
+}
+
+func (c *CodeInclude) IsRaw() bool {
+	return true
+}
+
+func (c *CodeInclude) Dump(source []byte, level int) {
+	ast.DumpHelper(c, source, level, map[string]string{"Path": c.Path, "Lang": c.Lang}, nil)
+}
+
+// openCodeInclude returns the include node for the directive args and
+// advances the reader past the directive line.
+func openCodeInclude(args string, reader text.Reader, pc parser.Context) *CodeInclude {
+	_, segment := reader.PeekLine()
+	n := NewCodeInclude()
+	n.Lines().Append(segment)
+	reader.AdvanceLine()
+
+	path, attrs, _ := strings.Cut(args, " ")
+	n.Path = path
+	n.Attrs = strings.TrimSpace(attrs)
+	if path == "" {
+		mdctx.PushErrorAt(pc, reader.Source(), n, errors.New("include: missing path"))
+		return n
+	}
+	file, fragment, _ := strings.Cut(path, "#")
+	absPath := resolveIncludePath(file, mdctx.GetFilePath(pc))
+	mdctx.AddDependency(pc, absPath)
+	code, err := readInclude(absPath, fragment)
+	if err != nil {
+		mdctx.PushErrorAt(pc, reader.Source(), n, fmt.Errorf("include %s: %w", path, err))
+		return n
+	}
+	n.Code = code
+	if lexer := matchLexer(filepath.Base(file)); lexer != nil {
+		n.Lang = strings.ToLower(lexer.Config().Name)
+		if aliases := lexer.Config().Aliases; len(aliases) > 0 {
+			n.Lang = aliases[0]
+		}
+	}
+	return n
+}
+
+// resolveIncludePath returns the absolute path of the included file. Paths
+// starting with "./" or "../" are relative to the directory of the Markdown
+// file at mdPath. Other paths are relative to the repo root.
+func resolveIncludePath(file, mdPath string) string {
+	if strings.HasPrefix(file, "./") || strings.HasPrefix(file, "../") {
+		return filepath.Join(filepath.Dir(mdPath), file)
+	}
+	return filepath.Join(git.RootDir(), file)
+}
+
+// readInclude reads the part of the file selected by the fragment, dedented
+// and ending with a single newline.
+func readInclude(path, fragment string) (string, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.SplitAfter(string(bs), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var selected []string
+	switch {
+	case isLineFragment(fragment):
+		start, end, err := parseLineFragment(fragment)
+		if err != nil {
+			return "", err
+		}
+		if end > len(lines) {
+			return "", fmt.Errorf("line %d is after the last line %d", end, len(lines))
+		}
+		selected = lines[start-1 : end]
+	default:
+		selected, err = extractRegion(lines, fragment)
+		if err != nil {
+			return "", err
+		}
+	}
+	// Trim blank lines around the code, like the blank line between a region
+	// marker and a doc comment.
+	code := strings.Trim(texts.Dedent(strings.Join(selected, "")), "\n")
+	if strings.TrimSpace(code) == "" {
+		return "", errors.New("included code is empty")
+	}
+	return code + "\n", nil
+}
+
+// isLineFragment returns true if the fragment is a line range like L10-L30.
+func isLineFragment(fragment string) bool {
+	return len(fragment) > 1 && fragment[0] == 'L' && '0' <= fragment[1] && fragment[1] <= '9'
+}
+
+// parseLineFragment parses a 1-based inclusive line range like L10-L30 or a
+// single line like L10.
+func parseLineFragment(fragment string) (start, end int, err error) {
+	startStr, endStr, isRange := strings.Cut(fragment, "-")
+	start, err = strconv.Atoi(strings.TrimPrefix(startStr, "L"))
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("want a line fragment like L10 or L10-L30, got %q", fragment)
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err = strconv.Atoi(strings.TrimPrefix(endStr, "L"))
+	if err != nil || !strings.HasPrefix(endStr, "L") || end < start {
+		return 0, 0, fmt.Errorf("want a line fragment like L10-L30, got %q", fragment)
+	}
+	return start, end, nil
+}
+
+// extractRegion returns the lines in the named region, without region marker
+// lines. If name is empty, returns all lines without marker lines. If a region
+// appears more than once, joins the lines of each occurrence.
+func extractRegion(lines []string, name string) ([]string, error) {
+	var out []string
+	var open []string // names of the enclosing regions
+	inRegion := name == ""
+	found := name == ""
+	for i, line := range lines {
+		isStart, markerName, ok := parseRegionMarker(line)
+		switch {
+		case !ok:
+			if inRegion {
+				out = append(out, line)
+			}
+		case isStart:
+			open = append(open, markerName)
+			if markerName == name {
+				inRegion = true
+				found = true
+			}
+		default:
+			if len(open) == 0 {
+				return nil, fmt.Errorf("line %d: endregion without a region", i+1)
+			}
+			last := open[len(open)-1]
+			if markerName != "" && markerName != last {
+				return nil, fmt.Errorf("line %d: endregion %s closes region %s", i+1, markerName, last)
+			}
+			open = open[:len(open)-1]
+			if last == name && name != "" {
+				inRegion = false
+			}
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("region %s has no endregion", open[len(open)-1])
+	}
+	if !found {
+		return nil, fmt.Errorf("no region named %s", name)
+	}
+	return out, nil
+}
+
+// regionCommentPrefixes are the line comment starts recognized before a
+// region marker.
+var regionCommentPrefixes = []string{"//", "/*", "<!--", "#", "--", ";"}
+
+// parseRegionMarker parses a comment line with a region marker, like
+// "// region Name" or "# endregion". Returns ok false for other lines.
+func parseRegionMarker(line string) (isStart bool, name string, ok bool) {
+	s := strings.TrimSpace(line)
+	hasComment := false
+	for _, prefix := range regionCommentPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			s = s[len(prefix):]
+			hasComment = true
+			break
+		}
+	}
+	if !hasComment {
+		return false, "", false
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "*/"), "-->")
+	fields := strings.Fields(s)
+	switch {
+	case len(fields) == 2 && fields[0] == "region":
+		return true, fields[1], true
+	case len(fields) == 1 && fields[0] == "endregion":
+		return false, "", true
+	case len(fields) == 2 && fields[0] == "endregion":
+		return false, fields[1], true
+	default:
+		return false, "", false
+	}
+}
+
+// codeIncludeInfo returns the code block info string for the include, used to
+// parse attributes like a fenced code block.
+func codeIncludeInfo(n *CodeInclude) string {
+	return strings.TrimSpace(n.Lang + " " + n.Attrs)
+}
+
+// isIncludeDirective returns true if the colon block line is an include,
+// like "::: include path".
+func isIncludeDirective(rest []byte) bool {
+	name, _, _ := bytes.Cut(rest, []byte{' '})
+	return ColonBlockName(name) == ColonBlockInclude
+}
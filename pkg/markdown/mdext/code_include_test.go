@@ -0,0 +1,127 @@
+package mdext
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/htmls/tags"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/mdtest"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+const includeTestFile = "pkg/markdown/mdext/testdata/include.go"
+
+func TestCodeIncludeExt(t *testing.T) {
+	title := tags.DivAttrs(`class="code-block-title"`, includeTestFile)
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"nested region without markers",
+			"::: include " + includeTestFile + "#Greet\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				title,
+				tags.DivAttrs(`class="code-block-lang"`, "go"),
+				tags.WrapAttrs("pre", `class="code-block"`,
+					"<code-kw>func</code-kw> <code-fn>Greet</code-fn>(name <code-kw>string</code-kw>) {\n",
+					"\tmsg := <code-str>&#34;hello &#34;</code-str> + name\n",
+					"\tfmt.Println(msg)\n",
+					"}\n")),
+		},
+		{
+			"dedents region with attributes",
+			"::: include " + includeTestFile + `#Body {hl=1 title="greet.go"}` + "\n\nafter\n",
+			tags.Join(
+				tags.DivAttrs(`class="code-block-container"`,
+					tags.DivAttrs(`class="code-block-title"`, "greet.go"),
+					tags.WrapAttrs("pre", `class="code-block"`,
+						tags.SpanAttrs(`class="code-line code-line-hl"`, "msg := <code-str>&#34;hello &#34;</code-str> + name\n"))),
+				tags.P("after")),
+		},
+		{
+			"line range",
+			"::: include " + includeTestFile + "#L3-L3 {linenos}\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				title,
+				tags.WrapAttrs("pre", `class="code-block"`,
+					tags.SpanAttrs(`class="code-line"`, lineNum(1), "<code-kw>import</code-kw> <code-str>&#34;fmt&#34;</code-str>\n"))),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewColonBlockExt(), NewCodeBlockExt())
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
+			wantDeps := []string{filepath.Join(git.RootDir(), includeTestFile)}
+			if diff := cmp.Diff(wantDeps, mdctx.GetDependencies(ctx)); diff != "" {
+				t.Errorf("dependencies mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodeIncludeExt_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"missing file", "::: include testdata/missing.go\n", "no such file or directory"},
+		{"missing region", "::: include " + includeTestFile + "#Missing\n", "no region named Missing"},
+		{"line after end", "::: include " + includeTestFile + "#L3-L99\n", "line 99 is after the last line 21"},
+		{"bad line range", "::: include " + includeTestFile + "#L3-4\n", `want a line fragment like L10-L30, got "L3-4"`},
+		{"missing path", "::: include\n", "include: missing path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewColonBlockExt(), NewCodeBlockExt())
+			md.Parser().Parse(text.NewReader([]byte(tt.src)), parser.WithContext(ctx))
+			errs := mdctx.PopErrors(ctx)
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+				t.Fatalf("parse errors mismatch: want %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestExtractRegion(t *testing.T) {
+	lines := strings.SplitAfter(strings.Join([]string{
+		"# region A",
+		"a1",
+		"<!-- region B -->",
+		"b1",
+		"<!-- endregion B -->",
+		"# endregion",
+		"/* region A */",
+		"a2",
+		"/* endregion */",
+		"",
+	}, "\n"), "\n")
+	lines = lines[:len(lines)-1]
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"A", []string{"a1\n", "b1\n", "a2\n"}},
+		{"B", []string{"b1\n"}},
+		{"", []string{"a1\n", "b1\n", "a2\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractRegion(lines, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("extractRegion(%q) mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
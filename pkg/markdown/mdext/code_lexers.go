@@ -0,0 +1,174 @@
+package mdext
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// customLexers are project-defined lexers for languages that chroma doesn't
+// support or highlights poorly, keyed by lowercase alias. Takes precedence
+// over chroma lexers with the same alias.
+var customLexers = make(map[string]chroma.Lexer)
+
+// registerLexer adds the lexer to customLexers under each alias.
+func registerLexer(l chroma.Lexer) chroma.Lexer {
+	for _, alias := range l.Config().Aliases {
+		customLexers[strings.ToLower(alias)] = l
+	}
+	return l
+}
+
+// lookupLexer returns the lexer for the language, preferring custom lexers.
+// Returns nil if no lexer supports the language.
+func lookupLexer(lang string) chroma.Lexer {
+	if l, ok := customLexers[strings.ToLower(lang)]; ok {
+		return l
+	}
+	return lexers.Get(lang)
+}
+
+// matchLexer returns the lexer for the filename, preferring custom lexers.
+// Returns nil if no lexer matches the filename.
+func matchLexer(filename string) chroma.Lexer {
+	for _, l := range customLexers {
+		for _, pattern := range l.Config().Filenames {
+			if ok, _ := filepath.Match(pattern, filename); ok {
+				return l
+			}
+		}
+	}
+	return lexers.Match(filename)
+}
+
+// isKnownLang returns true if a lexer highlights the code block language.
+func isKnownLang(lang string) bool {
+	return lang == "" || isSessionLang(lang) || lookupLexer(lang) != nil
+}
+
+// mathematicaLexer highlights the Wolfram Language. Unlike the chroma lexer,
+// distinguishes function calls, scoping constructs, and built-in constants.
+var mathematicaLexer = registerLexer(chroma.MustNewLazyLexer(
+	&chroma.Config{
+		Name:      "Mathematica",
+		Aliases:   []string{"mathematica", "mma", "wolfram", "wl"},
+		Filenames: []string{"*.nb", "*.wl", "*.wls"},
+	},
+	func() chroma.Rules {
+		return chroma.Rules{
+			"root": {
+				{Pattern: `(?s)\(\*.*?\*\)`, Type: chroma.CommentMultiline},
+				{Pattern: `"(\\\\|\\"|[^"])*"`, Type: chroma.String},
+				{Pattern: chroma.Words(``, `\b`,
+					"Block", "Check", "Do", "For", "Function", "If", "Module", "Return",
+					"Switch", "Table", "Which", "While", "With"), Type: chroma.Keyword},
+				{Pattern: chroma.Words(``, `\b`,
+					"All", "Automatic", "E", "False", "I", "Infinity", "None", "Null",
+					"Pi", "True"), Type: chroma.KeywordConstant},
+				{Pattern: `[A-Za-z$][A-Za-z0-9$]*(?=\[)`, Type: chroma.NameFunction},
+				{Pattern: `[A-Za-z$][A-Za-z0-9$]*_{1,3}[A-Za-z0-9$]*|_{1,3}[A-Za-z0-9$]*`, Type: chroma.NameVariable},
+				{Pattern: `##?\d*`, Type: chroma.NameVariable},
+				{Pattern: "[A-Za-z$][A-Za-z0-9$]*`", Type: chroma.NameNamespace},
+				{Pattern: `[A-Za-z$][A-Za-z0-9$]*`, Type: chroma.Name},
+				{Pattern: `\d+\.\d*(\*\^-?\d+)?|\.\d+|\d+`, Type: chroma.Number},
+				{Pattern: `<\||\|>|//\.|/\.|//|/@|@@@|@@|->|:>|:=|===|=!=|==|!=|<=|>=|&&|\|\||<>|;;|[-+*/^=!<>&@~?|;]`, Type: chroma.Operator},
+				{Pattern: `[\[\](){},]`, Type: chroma.Punctuation},
+				{Pattern: `\s+`, Type: chroma.TextWhitespace},
+				{Pattern: `.`, Type: chroma.Text},
+			},
+		}
+	},
+))
+
+// bazelQueryLexer highlights the Bazel query language, like:
+//
+//	rdeps(//server/...:all, //database:test_database) except kind(test, //...)
+var bazelQueryLexer = registerLexer(chroma.MustNewLazyLexer(
+	&chroma.Config{
+		Name:    "Bazel query",
+		Aliases: []string{"bazel-query", "bazelquery", "bzlquery"},
+	},
+	func() chroma.Rules {
+		return chroma.Rules{
+			"root": {
+				{Pattern: `#.*$`, Type: chroma.CommentSingle},
+				{Pattern: `'[^']*'|"[^"]*"`, Type: chroma.String},
+				{Pattern: chroma.Words(``, `\b`, "except", "in", "intersect", "let", "set", "union"), Type: chroma.Keyword},
+				{Pattern: chroma.Words(``, `(?=\s*\()`,
+					"allpaths", "allrdeps", "attr", "buildfiles", "deps", "filter", "kind",
+					"labels", "loadfiles", "rbuildfiles", "rdeps", "same_pkg_direct_rdeps",
+					"siblings", "some", "somepath", "tests", "visible"), Type: chroma.NameFunction},
+				{Pattern: `\$[A-Za-z_][A-Za-z0-9_]*`, Type: chroma.NameVariable},
+				// Target patterns, like //foo/..., @repo//foo:bar, and :baz.
+				{Pattern: `@?[A-Za-z0-9_.-]*//[^\s,()'"]*|:[^\s,()'"]+`, Type: chroma.StringOther},
+				{Pattern: `\d+`, Type: chroma.Number},
+				{Pattern: `[A-Za-z_][A-Za-z0-9_./-]*`, Type: chroma.Name},
+				{Pattern: `[\^+=-]`, Type: chroma.Operator},
+				{Pattern: `[(),;]`, Type: chroma.Punctuation},
+				{Pattern: `\s+`, Type: chroma.TextWhitespace},
+				{Pattern: `.`, Type: chroma.Text},
+			},
+		}
+	},
+))
+
+// tlaLexer highlights TLA+ specifications.
+var tlaLexer = registerLexer(chroma.MustNewLazyLexer(
+	&chroma.Config{
+		Name:      "TLA+",
+		Aliases:   []string{"tla", "tla+", "tlaplus"},
+		Filenames: []string{"*.tla"},
+	},
+	func() chroma.Rules {
+		return chroma.Rules{
+			"root": {
+				{Pattern: `(?s)\(\*.*?\*\)`, Type: chroma.CommentMultiline},
+				{Pattern: `\\\*.*$`, Type: chroma.CommentSingle},
+				{Pattern: `"(\\\\|\\"|[^"])*"`, Type: chroma.String},
+				{Pattern: `-{4,}|={4,}`, Type: chroma.Punctuation},
+				{Pattern: chroma.Words(``, `\b`,
+					"ASSUME", "ASSUMPTION", "AXIOM", "CASE", "CHOOSE", "CONSTANT", "CONSTANTS",
+					"DOMAIN", "ELSE", "ENABLED", "EXCEPT", "EXTENDS", "IF", "IN", "INSTANCE",
+					"LAMBDA", "LET", "LOCAL", "MODULE", "OTHER", "RECURSIVE", "SUBSET", "THEN",
+					"THEOREM", "UNCHANGED", "UNION", "VARIABLE", "VARIABLES", "WITH"), Type: chroma.Keyword},
+				{Pattern: `\b(WF|SF)_`, Type: chroma.Keyword},
+				{Pattern: chroma.Words(``, `\b`, "BOOLEAN", "FALSE", "STRING", "TRUE"), Type: chroma.KeywordConstant},
+				// Operator definitions, like "Next ==" or "Add(a, b) ==".
+				{Pattern: `^([A-Za-z_][A-Za-z0-9_]*)((?:\([^)]*\))?\s*)(==)`, Type: chroma.ByGroups(chroma.NameFunction, chroma.Text, chroma.Operator)},
+				{Pattern: `\\[A-Za-z]+|/\\|\\/|<=>|=>|==|:=|\|->|->|<-|\[\]|<>|\.\.|[~'#=<>+*/-]`, Type: chroma.Operator},
+				{Pattern: `\d+`, Type: chroma.Number},
+				{Pattern: `[A-Za-z_][A-Za-z0-9_]*`, Type: chroma.Name},
+				{Pattern: `<<|>>|[\[\](){},:@!.|]`, Type: chroma.Punctuation},
+				{Pattern: `\s+`, Type: chroma.TextWhitespace},
+				{Pattern: `.`, Type: chroma.Text},
+			},
+		}
+	},
+))
+
+// codeLangTransformer warns about code blocks with a language that no lexer
+// supports, since the code block renders without highlighting.
+type codeLangTransformer struct{}
+
+func (c codeLangTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	src := reader.Source()
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		fenced, ok := n.(*ast.FencedCodeBlock)
+		if !entering || !ok || fenced.Info == nil {
+			return ast.WalkContinue, nil
+		}
+		// The renderer reports invalid attributes, so ignore the error.
+		lang, _, _ := parseCodeBlockInfo(string(fenced.Info.Segment.Value(src)))
+		if !isKnownLang(lang) {
+			mdctx.PushWarningAt(pc, src, fenced, fmt.Errorf("unknown code block language %q renders without highlighting", lang))
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
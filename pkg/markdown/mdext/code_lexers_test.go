@@ -0,0 +1,73 @@
+package mdext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jschaf/jsc/pkg/htmls/tags"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/mdtest"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestCodeBlockExt_CustomLexers(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"mathematica",
+			"```wolfram\nf[x_] := Module[{y = 2}, x^y] /. True -> \"a\" (* c *)\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("pre", `class="code-block"`,
+					"<code-fn>f</code-fn>[x_] := <code-kw>Module</code-kw>[{y = 2}, x^y] /. ",
+					"<code-kw>True</code-kw> -&gt; <code-str>&#34;a&#34;</code-str> <code-comment>(* c *)</code-comment>\n")),
+		},
+		{
+			"bazel query",
+			"```bazel-query\nrdeps(//server/...:all, @db//:test) except kind(test, :foo) # c\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("pre", `class="code-block"`,
+					"<code-fn>rdeps</code-fn>(<code-str>//server/...:all</code-str>, <code-str>@db//:test</code-str>) ",
+					"<code-kw>except</code-kw> <code-fn>kind</code-fn>(test, <code-str>:foo</code-str>) <code-comment># c</code-comment>\n")),
+		},
+		{
+			"tla+",
+			"```tla\nEXTENDS Naturals\nNext == x' = x + 1 \\* c\n```\n",
+			tags.DivAttrs(`class="code-block-container"`,
+				tags.WrapAttrs("pre", `class="code-block"`,
+					"<code-kw>EXTENDS</code-kw> Naturals\n",
+					"<code-fn>Next</code-fn> == x&#39; = x + 1 <code-comment>\\* c</code-comment>\n")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewCodeBlockExt())
+			doc := md.Parser().Parse(text.NewReader([]byte(tt.src)), parser.WithContext(ctx))
+			if ds := mdctx.PopDiagnostics(ctx); len(ds) > 0 {
+				t.Errorf("want no diagnostics, got %v", ds)
+			}
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
+		})
+	}
+}
+
+func TestCodeBlockExt_UnknownLangWarning(t *testing.T) {
+	src := "```nosuchlang\nfoo\n```\n\n```go\nx\n```\n\n```console\n$ ls\n```\n"
+	md, ctx := mdtest.NewTester(t, NewCodeBlockExt())
+	doc := md.Parser().Parse(text.NewReader([]byte(src)), parser.WithContext(ctx))
+	ws := mdctx.PopDiagnostics(ctx).Warnings()
+	if len(ws) != 1 || !strings.Contains(ws[0].Error(), `unknown code block language "nosuchlang"`) {
+		t.Fatalf("want one unknown language warning, got %v", ws)
+	}
+	want := tags.Join(
+		tags.DivAttrs(`class="code-block-container"`, tags.WrapAttrs("pre", `class="code-block"`, "foo\n")),
+		tags.DivAttrs(`class="code-block-container"`, tags.WrapAttrs("pre", `class="code-block"`, "x\n")),
+		tags.DivAttrs(`class="code-block-container"`,
+			tags.WrapAttrs("button", `class="code-copy" type="button" data-copy="ls&#10;"`, "Copy"),
+			tags.WrapAttrs("pre", `class="code-block"`, tags.SpanAttrs(`class="code-prompt"`, "$ "), "ls\n")),
+	)
+	mdtest.AssertNoRenderDiff(t, doc, md, src, want)
+}
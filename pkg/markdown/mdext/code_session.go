@@ -0,0 +1,114 @@
+package mdext
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+)
+
+// defaultSessionPrompts are the prompts that start a command in a terminal
+// session code block without a prompt attribute.
+var defaultSessionPrompts = []string{"$ ", "# "}
+
+// isSessionLang returns true if the code block language is a terminal
+// session, where lines starting with a prompt are commands and other lines are
+// output, like:
+//
+//	```console
+//	$ echo hello
+//	hello
+//	```
+func isSessionLang(lang string) bool {
+	switch lang {
+	case "console", "shell-session", "bash-session":
+		return true
+	default:
+		return false
+	}
+}
+
+// session is a terminal session split into commands and output.
+type session struct {
+	// The tokens of each line. Commands are highlighted with the shell lexer.
+	// Output lines are a single generic output token.
+	lines [][]chroma.Token
+	// The prompt of each line, or empty for output and continuation lines.
+	prompts []string
+	// Whether each line is output.
+	output []bool
+	// The commands without prompts, one per line, for the copy button.
+	commands string
+}
+
+// tokenizeSession splits the code into commands and output. A line starting
+// with one of the prompts starts a command. A command ending with a backslash
+// continues on the next line.
+func tokenizeSession(lexer chroma.Lexer, code string, prompts []string) (session, error) {
+	lines := strings.SplitAfter(code, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	s := session{
+		lines:   make([][]chroma.Token, 0, len(lines)),
+		prompts: make([]string, 0, len(lines)),
+		output:  make([]bool, 0, len(lines)),
+	}
+	commands := strings.Builder{}
+	for i := 0; i < len(lines); i++ {
+		prompt, cmd, ok := cutPrompt(lines[i], prompts)
+		if !ok {
+			s.lines = append(s.lines, []chroma.Token{{Type: chroma.GenericOutput, Value: lines[i]}})
+			s.prompts = append(s.prompts, "")
+			s.output = append(s.output, true)
+			continue
+		}
+		// Tokenize continued lines together so strings spanning lines
+		// highlight correctly.
+		cmdLines := []string{cmd}
+		for isContinued(cmdLines[len(cmdLines)-1]) && i+1 < len(lines) {
+			i++
+			cmdLines = append(cmdLines, lines[i])
+		}
+		fullCmd := strings.Join(cmdLines, "")
+		if !strings.HasSuffix(fullCmd, "\n") {
+			fullCmd += "\n"
+		}
+		commands.WriteString(fullCmd)
+		iter, err := lexer.Tokenise(nil, fullCmd)
+		if err != nil {
+			return session{}, fmt.Errorf("tokenize command %q: %w", strings.TrimSpace(fullCmd), err)
+		}
+		for j, tokens := range chroma.SplitTokensIntoLines(iter.Tokens()) {
+			p := ""
+			if j == 0 {
+				p = prompt
+			}
+			s.lines = append(s.lines, tokens)
+			s.prompts = append(s.prompts, p)
+			s.output = append(s.output, false)
+		}
+	}
+	s.commands = commands.String()
+	return s, nil
+}
+
+// cutPrompt returns the prompt and the command of a line that starts with one
+// of the prompts. A line with only the prompt, without trailing space, is an
+// empty command.
+func cutPrompt(line string, prompts []string) (prompt, cmd string, ok bool) {
+	for _, p := range prompts {
+		if cmd, ok := strings.CutPrefix(line, p); ok {
+			return p, cmd, true
+		}
+		if trimmed := strings.TrimRight(p, " "); strings.TrimRight(line, " \n") == trimmed && trimmed != "" {
+			return p, strings.TrimPrefix(line, trimmed), true
+		}
+	}
+	return "", "", false
+}
+
+// isContinued returns true if the command line ends with a backslash.
+func isContinued(line string) bool {
+	return strings.HasSuffix(strings.TrimRight(line, " \t\n"), `\`)
+}
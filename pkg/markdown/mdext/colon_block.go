@@ -23,8 +23,15 @@ var KindColonBlock = ast.NewNodeKind("ColonBlock")
 type ColonBlockName string
 
 const (
-	ColonBlockPreview  ColonBlockName = "preview"
-	ColonBlockFootnote ColonBlockName = "footnote"
+	ColonBlockPreview   ColonBlockName = "preview"
+	ColonBlockFootnote  ColonBlockName = "footnote"
+	ColonBlockNote      ColonBlockName = "note"
+	ColonBlockWarning   ColonBlockName = "warning"
+	ColonBlockTip       ColonBlockName = "tip"
+	ColonBlockEpigraph  ColonBlockName = "epigraph"
+	ColonBlockPullQuote ColonBlockName = "pullquote"
+	ColonBlockInclude   ColonBlockName = "include"
+	ColonBlockCallouts  ColonBlockName = "callouts"
 )
 
 // Preview is a link preview.
@@ -108,11 +115,15 @@ type ColonBlock struct {
 
 	Name ColonBlockName
 	Args string
+	// The number of colons in the opening fence. The closing fence must have
+	// the same number of colons.
+	fenceLen int
 }
 
 func NewColonBlock() *ColonBlock {
 	return &ColonBlock{
 		BaseBlock: ast.BaseBlock{},
+		fenceLen:  len(colonBlockDelim),
 	}
 }
 
@@ -135,15 +146,25 @@ func (cbp colonBlockParser) Trigger() []byte {
 	return []byte{':'}
 }
 
-func (cbp colonBlockParser) Open(_ ast.Node, reader text.Reader, _ parser.Context) (ast.Node, parser.State) {
+func (cbp colonBlockParser) Open(_ ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
 	line, _ := reader.PeekLine()
 	if !bytes.HasPrefix(line, []byte(colonBlockDelim)) {
 		return nil, parser.NoChildren
 	}
+	fenceLen := len(line) - len(bytes.TrimLeft(line, ":"))
+	rest := bytes.Trim(line[fenceLen:], " \t\n")
+	if len(rest) == 0 {
+		return nil, parser.NoChildren // a closing fence without an open block
+	}
+	if isIncludeDirective(rest) {
+		// An include has no content, so it has no closing fence.
+		_, args, _ := bytes.Cut(rest, []byte{' '})
+		return openCodeInclude(strings.TrimSpace(string(args)), reader, pc), parser.Close
+	}
 	reader.AdvanceLine()
-	rest := bytes.Trim(line[len(colonBlockDelim):], " \t\n")
 	nameArgs := bytes.SplitN(rest, []byte{' '}, 2)
 	block := NewColonBlock()
+	block.fenceLen = fenceLen
 	if len(nameArgs) >= 1 {
 		block.Name = ColonBlockName(strings.Trim(string(nameArgs[0]), " "))
 	}
@@ -153,17 +174,33 @@ func (cbp colonBlockParser) Open(_ ast.Node, reader text.Reader, _ parser.Contex
 	return block, parser.HasChildren
 }
 
-func (cbp colonBlockParser) Continue(_ ast.Node, reader text.Reader, _ parser.Context) parser.State {
-	line, _ := reader.PeekLine()
-	if bytes.HasPrefix(line, []byte(colonBlockDelim)) {
-		reader.AdvanceLine()
+// Continue closes the block on a line with only the colons of the opening
+// fence. Nest blocks by using more colons for the outer block, like:
+//
+//	:::: note
+//	::: tip
+//	:::
+//	::::
+func (cbp colonBlockParser) Continue(node ast.Node, reader text.Reader, _ parser.Context) parser.State {
+	if _, ok := node.(*CodeInclude); ok {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	fence := bytes.TrimRight(line, " \t\n")
+	if len(fence) == node.(*ColonBlock).fenceLen && len(bytes.TrimLeft(fence, ":")) == 0 {
+		// Advance to the end of the fence, not the next line, so the parser
+		// doesn't treat the next line as a continuation of the last child.
+		reader.Advance(segment.Len() - (len(line) - len(fence)))
 		return parser.Close
 	}
 	return parser.Continue | parser.HasChildren
 }
 
-func (cbp colonBlockParser) Close(node ast.Node, _ text.Reader, pc parser.Context) {
-	block := node.(*ColonBlock)
+func (cbp colonBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	block, ok := node.(*ColonBlock)
+	if !ok {
+		return // a code include, complete when opened
+	}
 	switch block.Name {
 	case ColonBlockPreview:
 		url := block.Args
@@ -176,7 +213,7 @@ func (cbp colonBlockParser) Close(node ast.Node, _ text.Reader, pc parser.Contex
 		// Replace the ColonBlock with a FootnoteBody.
 		name, variant, err := parseFootnoteName(block.Args)
 		if err != nil {
-			mdctx.PushError(pc, fmt.Errorf("close colon block footnote: %w", err))
+			mdctx.PushErrorAt(pc, reader.Source(), node, fmt.Errorf("close colon block footnote: %w", err))
 		}
 		body := NewFootnoteBody()
 		body.Name = name
@@ -186,8 +223,18 @@ func (cbp colonBlockParser) Close(node ast.Node, _ text.Reader, pc parser.Contex
 		parent.ReplaceChild(parent, node, body)
 		AddFootnoteBody(pc, body)
 
+	case ColonBlockNote, ColonBlockWarning, ColonBlockTip, ColonBlockEpigraph, ColonBlockPullQuote:
+		replaceColonBlock(block)
+
+	case ColonBlockCallouts:
+		// Paired with the preceding code block by codeCalloutTransformer.
+		callouts := NewCodeCallouts()
+		asts.Reparent(callouts, node)
+		parent := node.Parent()
+		parent.ReplaceChild(parent, node, callouts)
+
 	default:
-		mdctx.PushError(pc, fmt.Errorf("unknown colon block name %q", block.Name))
+		mdctx.PushErrorAt(pc, reader.Source(), node, fmt.Errorf("unknown colon block name %q", block.Name))
 	}
 }
 
@@ -199,7 +246,8 @@ func (cbp colonBlockParser) CanAcceptIndentedLine() bool {
 	return false // No, the colon block must not be indented.
 }
 
-// colonBlockRenderer renders colon block by omitting them from HTML.
+// colonBlockRenderer renders the nodes created from colon blocks. Omits
+// previews and footnote bodies from the HTML since they render elsewhere.
 type colonBlockRenderer struct{}
 
 func newColonBlockRenderer() colonBlockRenderer {
@@ -208,6 +256,11 @@ func newColonBlockRenderer() colonBlockRenderer {
 
 func (cbr colonBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(KindColonBlock, cbr.renderColonBlock)
+	reg.Register(KindNote, cbr.renderAdmonition)
+	reg.Register(KindWarning, cbr.renderAdmonition)
+	reg.Register(KindTip, cbr.renderAdmonition)
+	reg.Register(KindEpigraph, cbr.renderEpigraph)
+	reg.Register(KindPullQuote, cbr.renderPullQuote)
 }
 
 func (cbr colonBlockRenderer) renderColonBlock(_ util.BufWriter, _ []byte, n ast.Node, _ bool) (ast.WalkStatus, error) {
@@ -228,6 +281,10 @@ func (cbr colonBlockRenderer) renderColonBlock(_ util.BufWriter, _ []byte, n ast
 //	::: preview http://example.com
 //	# header
 //	:::
+//
+// Supports previews, footnote bodies, admonitions (note, warning, tip),
+// epigraphs, pull quotes, source code includes, and code callouts. Render
+// includes and callouts with CodeBlockExt.
 type ColonBlockExt struct{}
 
 func NewColonBlockExt() goldmark.Extender {
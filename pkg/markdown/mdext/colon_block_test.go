@@ -45,3 +45,115 @@ func TestNewColonBlockExt_preview(t *testing.T) {
 		})
 	}
 }
+
+func TestNewColonBlockExt_quotes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"note",
+			texts.Dedent(`
+				::: note
+				Some *content*.
+				:::
+			`),
+			texts.Dedent(`
+				<aside class="admonition admonition-note">
+				  <p class=admonition-title>Note</p>
+				  <p>Some <em>content</em>.</p>
+				</aside>
+			`),
+		},
+		{
+			"warning with title",
+			texts.Dedent(`
+				::: warning Don't <panic>
+				foo
+
+				- bar
+				:::
+			`),
+			texts.Dedent(`
+				<aside class="admonition admonition-warning">
+				  <p class=admonition-title>Don't &lt;panic&gt;</p>
+				  <p>foo</p>
+				  <ul><li>bar</li></ul>
+				</aside>
+			`),
+		},
+		{
+			"tip nested in note",
+			texts.Dedent(`
+				:::: note
+				::: tip
+				foo
+				:::
+				::::
+			`),
+			texts.Dedent(`
+				<aside class="admonition admonition-note">
+				  <p class=admonition-title>Note</p>
+				  <aside class="admonition admonition-tip">
+				    <p class=admonition-title>Tip</p>
+				    <p>foo</p>
+				  </aside>
+				</aside>
+			`),
+		},
+		{
+			"paragraph right after fence",
+			texts.Dedent(`
+				::: note
+				foo
+				:::
+				bar
+			`),
+			texts.Dedent(`
+				<aside class="admonition admonition-note">
+				  <p class=admonition-title>Note</p>
+				  <p>foo</p>
+				</aside>
+				<p>bar</p>
+			`),
+		},
+		{
+			"epigraph with attribution",
+			texts.Dedent(`
+				::: epigraph Hamlet
+				To be, or not to be.
+				:::
+			`),
+			texts.Dedent(`
+				<figure class=epigraph>
+				  <blockquote><p>To be, or not to be.</p></blockquote>
+				  <figcaption>Hamlet</figcaption>
+				</figure>
+			`),
+		},
+		{
+			"pullquote",
+			texts.Dedent(`
+				::: pullquote
+				The **best** part.
+				:::
+			`),
+			texts.Dedent(`
+				<figure class=pullquote>
+				  <blockquote><p>The <strong>best</strong> part.</p></blockquote>
+				</figure>
+			`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewColonBlockExt())
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
+
+			clone := CloneNode(doc)
+			mdtest.AssertNoRenderDiff(t, clone, md, tt.src, tt.want)
+		})
+	}
+}
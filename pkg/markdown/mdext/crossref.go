@@ -0,0 +1,375 @@
+package mdext
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	qjskatex "github.com/graemephi/goldmark-qjs-katex"
+	"github.com/jschaf/jsc/pkg/markdown/asts"
+	"github.com/jschaf/jsc/pkg/markdown/attrs"
+	"github.com/jschaf/jsc/pkg/markdown/extenders"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/ord"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Cross-references link to a labeled figure, table, equation, or section.
+// A label is a trailing {#kind:name} on a figure caption, table caption,
+// display equation, or heading:
+//
+//	CAPTION: Gorilla architecture {#fig:gorilla-arch}
+//
+// A reference is @kind:name in text, like "see @fig:gorilla-arch", and renders
+// as a link with the number of the target, like "Figure 3".
+
+// crossRefKind is the prefix of a cross-reference label, like "fig" in
+// "fig:gorilla-arch".
+type crossRefKind string
+
+const (
+	crossRefFigure   crossRefKind = "fig"
+	crossRefTable    crossRefKind = "tbl"
+	crossRefEquation crossRefKind = "eq"
+	crossRefSection  crossRefKind = "sec"
+)
+
+// crossRefLabelRegexp matches a label at the end of text.
+var crossRefLabelRegexp = regexp.MustCompile(`\s*\{#((?:fig|tbl|eq|sec):[A-Za-z0-9_-]+)\}\s*$`)
+
+// crossRefTarget is a labeled node that a CrossRef links to.
+type crossRefTarget struct {
+	// The link text, like "Figure 3". Sections use the heading text since
+	// sections aren't numbered.
+	text string
+}
+
+var crossRefTargetsCtxKey = parser.NewContextKey()
+
+func getCrossRefTargets(pc parser.Context) map[string]crossRefTarget {
+	targets, ok := pc.Get(crossRefTargetsCtxKey).(map[string]crossRefTarget)
+	if !ok {
+		targets = make(map[string]crossRefTarget)
+		pc.Set(crossRefTargetsCtxKey, targets)
+	}
+	return targets
+}
+
+// addCrossRefTarget records the target for label. Reports duplicate labels as
+// errors positioned at node.
+func addCrossRefTarget(pc parser.Context, source []byte, node ast.Node, label string, target crossRefTarget) {
+	targets := getCrossRefTargets(pc)
+	if _, ok := targets[label]; ok {
+		mdctx.PushErrorAt(pc, source, node, fmt.Errorf("duplicate cross-reference label %q", label))
+		return
+	}
+	targets[label] = target
+}
+
+// trimCrossRefLabel removes a trailing label from the text children of node
+// and returns the label. Returns the empty string if node has no label.
+func trimCrossRefLabel(node ast.Node, source []byte) string {
+	// The label might span multiple text nodes since inline parsers, like small
+	// caps, trigger on spaces.
+	var texts []*ast.Text
+	for c := node.LastChild(); c != nil; c = c.PreviousSibling() {
+		t, ok := c.(*ast.Text)
+		if !ok {
+			break
+		}
+		texts = append(texts, t)
+	}
+	if len(texts) == 0 {
+		return ""
+	}
+	b := &strings.Builder{}
+	for i := len(texts) - 1; i >= 0; i-- {
+		b.Write(texts[i].Segment.Value(source))
+	}
+	s := b.String()
+	m := crossRefLabelRegexp.FindStringSubmatchIndex(s)
+	if m == nil {
+		return ""
+	}
+	label := s[m[2]:m[3]]
+
+	// Trim the matched suffix from the text nodes, last node first.
+	trim := len(s) - m[0]
+	for _, t := range texts {
+		if trim == 0 {
+			break
+		}
+		if n := t.Segment.Len(); n <= trim {
+			trim -= n
+			node.RemoveChild(node, t)
+			continue
+		}
+		t.Segment = t.Segment.WithStop(t.Segment.Stop - trim)
+		trim = 0
+	}
+	return label
+}
+
+// crossRefHeadingTransformer removes labels from headings and uses the label
+// as the heading ID. Runs before headingIDTransformer so the label isn't part
+// of the generated ID or the TOC.
+type crossRefHeadingTransformer struct{}
+
+func (c crossRefHeadingTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	_ = asts.WalkHeadings(doc, func(h *ast.Heading) (ast.WalkStatus, error) {
+		label := trimCrossRefLabel(h, source)
+		if label == "" {
+			return ast.WalkSkipChildren, nil
+		}
+		if kind, _, _ := strings.Cut(label, ":"); crossRefKind(kind) != crossRefSection {
+			mdctx.PushErrorAt(pc, source, h, fmt.Errorf("cross-reference label %q on heading must start with %q", label, crossRefSection+":"))
+			return ast.WalkSkipChildren, nil
+		}
+		h.SetAttributeString("id", []byte(label))
+		mdctx.HeadingIDs(pc)[label] = struct{}{}
+		title := strings.TrimSpace(string(h.Text(source)))
+		addCrossRefTarget(pc, source, h, label, crossRefTarget{text: title})
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// crossRefTransformer numbers labeled figures, tables, and equations, and
+// resolves each CrossRef to its target. Runs after the figure and table
+// caption transformers create the caption nodes and before the continue
+// reading transformer truncates the document, so a reference on the index page
+// resolves even if the target is cut off.
+type crossRefTransformer struct{}
+
+func (c crossRefTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	eqNum := 0
+	var refs []*CrossRef
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *FigCaption:
+			label := c.trimLabel(pc, source, n, crossRefFigure)
+			if label != "" {
+				if fig, ok := n.Parent().(*Figure); ok {
+					fig.Label = label
+				}
+				addCrossRefTarget(pc, source, n, label, crossRefTarget{text: "Figure " + strconv.Itoa(n.Order)})
+			}
+		case *TableCaption:
+			label := c.trimLabel(pc, source, n, crossRefTable)
+			if label != "" {
+				if tbl, ok := n.Parent().(*extast.Table); ok {
+					tbl.SetAttributeString("id", []byte(label))
+				}
+				addCrossRefTarget(pc, source, n, label, crossRefTarget{text: "Table " + strconv.Itoa(n.Order)})
+			}
+		case *ast.Paragraph:
+			if !isDisplayMathParagraph(n, source) {
+				break
+			}
+			label := c.trimLabel(pc, source, n, crossRefEquation)
+			if label == "" {
+				break
+			}
+			eqNum++
+			n.SetAttributeString(attrs.CustomTagAttr, []byte("div"))
+			n.SetAttributeString("class", []byte("equation"))
+			n.SetAttributeString("id", []byte(label))
+			num := NewEquationNumber()
+			num.Order = eqNum
+			n.AppendChild(n, num)
+			addCrossRefTarget(pc, source, n, label, crossRefTarget{text: "Equation " + strconv.Itoa(eqNum)})
+		case *CrossRef:
+			refs = append(refs, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	targets := getCrossRefTargets(pc)
+	path := GetTOMLMeta(pc).Path
+	for _, ref := range refs {
+		target, ok := targets[ref.Label]
+		if !ok {
+			mdctx.PushErrorAt(pc, source, ref, fmt.Errorf("unknown cross-reference label %q", ref.Label))
+			continue
+		}
+		ref.LinkText = target.text
+		// Use an absolute path like citations because the index page might cut
+		// off the target.
+		ref.Destination = path + "#" + ref.Label
+	}
+}
+
+// trimLabel removes the label from node and checks that the label has the
+// kind expected for the node.
+func (c crossRefTransformer) trimLabel(pc parser.Context, source []byte, n ast.Node, want crossRefKind) string {
+	label := trimCrossRefLabel(n, source)
+	if label == "" {
+		return ""
+	}
+	if kind, _, _ := strings.Cut(label, ":"); crossRefKind(kind) != want {
+		mdctx.PushErrorAt(pc, source, n, fmt.Errorf("cross-reference label %q must start with %q", label, want+":"))
+		return ""
+	}
+	return label
+}
+
+// isDisplayMathParagraph returns true if the paragraph starts with display
+// math, like $$ x^2 $$, followed only by text.
+func isDisplayMathParagraph(p *ast.Paragraph, source []byte) bool {
+	first := p.FirstChild()
+	if first == nil || first.Kind() != qjskatex.KindTex {
+		return false
+	}
+	txt, ok := first.NextSibling().(*ast.Text)
+	if !ok {
+		return false
+	}
+	// The Katex node doesn't expose its mode, so check the delimiter.
+	start := txt.Segment.Start
+	return start >= 2 && string(source[start-2:start]) == "$$"
+}
+
+var KindCrossRef = ast.NewNodeKind("CrossRef")
+
+// CrossRef is an inline reference to a labeled node, like @fig:foo. The child
+// text node holds the source of the reference.
+type CrossRef struct {
+	ast.BaseInline
+	// The label of the target, like "fig:foo".
+	Label string
+	// The link text and destination, set when resolving the target.
+	LinkText    string
+	Destination string
+}
+
+func NewCrossRef() *CrossRef {
+	return &CrossRef{}
+}
+
+func (c *CrossRef) Kind() ast.NodeKind {
+	return KindCrossRef
+}
+
+func (c *CrossRef) Dump(source []byte, level int) {
+	ast.DumpHelper(c, source, level, map[string]string{"Label": c.Label}, nil)
+}
+
+var KindEquationNumber = ast.NewNodeKind("EquationNumber")
+
+// EquationNumber is the number shown next to a labeled display equation.
+type EquationNumber struct {
+	ast.BaseInline
+	Order int
+}
+
+func NewEquationNumber() *EquationNumber {
+	return &EquationNumber{}
+}
+
+func (e *EquationNumber) Kind() ast.NodeKind {
+	return KindEquationNumber
+}
+
+func (e *EquationNumber) Dump(source []byte, level int) {
+	ast.DumpHelper(e, source, level, nil, nil)
+}
+
+// crossRefParser parses references like @fig:foo.
+type crossRefParser struct{}
+
+func (c crossRefParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (c crossRefParser) Parse(_ ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	// Skip emails like foo@fig:bar.
+	if prev := block.PrecendingCharacter(); prev < utf8.RuneSelf && util.IsAlphaNumeric(byte(prev)) {
+		return nil
+	}
+	line, segment := block.PeekLine()
+	kind, rest, ok := strings.Cut(string(line[1:]), ":")
+	if !ok {
+		return nil
+	}
+	switch crossRefKind(kind) {
+	case crossRefFigure, crossRefTable, crossRefEquation, crossRefSection:
+	default:
+		return nil
+	}
+	name := 0
+	for name < len(rest) && isCrossRefNameChar(rest[name]) {
+		name++
+	}
+	// Don't end a name with a hyphen, like "@fig:foo-" in "@fig:foo--bar".
+	for name > 0 && rest[name-1] == '-' {
+		name--
+	}
+	if name == 0 {
+		return nil
+	}
+	n := 1 + len(kind) + 1 + name
+	ref := NewCrossRef()
+	ref.Label = kind + ":" + rest[:name]
+	ref.AppendChild(ref, ast.NewTextSegment(text.NewSegment(segment.Start, segment.Start+n)))
+	block.Advance(n)
+	return ref
+}
+
+func isCrossRefNameChar(b byte) bool {
+	return util.IsAlphaNumeric(b) || b == '-' || b == '_'
+}
+
+type crossRefRenderer struct{}
+
+func (c crossRefRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindCrossRef, c.renderCrossRef)
+	reg.Register(KindEquationNumber, c.renderEquationNumber)
+}
+
+func (c crossRefRenderer) renderCrossRef(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+	n := node.(*CrossRef)
+	_, _ = w.WriteString(`<a class="cross-ref" href="`)
+	_, _ = w.Write(util.EscapeHTML(util.URLEscape([]byte(n.Destination), false)))
+	_, _ = w.WriteString(`">`)
+	_, _ = w.Write(util.EscapeHTML([]byte(n.LinkText)))
+	_, _ = w.WriteString("</a>")
+	return ast.WalkSkipChildren, nil
+}
+
+func (c crossRefRenderer) renderEquationNumber(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*EquationNumber)
+		_, _ = w.WriteString(`<span class="equation-number">(`)
+		_, _ = w.WriteString(strconv.Itoa(n.Order))
+		_, _ = w.WriteString(")</span>")
+	}
+	return ast.WalkContinue, nil
+}
+
+type CrossRefExt struct{}
+
+func NewCrossRefExt() CrossRefExt {
+	return CrossRefExt{}
+}
+
+func (c CrossRefExt) Extend(m goldmark.Markdown) {
+	extenders.AddInlineParser(m, crossRefParser{}, ord.CrossRefParser)
+	extenders.AddASTTransform(m, crossRefHeadingTransformer{}, ord.CrossRefHeadingTransformer)
+	extenders.AddASTTransform(m, crossRefTransformer{}, ord.CrossRefTransformer)
+	extenders.AddRenderer(m, crossRefRenderer{}, ord.CrossRefRenderer)
+}
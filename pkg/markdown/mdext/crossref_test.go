@@ -0,0 +1,148 @@
+package mdext
+
+import (
+	"testing"
+
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/mdtest"
+	"github.com/jschaf/jsc/pkg/texts"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestNewCrossRefExt(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"figure",
+			texts.Dedent(`
+				![alt](/a.png)
+
+				CAPTION: First {#fig:first}
+
+				![alt](/b.png)
+
+				CAPTION: Second {#fig:second}
+
+				See @fig:second and @fig:first.
+			`),
+			texts.Dedent(`
+				<figure id="fig:first">
+					<picture><img src="/a.png" alt="alt"></picture>
+					<figcaption><span class="caption-label">Figure 1:</span> First</figcaption>
+				</figure>
+				<figure id="fig:second">
+					<picture><img src="/b.png" alt="alt"></picture>
+					<figcaption><span class="caption-label">Figure 2:</span> Second</figcaption>
+				</figure>
+				<p>See <a class="cross-ref" href="#fig:second">Figure 2</a> and <a class="cross-ref" href="#fig:first">Figure 1</a>.</p>
+			`),
+		},
+		{
+			"table",
+			texts.Dedent(`
+				TABLE: Results {#tbl:results}
+				| a |
+				|---|
+				| 1 |
+
+				Per @tbl:results.
+			`),
+			texts.Dedent(`
+				<table id="tbl:results">
+					<caption><span class=table-caption-order>Table 1:</span> Results</caption>
+					<thead><tr><th>a</th></tr></thead>
+					<tbody><tr><td>1</td></tr></tbody>
+				</table>
+				<p>Per <a class="cross-ref" href="#tbl:results">Table 1</a>.</p>
+			`),
+		},
+		{
+			"section",
+			texts.Dedent(`
+				## Design {#sec:design}
+
+				Read @sec:design first.
+			`),
+			texts.Dedent(`
+				<h2 id="sec:design">Design</h2>
+				<p>Read <a class="cross-ref" href="#sec:design">Design</a> first.</p>
+			`),
+		},
+		{
+			"email is not a reference",
+			texts.Dedent(`
+				Mail me@fig:foo.
+			`),
+			texts.Dedent(`
+				<p>Mail me@fig:foo.</p>
+			`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewCrossRefExt(), NewFigureExt(), NewTableExt(), NewHeadingIDExt(), NewHeadingExt(HeadingAnchorStyleNone))
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
+		})
+	}
+}
+
+func TestNewCrossRefExt_ContinueReading(t *testing.T) {
+	src := texts.Dedent(`
+		See @fig:later.
+
+		CONTINUE_READING
+
+		![alt](/a.png)
+
+		CAPTION: Later {#fig:later}
+	`)
+	want := texts.Dedent(`
+		<p>See <a class="cross-ref" href="/foo/#fig:later">Figure 1</a>.</p>
+	`) + contReadingLink("/foo")
+	md, ctx := mdtest.NewTester(t, NewCrossRefExt(), NewFigureExt(), NewContinueReadingExt())
+	SetTOMLMeta(ctx, PostMeta{Slug: "foo", Path: "/foo/"})
+	doc := mdtest.MustParseMarkdown(t, md, ctx, src)
+	mdtest.AssertNoRenderDiff(t, doc, md, src, want)
+}
+
+func TestNewCrossRefExt_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			"unknown label",
+			"See @fig:missing.\n",
+			`/md/test/path.md:1:5: unknown cross-reference label "fig:missing"`,
+		},
+		{
+			"duplicate label",
+			"## A {#sec:a}\n\n## B {#sec:a}\n",
+			`/md/test/path.md:3:4: duplicate cross-reference label "sec:a"`,
+		},
+		{
+			"wrong kind",
+			"## A {#fig:a}\n",
+			`/md/test/path.md:1:4: cross-reference label "fig:a" on heading must start with "sec:"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewCrossRefExt(), NewFigureExt(), NewTableExt(), NewHeadingIDExt())
+			md.Parser().Parse(text.NewReader([]byte(tt.src)), parser.WithContext(ctx))
+			errs := mdctx.PopErrors(ctx)
+			if len(errs) != 1 {
+				t.Fatalf("want 1 error; got %d: %v", len(errs), errs)
+			}
+			if got := errs[0].Error(); got != tt.wantErr {
+				t.Errorf("want error %q; got %q", tt.wantErr, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package mdext
 
 import (
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/jschaf/jsc/pkg/markdown/extenders"
@@ -28,6 +29,8 @@ type Figure struct {
 	Destination []byte
 	Title       []byte
 	AltText     []byte
+	// The cross-reference label, like "fig:foo", rendered as the figure ID.
+	Label string
 }
 
 func NewFigure() *Figure {
@@ -45,6 +48,8 @@ func (f *Figure) Kind() ast.NodeKind {
 // FigCaption represents the caption for a figure, a `<figcaption>` in HTML5.
 type FigCaption struct {
 	ast.BaseBlock
+	// The 1-based number of the figure among the captioned figures.
+	Order int
 }
 
 func NewFigCaption() *FigCaption {
@@ -113,6 +118,11 @@ func (f *figureASTTransformer) Transform(doc *ast.Document, r text.Reader, pc pa
 		fig.Destination = []byte(newDest)
 		fig.Title = img.Title
 		fig.AltText = img.Text(r.Source())
+		// Keep responsive image attributes, like srcset, from the image
+		// transformer.
+		for _, attr := range img.Attributes() {
+			fig.SetAttribute(attr.Name, attr.Value)
+		}
 
 		para := img.Parent()
 		root := para.Parent()
@@ -121,6 +131,7 @@ func (f *figureASTTransformer) Transform(doc *ast.Document, r text.Reader, pc pa
 	}
 
 	// Pull captions into the figure if they have the appropriate marker.
+	order := 0
 	for _, fig := range figs {
 		capt := fig.NextSibling()
 		if !isCaption(capt, r) {
@@ -129,6 +140,8 @@ func (f *figureASTTransformer) Transform(doc *ast.Document, r text.Reader, pc pa
 		txt := capt.FirstChild().(*ast.Text)
 		txt.Segment.Start += len(figureCaptionMarker)
 		figCaption := NewFigCaption()
+		order++
+		figCaption.Order = order
 		asts.Reparent(figCaption, capt)
 		parent := capt.Parent()
 		parent.RemoveChild(parent, capt)
@@ -155,13 +168,18 @@ func (f *figureRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer)
 func (f *figureRenderer) renderFigure(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*Figure)
 	if entering {
-		_, _ = w.WriteString("<figure>")
+		if n.Label != "" {
+			_, _ = w.WriteString(`<figure id="`)
+			_, _ = w.Write(util.EscapeHTML([]byte(n.Label)))
+			_, _ = w.WriteString(`">`)
+		} else {
+			_, _ = w.WriteString("<figure>")
+		}
 		_, _ = w.WriteString("<picture>")
 		_, _ = w.WriteString("<img src=\"")
 		escapedSrc := util.EscapeHTML(util.URLEscape(n.Destination, true))
 		_, _ = w.Write(escapedSrc)
 		_, _ = w.WriteString(`"`)
-		_, _ = w.WriteString(` loading="lazy"`)
 		_, _ = w.WriteString(` alt="` + string(n.AltText) + `"`)
 		if n.Title != nil {
 			_, _ = w.WriteString(` title="`)
@@ -179,9 +197,12 @@ func (f *figureRenderer) renderFigure(w util.BufWriter, _ []byte, node ast.Node,
 	return ast.WalkContinue, nil
 }
 
-func (f *figureRenderer) renderFigCaption(w util.BufWriter, _ []byte, _ ast.Node, entering bool) (ast.WalkStatus, error) {
+func (f *figureRenderer) renderFigCaption(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		_, _ = w.WriteString("<figcaption><span class=caption-label>Figure:</span>")
+		n := node.(*FigCaption)
+		_, _ = w.WriteString("<figcaption><span class=caption-label>Figure ")
+		_, _ = w.WriteString(strconv.Itoa(n.Order))
+		_, _ = w.WriteString(":</span>")
 	} else {
 		_, _ = w.WriteString("</figcaption>")
 	}
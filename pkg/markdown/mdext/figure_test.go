@@ -21,7 +21,7 @@ func TestNewFigureExt(t *testing.T) {
 			texts.Dedent(`
 			  <figure>
 		  <picture>
-		    <img src="qux.png" loading="lazy" alt="alt text" title="title">
+		    <img src="qux.png" alt="alt text" title="title">
 		  </picture>
 			  </figure>
 		`),
@@ -36,10 +36,10 @@ func TestNewFigureExt(t *testing.T) {
 			texts.Dedent(`
 			  <figure>
 					<picture>
-						<img src="bar.png" loading="lazy" alt="alt text" title="title">
+						<img src="bar.png" alt="alt text" title="title">
 					</picture>
 					<figcaption>
-						<span class="caption-label">Figure:</span>
+						<span class="caption-label">Figure 1:</span>
 						foobar
 					</figcaption>
 			  </figure>
@@ -59,10 +59,10 @@ func TestNewFigureExt(t *testing.T) {
 			texts.Dedent(`
 			  <figure>
 					<picture>
-						<img src="/some_slug/bar.png" loading="lazy" alt="alt text" title="title">
+						<img src="/some_slug/bar.png" alt="alt text" title="title">
 					</picture>
 					<figcaption>
-						<span class="caption-label">Figure:</span>
+						<span class="caption-label">Figure 1:</span>
 						foobar
 					</figcaption>
 			  </figure>
@@ -82,10 +82,10 @@ func TestNewFigureExt(t *testing.T) {
 			texts.Dedent(`
 			  <figure>
 					<picture>
-						<img src="https://example.com/bar.png" loading="lazy" alt="alt text" title="title">
+						<img src="https://example.com/bar.png" alt="alt text" title="title">
 					</picture>
 					<figcaption>
-						<span class="caption-label">Figure:</span>
+						<span class="caption-label">Figure 1:</span>
 						foobar
 					</figcaption>
 			  </figure>
@@ -111,10 +111,10 @@ func TestNewFigureExt(t *testing.T) {
             <p>one</p>
 						<figure>
 							<picture>
-								<img src="https://example.com/bar.png" loading="lazy" alt="alt text" title="title">
+								<img src="https://example.com/bar.png" alt="alt text" title="title">
 							</picture>
 							<figcaption>
-								<span class="caption-label">Figure:</span>
+								<span class="caption-label">Figure 1:</span>
 								foobar
 							</figcaption>
 						</figure>
@@ -140,10 +140,10 @@ func TestNewFigureExt(t *testing.T) {
         </p>
 			  <figure>
 					<picture>
-						<img src="bar.png" loading="lazy" alt="alt text" title="title">
+						<img src="bar.png" alt="alt text" title="title">
 					</picture>
 					<figcaption>
-						<span class="caption-label">Figure:</span>
+						<span class="caption-label">Figure 1:</span>
 						foobar
 					</figcaption>
 			  </figure>
@@ -5,12 +5,14 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/jschaf/bibtex"
 	"github.com/jschaf/jsc/pkg/cite"
+	"github.com/jschaf/jsc/pkg/markdown/assets"
 	"github.com/jschaf/jsc/pkg/markdown/attrs"
 	"github.com/jschaf/jsc/pkg/markdown/extenders"
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
@@ -52,6 +54,12 @@ type FootnoteLink struct {
 	// and cite variant. Always zero for other variants. Duplicate citations
 	// re-use the earliest order number.
 	Order int
+	// Narrative is true for cite variants that name the authors as part of
+	// the sentence, written as [^+@spanner2012].
+	Narrative bool
+	// The citation for cite variants. Nil for other variants or if the
+	// citation key has no bibtex entry.
+	Citation *Citation
 }
 
 func NewFootnoteLink() *FootnoteLink {
@@ -109,7 +117,8 @@ func (f *FootnoteBody) addCiteTag() {
 }
 
 // footnoteLinkParser is an inline parser to parse footnote links like
-// [^side:foo], or [^margin:qux].
+// [^side:foo], or [^margin:qux]. Citations look like [^@foo] or, for narrative
+// citations, [^+@foo].
 type footnoteLinkParser struct{}
 
 func (f footnoteLinkParser) Trigger() []byte {
@@ -151,9 +160,19 @@ func (f footnoteLinkParser) Parse(_ ast.Node, block text.Reader, pc parser.Conte
 
 	block.Advance(closes + 1)
 	link := NewFootnoteLink()
+	if strings.HasPrefix(value, "+@") {
+		link.Narrative = true
+		value = value[1:]
+	}
 	name, variant, err := parseFootnoteName(value)
 	if err != nil {
-		mdctx.PushError(pc, fmt.Errorf("parse inline footnote: %w", err))
+		line, col := mdctx.OffsetPosition(block.Source(), segment.Start+open)
+		mdctx.PushDiagnostic(pc, mdctx.Diagnostic{
+			Line:     line,
+			Col:      col,
+			Severity: mdctx.SeverityError,
+			Err:      fmt.Errorf("parse inline footnote: %w", err),
+		})
 		return nil
 	}
 	link.Name = name
@@ -167,6 +186,8 @@ func (f footnoteLinkParser) Parse(_ ast.Node, block text.Reader, pc parser.Conte
 // parsed by colonBlockParser below the location of the corresponding
 // FootnoteLink.
 type footnoteBodyTransformer struct {
+	citeStyle        cite.Style
+	citeStyleErr     error // non-nil if the site cite style is unsupported
 	citeRefsAttacher CitationReferencesAttacher
 }
 
@@ -193,6 +214,10 @@ func insertFootnoteBody(link *FootnoteLink, body *FootnoteBody) {
 }
 
 func (fb footnoteBodyTransformer) Transform(doc *ast.Document, source text.Reader, pc parser.Context) {
+	if fb.citeStyleErr != nil {
+		mdctx.PushError(pc, fmt.Errorf("site cite style: %w", fb.citeStyleErr))
+		return
+	}
 	links := GetFootnoteLinks(pc)
 	bodies := GetFootnoteBodies(pc)
 	bibs := GetTOMLMeta(pc).BibPaths
@@ -202,6 +227,10 @@ func (fb footnoteBodyTransformer) Transform(doc *ast.Document, source text.Reade
 		return
 	}
 	absPath := GetTOMLMeta(pc).Path
+	citeStyle := fb.citeStyle
+	if s := GetTOMLMeta(pc).CiteStyle; s != "" {
+		citeStyle = s
+	}
 	seenOrders := make(map[string]int)
 	order := 1
 
@@ -221,7 +250,7 @@ func (fb footnoteBodyTransformer) Transform(doc *ast.Document, source text.Reade
 			// All other variants must have a corresponding body node.
 			b, ok := bodies[link.Name]
 			if !ok {
-				mdctx.PushError(pc, fmt.Errorf("no footnote body for footnote link %q", link.Name))
+				mdctx.PushErrorAt(pc, source.Source(), link, fmt.Errorf("no footnote body for footnote link %q", link.Name))
 				continue
 			}
 			body = b
@@ -255,10 +284,16 @@ func (fb footnoteBodyTransformer) Transform(doc *ast.Document, source text.Reade
 			c.Key = bibtex.CiteKey(link.Name)
 			bib, ok := bibEntries[c.Key]
 			if !ok {
-				mdctx.PushError(pc, fmt.Errorf("footnote: no bibtex found for key: %s", c.Key))
-				return
+				mdctx.PushErrorAt(pc, source.Source(), link, fmt.Errorf("footnote: no bibtex found for key: %s", c.Key))
+				continue
 			}
 			c.Bibtex = bib
+			c.Style = citeStyle
+			if err := checkCiteAuthors(c); err != nil {
+				mdctx.PushErrorAt(pc, source.Source(), link, err)
+				continue
+			}
+			link.Citation = c
 
 			// Render preview for hover when we're not showing side notes. Cite
 			// links are preview targets in narrow viewports when the citation
@@ -267,7 +302,7 @@ func (fb footnoteBodyTransformer) Transform(doc *ast.Document, source text.Reade
 			b := &bytes.Buffer{}
 			citeHTML := bufio.NewWriter(b)
 			_, _ = citeHTML.WriteString("<p>")
-			renderCiteRefContent(citeHTML, c)
+			citationStyleFor(citeStyle).RenderReference(citeHTML, c)
 			_, _ = citeHTML.WriteString("</p>")
 			_ = citeHTML.Flush()
 			link.SetAttribute([]byte("data-preview-snippet"), b.Bytes())
@@ -312,29 +347,80 @@ func (fb footnoteBodyTransformer) Transform(doc *ast.Document, source text.Reade
 
 		switch link.Variant {
 		case FootnoteVariantMargin, FootnoteVariantPara: // no cite tag
-		case FootnoteVariantSide, FootnoteVariantCite:
+		case FootnoteVariantCite:
+			// Author-date references start with the authors and year, which
+			// already identify the citation.
+			if !citeStyle.IsAuthorDate() {
+				body.addCiteTag() // depends on order
+			}
+		case FootnoteVariantSide:
 			body.addCiteTag() // depends on order
 		}
 	}
 
 	// Build the citation references. The references section contains 1 copy of
-	// each citation in order by appearance.
+	// each citation in order by appearance, or by author for author-date
+	// styles.
 	refs := NewCitationReferences()
+	refs.Style = citeStyle
 	for _, ref := range citeRefs {
 		refs.Refs = append(refs.Refs, ref)
 	}
 	sort.Slice(refs.Refs, func(i, j int) bool {
 		return refs.Refs[i].Order < refs.Refs[j].Order
 	})
+	if citeStyle.IsAuthorDate() {
+		sortAuthorDate(refs.Refs)
+	}
 
 	// Attach the citation references.
 	if fb.citeRefsAttacher != nil {
+		if len(refs.Refs) > 0 && absPath != "" {
+			exportReferences(pc, refs, bibs)
+		}
 		if err := fb.citeRefsAttacher.Attach(doc, refs); err != nil {
 			mdctx.PushError(pc, fmt.Errorf("attach cite references: %w", err))
 		}
 	}
 }
 
+const (
+	// referencesBibTeXFile is the file name of the BibTeX export of the
+	// references in the post dir.
+	referencesBibTeXFile = "references.bib"
+	// referencesCSLJSONFile is the file name of the CSL-JSON export of the
+	// references in the post dir.
+	referencesCSLJSONFile = "references.json"
+)
+
+// exportReferences adds assets that generate the BibTeX and CSL-JSON files
+// containing only the cited references, in the order of refs, and links them
+// from the references section.
+func exportReferences(pc parser.Context, refs *CitationReferences, bibPaths []string) {
+	keys := make([]bibtex.CiteKey, len(refs.Refs))
+	entries := make([]bibtex.Entry, len(refs.Refs))
+	for i, ref := range refs.Refs {
+		keys[i] = ref.Citation.Key
+		entries[i] = ref.Citation.Bibtex
+	}
+	postPath := GetTOMLMeta(pc).Path
+	refs.BibTeXPath = path.Join(postPath, referencesBibTeXFile)
+	refs.CSLJSONPath = path.Join(postPath, referencesCSLJSONFile)
+	// No single source file determines the exports, so always regenerate.
+	mdctx.AddAsset(pc, assets.Blob{
+		Dest: refs.BibTeXPath,
+		GenFunc: func(dest string) error {
+			return cite.ExportBibTeX(dest, bibPaths, keys)
+		},
+	})
+	mdctx.AddAsset(pc, assets.Blob{
+		Dest: refs.CSLJSONPath,
+		GenFunc: func(dest string) error {
+			return cite.ExportCSLJSON(dest, entries)
+		},
+	})
+}
+
 // readBibs returns all bibtex elements from the file paths in bibs merged into
 // a map by the key.
 func (fb footnoteBodyTransformer) readBibs(bibFiles []string) (map[bibtex.CiteKey]bibtex.Entry, error) {
@@ -420,7 +506,19 @@ func (fr footnoteRenderer) renderFootnoteLink(w util.BufWriter, _ []byte, n ast.
 	switch f.Variant {
 	case FootnoteVariantPara: // no indicator for a paragraph note
 	case FootnoteVariantMargin: // no indicator for a margin note
-	case FootnoteVariantSide, FootnoteVariantCite:
+	case FootnoteVariantCite:
+		if f.Citation == nil {
+			_, _ = w.WriteString("<cite>[" + strconv.Itoa(f.Order) + "]</cite>")
+			break
+		}
+		if f.Citation.Style.IsAuthorDate() {
+			_, _ = w.WriteString("<cite class=cite-author-date>")
+		} else {
+			_, _ = w.WriteString("<cite>")
+		}
+		citationStyleFor(f.Citation.Style).RenderInline(w, f.Citation, f.Order, f.Narrative)
+		_, _ = w.WriteString("</cite>")
+	case FootnoteVariantSide:
 		_, _ = w.WriteString("<cite>[")
 		_, _ = w.WriteString(strconv.Itoa(f.Order))
 		_, _ = w.WriteString("]</cite>")
@@ -443,7 +541,8 @@ func (fr footnoteRenderer) renderFootnoteBody(w util.BufWriter, _ []byte, n ast.
 	return ast.WalkContinue, nil
 }
 
-// FootnoteExt is the Goldmark extension to render a Markdown footnote.
+// FootnoteExt is the Goldmark extension to render a Markdown footnote. The
+// cite style applies to posts that don't set cite_style in the frontmatter.
 type FootnoteExt struct {
 	citeStyle cite.Style
 	attacher  CitationReferencesAttacher
@@ -454,13 +553,16 @@ func NewFootnoteExt(citeStyle cite.Style, attacher CitationReferencesAttacher) *
 }
 
 func (f *FootnoteExt) Extend(m goldmark.Markdown) {
-	if f.citeStyle != cite.IEEE {
-		panic("unsupported cite style: " + f.citeStyle)
-	}
+	// Report an unsupported style when parsing since Extend can't fail.
+	citeStyle, err := cite.ParseStyle(string(f.citeStyle))
 	extenders.AddInlineParser(m, footnoteLinkParser{}, ord.FootnoteLinkParser)
-	extenders.AddASTTransform(m, footnoteBodyTransformer{citeRefsAttacher: f.attacher}, ord.FootnoteBodyTransformer)
+	extenders.AddASTTransform(m, footnoteBodyTransformer{
+		citeStyle:        citeStyle,
+		citeStyleErr:     err,
+		citeRefsAttacher: f.attacher,
+	}, ord.FootnoteBodyTransformer)
 	extenders.AddRenderer(m, footnoteRenderer{}, ord.FootnoteRenderer)
-	extenders.AddRenderer(m, &footnoteIEEERenderer{
+	extenders.AddRenderer(m, &citationRenderer{
 		includeRefs: f.attacher != nil,
 	}, ord.CitationRenderer)
 }
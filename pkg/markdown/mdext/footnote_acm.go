@@ -0,0 +1,88 @@
+package mdext
+
+import (
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/yuin/goldmark/util"
+)
+
+// acmStyle formats citations in the ACM Reference Format. In-text citations
+// are numbered like IEEE.
+// See https://www.acm.org/publications/authors/reference-formatting.
+type acmStyle struct{}
+
+func (acmStyle) RenderInline(w util.BufWriter, c *Citation, order int, narrative bool) {
+	renderNumericInline(w, c, order, narrative)
+}
+
+// RenderReference writes an ACM reference, like:
+//
+//	Tuomas Pelkonen and Scott Franklin. 2015. Gorilla: A Fast, Scalable,
+//	In-Memory Time Series Database. Proc. VLDB Endow. 8, 12 (2015),
+//	1816–1827. https://doi.org/10.14778/2824032.2824078
+func (acmStyle) RenderReference(w util.BufWriter, c *Citation) {
+	var parts []string
+	authors, hasOthers := citeAuthors(c)
+	if len(authors) > 0 {
+		names := make([]string, len(authors))
+		for i, a := range authors {
+			names[i] = authorFull(a)
+		}
+		if hasOthers {
+			parts = append(parts, withPeriod(strings.Join(names, ", ")+", et al."))
+		} else {
+			parts = append(parts, withPeriod(joinNames(names, "and", false)))
+		}
+	}
+	year := citeYear(c)
+	parts = append(parts, year+".")
+
+	switch c.Bibtex.Type {
+	case bibtex.EntryBook:
+		parts = append(parts, `<em class=cite-book>`+citeTitleHTML(c)+`</em>.`)
+	default:
+		parts = append(parts, withPeriod(citeTitleHTML(c)))
+	}
+
+	publisher := citeField(c, bibtex.FieldPublisher)
+	pages := citePages(c)
+	switch c.Bibtex.Type {
+	case bibtex.EntryArticle:
+		// Journal Volume, Number (Year), Pages.
+		src := `<em class=cite-journal>` + citeField(c, bibtex.FieldJournal) + `</em>`
+		if vol := citeField(c, bibtex.FieldVolume); vol != "" {
+			src += " " + vol
+			if num := citeField(c, bibtex.FieldNumber); num != "" {
+				src += ", " + num
+			}
+		}
+		src += " (" + year + ")"
+		if pages != "" {
+			src += ", " + pages
+		}
+		parts = append(parts, src+".")
+	case bibtex.EntryInProceedings:
+		// In Booktitle. Publisher, Pages.
+		parts = append(parts, withPeriod(`In <em class=cite-conference>`+citeField(c, bibtex.FieldBookTitle)+`</em>`))
+		var pub []string
+		if publisher != "" {
+			pub = append(pub, publisher)
+		}
+		if pages != "" {
+			pub = append(pub, pages)
+		}
+		if len(pub) > 0 {
+			parts = append(parts, strings.Join(pub, ", ")+".")
+		}
+	case bibtex.EntryBook:
+		if publisher != "" {
+			parts = append(parts, withPeriod(publisher))
+		}
+	}
+
+	if doi := citeDOIHTML(c); doi != "" {
+		parts = append(parts, doi)
+	}
+	_, _ = w.WriteString(strings.Join(parts, " "))
+}
@@ -0,0 +1,102 @@
+package mdext
+
+import (
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/yuin/goldmark/util"
+)
+
+// apaMaxAuthors is the most authors listed in an APA reference. Longer lists
+// elide the middle authors.
+const apaMaxAuthors = 20
+
+// apaStyle formats citations in the author-date style of the 7th edition of
+// the APA Publication Manual.
+// See https://apastyle.apa.org/style-grammar-guidelines/references.
+type apaStyle struct{}
+
+// RenderInline writes an APA in-text citation, like "(Pelkonen et al., 2015)"
+// or "Pelkonen et al. (2015)" for a narrative citation.
+func (apaStyle) RenderInline(w util.BufWriter, c *Citation, _ int, narrative bool) {
+	if narrative {
+		_, _ = w.WriteString(shortAuthors(c, "and", 2) + " (" + citeYear(c) + ")")
+		return
+	}
+	_, _ = w.WriteString("(" + shortAuthors(c, "&amp;", 2) + ", " + citeYear(c) + ")")
+}
+
+// RenderReference writes an APA reference, like:
+//
+//	Pelkonen, T., &amp; Franklin, S. (2015). Gorilla: A fast, scalable,
+//	in-memory time series database. Proceedings of the VLDB Endowment,
+//	8(12), 1816–1827. https://doi.org/10.14778/2824032.2824078
+func (apaStyle) RenderReference(w util.BufWriter, c *Citation) {
+	var parts []string
+	authors, hasOthers := citeAuthors(c)
+	if len(authors) > 0 {
+		names := make([]string, len(authors))
+		for i, a := range authors {
+			names[i] = authorLast(a)
+			if initials := authorInitials(a); initials != "" {
+				names[i] += ", " + initials
+			}
+		}
+		switch {
+		case len(names) > apaMaxAuthors:
+			// List the first 19 authors, an ellipsis, and the final author.
+			parts = append(parts, withPeriod(strings.Join(names[:apaMaxAuthors-1], ", ")+", . . . "+names[len(names)-1]))
+		case hasOthers:
+			parts = append(parts, withPeriod(strings.Join(names, ", ")+", et al."))
+		case len(names) == 1:
+			parts = append(parts, withPeriod(names[0]))
+		default:
+			parts = append(parts, withPeriod(strings.Join(names[:len(names)-1], ", ")+", &amp; "+names[len(names)-1]))
+		}
+	}
+	parts = append(parts, "("+citeYear(c)+").")
+
+	switch c.Bibtex.Type {
+	case bibtex.EntryBook:
+		parts = append(parts, `<em class=cite-book>`+citeTitleHTML(c)+`</em>.`)
+	default:
+		parts = append(parts, withPeriod(citeTitleHTML(c)))
+	}
+
+	publisher := citeField(c, bibtex.FieldPublisher)
+	pages := citePages(c)
+	switch c.Bibtex.Type {
+	case bibtex.EntryArticle:
+		// Journal, Volume(Number), Pages.
+		src := `<em class=cite-journal>` + citeField(c, bibtex.FieldJournal) + `</em>`
+		if vol := citeField(c, bibtex.FieldVolume); vol != "" {
+			src += ", <em>" + vol + "</em>"
+			if num := citeField(c, bibtex.FieldNumber); num != "" {
+				src += "(" + num + ")"
+			}
+		}
+		if pages != "" {
+			src += ", " + pages
+		}
+		parts = append(parts, src+".")
+	case bibtex.EntryInProceedings:
+		// In Booktitle (pp. Pages). Publisher.
+		src := `In <em class=cite-conference>` + citeField(c, bibtex.FieldBookTitle) + `</em>`
+		if pages != "" {
+			src += " (pp. " + pages + ")"
+		}
+		parts = append(parts, src+".")
+		if publisher != "" {
+			parts = append(parts, withPeriod(publisher))
+		}
+	case bibtex.EntryBook:
+		if publisher != "" {
+			parts = append(parts, withPeriod(publisher))
+		}
+	}
+
+	if doi := citeDOIHTML(c); doi != "" {
+		parts = append(parts, doi)
+	}
+	_, _ = w.WriteString(strings.Join(parts, " "))
+}
@@ -0,0 +1,104 @@
+package mdext
+
+import (
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/yuin/goldmark/util"
+)
+
+const (
+	// chicagoMaxAuthors is the most authors listed in a Chicago reference.
+	chicagoMaxAuthors = 10
+	// chicagoEtAlAuthors is the number of authors listed before "et al." for
+	// references with more than chicagoMaxAuthors.
+	chicagoEtAlAuthors = 7
+)
+
+// chicagoStyle formats citations in the author-date style of the 17th edition
+// of the Chicago Manual of Style.
+// See https://www.chicagomanualofstyle.org/tools_citationguide/citation-guide-2.html.
+type chicagoStyle struct{}
+
+// RenderInline writes a Chicago in-text citation, like
+// "(Pelkonen et al. 2015)" or "Pelkonen et al. (2015)" for a narrative
+// citation.
+func (chicagoStyle) RenderInline(w util.BufWriter, c *Citation, _ int, narrative bool) {
+	if narrative {
+		_, _ = w.WriteString(shortAuthors(c, "and", 3) + " (" + citeYear(c) + ")")
+		return
+	}
+	_, _ = w.WriteString("(" + shortAuthors(c, "and", 3) + " " + citeYear(c) + ")")
+}
+
+// RenderReference writes a Chicago reference, like:
+//
+//	Pelkonen, Tuomas, and Scott Franklin. 2015. "Gorilla: A Fast, Scalable,
+//	In-Memory Time Series Database." Proceedings of the VLDB Endowment 8
+//	(12): 1816–1827. https://doi.org/10.14778/2824032.2824078.
+func (chicagoStyle) RenderReference(w util.BufWriter, c *Citation) {
+	var parts []string
+	authors, hasOthers := citeAuthors(c)
+	if len(authors) > 0 {
+		// Invert only the first author.
+		first := authorLast(authors[0])
+		if given := optionalText(authors[0].First); given != "" {
+			first += ", " + given
+		}
+		names := []string{first}
+		for _, a := range authors[1:] {
+			names = append(names, authorFull(a))
+		}
+		if len(names) > chicagoMaxAuthors || hasOthers {
+			names = names[:min(len(names), chicagoEtAlAuthors)]
+			parts = append(parts, strings.Join(names, ", ")+", et al.")
+		} else {
+			parts = append(parts, withPeriod(joinNames(names, "and", true)))
+		}
+	}
+	parts = append(parts, citeYear(c)+".")
+
+	switch c.Bibtex.Type {
+	case bibtex.EntryBook:
+		parts = append(parts, `<em class=cite-book>`+citeTitleHTML(c)+`</em>.`)
+	default:
+		parts = append(parts, `"`+withPeriod(citeTitleHTML(c))+`"`)
+	}
+
+	publisher := citeField(c, bibtex.FieldPublisher)
+	pages := citePages(c)
+	switch c.Bibtex.Type {
+	case bibtex.EntryArticle:
+		// Journal Volume (Number): Pages.
+		src := `<em class=cite-journal>` + citeField(c, bibtex.FieldJournal) + `</em>`
+		if vol := citeField(c, bibtex.FieldVolume); vol != "" {
+			src += " " + vol
+		}
+		if num := citeField(c, bibtex.FieldNumber); num != "" {
+			src += " (" + num + ")"
+		}
+		if pages != "" {
+			src += ": " + pages
+		}
+		parts = append(parts, src+".")
+	case bibtex.EntryInProceedings:
+		// In Booktitle, Pages. Publisher.
+		src := `In <em class=cite-conference>` + citeField(c, bibtex.FieldBookTitle) + `</em>`
+		if pages != "" {
+			src += ", " + pages
+		}
+		parts = append(parts, src+".")
+		if publisher != "" {
+			parts = append(parts, withPeriod(publisher))
+		}
+	case bibtex.EntryBook:
+		if publisher != "" {
+			parts = append(parts, withPeriod(publisher))
+		}
+	}
+
+	if doi := citeDOIHTML(c); doi != "" {
+		parts = append(parts, doi+".")
+	}
+	_, _ = w.WriteString(strings.Join(parts, " "))
+}
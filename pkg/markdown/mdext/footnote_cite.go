@@ -3,10 +3,19 @@ package mdext
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/jschaf/bibtex"
+	bibast "github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/jsc/pkg/cite"
+	"github.com/jschaf/jsc/pkg/markdown/asts"
+	"github.com/jschaf/jsc/pkg/texts"
 	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
 )
 
 var KindCitation = ast.NewNodeKind("citation")
@@ -22,6 +31,8 @@ type Citation struct {
 	Prefix string
 	// The suffix in a citation reference, i.e the "bar" in `[^@qux bar]`.
 	Suffix string
+	// The style used to render the citation.
+	Style cite.Style
 }
 
 func NewCitation() *Citation {
@@ -81,8 +92,14 @@ func (c *CitationRef) Dump(source []byte, level int) {
 // CitationReferences is a list of citations that appeared in the document.
 type CitationReferences struct {
 	ast.BaseBlock
-	// Unique list of citations refs ordered by appearance.
+	// Unique list of citations refs ordered by appearance for numeric styles
+	// or by author and year for author-date styles.
 	Refs []*CitationRef
+	// The style used to render the references.
+	Style cite.Style
+	// The URL paths of the exported BibTeX and CSL-JSON files with the
+	// references or empty if not exported.
+	BibTeXPath, CSLJSONPath string
 }
 
 func NewCitationReferences() *CitationReferences {
@@ -137,3 +154,305 @@ func (c CitationArticleAttacher) Attach(doc *ast.Document, refs *CitationReferen
 func NewCitationNopAttacher() CitationReferencesAttacher {
 	return nil
 }
+
+// CitationStyle formats citations and references for a cite.Style.
+type CitationStyle interface {
+	// RenderInline writes the in-text citation for c, the order-th footnote
+	// in the document, like "[1]" or "(Pelkonen et al., 2015)". Narrative
+	// citations are part of the sentence, like "Pelkonen et al. [1]" or
+	// "Pelkonen et al. (2015)".
+	RenderInline(w util.BufWriter, c *Citation, order int, narrative bool)
+	// RenderReference writes the full reference for c as shown in side notes,
+	// previews, and the references section.
+	RenderReference(w util.BufWriter, c *Citation)
+}
+
+var citationStyles = map[cite.Style]CitationStyle{
+	cite.IEEE:    ieeeStyle{},
+	cite.ACM:     acmStyle{},
+	cite.APA:     apaStyle{},
+	cite.Chicago: chicagoStyle{},
+}
+
+// citationStyleFor returns the CitationStyle for s, defaulting to IEEE.
+func citationStyleFor(s cite.Style) CitationStyle {
+	if cs, ok := citationStyles[s]; ok {
+		return cs
+	}
+	return ieeeStyle{}
+}
+
+// citationRenderer renders citations and the references section in the
+// citation style stored on each node.
+type citationRenderer struct {
+	includeRefs bool
+}
+
+func (cr *citationRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindCitation, cr.renderCitation)
+	if cr.includeRefs {
+		reg.Register(KindCitationReferences, cr.renderReferenceList)
+	} else {
+		reg.Register(KindCitationReferences, asts.NopRender)
+	}
+}
+
+func (cr *citationRenderer) renderCitation(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+	c := n.(*Citation)
+	citationStyleFor(c.Style).RenderReference(w, c)
+	// Citations generate content solely from the citation, not children.
+	return ast.WalkSkipChildren, nil
+}
+
+func (cr *citationRenderer) renderReferenceList(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+	refs := n.(*CitationReferences)
+	if len(refs.Refs) == 0 {
+		return ast.WalkSkipChildren, nil
+	}
+
+	if refs.Style.IsAuthorDate() {
+		_, _ = w.WriteString(`<div class="cite-references cite-references-author-date">`)
+	} else {
+		_, _ = w.WriteString(`<div class=cite-references>`)
+	}
+	_, _ = w.WriteString(`<h2>References</h2>`)
+	if refs.BibTeXPath != "" && refs.CSLJSONPath != "" {
+		_, _ = w.WriteString(`<p class=cite-export>Download as <a href="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(refs.BibTeXPath)))
+		_, _ = w.WriteString(`" download>BibTeX</a> or <a href="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(refs.CSLJSONPath)))
+		_, _ = w.WriteString(`" download>CSL-JSON</a>.</p>`)
+	}
+	for _, ref := range refs.Refs {
+		cr.renderCiteRef(w, ref, refs.Style)
+	}
+	_, _ = w.WriteString(`</div>`)
+
+	return ast.WalkContinue, nil
+}
+
+// allCiteIDs returns a slice of strings where each string is an HTML ID of a
+// citation.
+func allCiteIDs(cr *CitationRef) []string {
+	ids := make([]string, cr.Count)
+	for i := range ids {
+		ids[i] = cr.Citation.CiteID(i)
+	}
+	return ids
+}
+
+// renderCiteRef renders a single entry of the references section. Numeric
+// styles lead with the citation number. Author-date styles lead with the
+// authors, so the backlinks to the citations trail the reference.
+func (cr *citationRenderer) renderCiteRef(w util.BufWriter, ref *CitationRef, style cite.Style) {
+	_, _ = w.WriteString(`<div id="`)
+	_, _ = w.WriteString(ref.Citation.ReferenceID())
+	_, _ = w.WriteString(`" class=cite-reference>`)
+	if style.IsAuthorDate() {
+		citationStyleFor(style).RenderReference(w, ref.Citation)
+		_ = w.WriteByte(' ')
+		renderCiteBacklinks(w, ref, "↩")
+	} else {
+		renderCiteBacklinks(w, ref, "["+strconv.Itoa(ref.Order)+"]")
+		_ = w.WriteByte(' ')
+		citationStyleFor(style).RenderReference(w, ref.Citation)
+	}
+	_, _ = w.WriteString(`</div>`)
+}
+
+// renderCiteBacklinks renders the preview target that shows every citation
+// of the reference on hover.
+func renderCiteBacklinks(w util.BufWriter, ref *CitationRef, label string) {
+	_, _ = w.WriteString(`<cite class=preview-target data-link-type=cite-reference-num data-cite-ids="`)
+	for i, id := range allCiteIDs(ref) {
+		if i > 0 {
+			_ = w.WriteByte(' ')
+		}
+		_, _ = w.WriteString(id)
+	}
+	_, _ = w.WriteString(`">`)
+	_, _ = w.WriteString(label)
+	_, _ = w.WriteString(`</cite>`)
+}
+
+// sortAuthorDate sorts references by the family name of the first author,
+// then year, then title, as expected by author-date styles.
+func sortAuthorDate(refs []*CitationRef) {
+	key := func(c *Citation) string {
+		authors, _ := citeAuthors(c)
+		name := ""
+		if len(authors) > 0 {
+			name = authorLast(authors[0])
+		}
+		return strings.ToLower(name + "\x00" + citeField(c, bibtex.FieldYear) + "\x00" + citeField(c, bibtex.FieldTitle))
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		return key(refs[i].Citation) < key(refs[j].Citation)
+	})
+}
+
+// checkCiteAuthors returns an error if the author field of the citation isn't
+// a list of authors.
+func checkCiteAuthors(c *Citation) error {
+	x := c.Bibtex.Tags[bibtex.FieldAuthor]
+	if x == nil {
+		return nil
+	}
+	if _, ok := x.(bibast.Authors); !ok {
+		return fmt.Errorf("citation %s: author must be a list of names; got %T", c.Key, x)
+	}
+	return nil
+}
+
+// citeAuthors returns the authors of the citation and whether the author list
+// ends with "and others". Returns no authors if the author field isn't a list
+// of authors, reported by checkCiteAuthors when parsing.
+func citeAuthors(c *Citation) (authors []*bibast.Author, hasOthers bool) {
+	all, ok := c.Bibtex.Tags[bibtex.FieldAuthor].(bibast.Authors)
+	if !ok {
+		return nil, false
+	}
+	for _, a := range all {
+		if a.IsOthers() {
+			hasOthers = true
+			continue
+		}
+		authors = append(authors, a)
+	}
+	return authors, hasOthers
+}
+
+// authorLast returns the family name of the author including the "von" part,
+// like "van Renesse".
+func authorLast(a *bibast.Author) string {
+	last := optionalText(a.Last)
+	if prefix := optionalText(a.Prefix); prefix != "" {
+		return prefix + " " + last
+	}
+	return last
+}
+
+// authorFull returns the given names followed by the family name, like
+// "Tuomas Pelkonen".
+func authorFull(a *bibast.Author) string {
+	if first := optionalText(a.First); first != "" {
+		return first + " " + authorLast(a)
+	}
+	return authorLast(a)
+}
+
+// authorInitials returns the initials of the given names, like "F. Q." for
+// "Fred Q." or "J. L." for "J.L.".
+func authorInitials(a *bibast.Author) string {
+	names := strings.FieldsFunc(optionalText(a.First), func(r rune) bool {
+		return r == ' ' || r == '.'
+	})
+	initials := make([]string, 0, len(names))
+	for _, name := range names {
+		if r, _ := utf8.DecodeRuneInString(name); r != utf8.RuneError {
+			initials = append(initials, string(r)+".")
+		}
+	}
+	return strings.Join(initials, " ")
+}
+
+// shortAuthors returns the family names of the authors for in-text citations,
+// like "Pelkonen", "Pelkonen and Franklin", or "Pelkonen et al.". Lists up to
+// maxNames authors joined by conj before using "et al.". Falls back to the
+// title if the citation has no authors.
+func shortAuthors(c *Citation, conj string, maxNames int) string {
+	authors, hasOthers := citeAuthors(c)
+	switch {
+	case len(authors) == 0:
+		return citeField(c, bibtex.FieldTitle)
+	case len(authors) > maxNames || hasOthers:
+		return authorLast(authors[0]) + " et al."
+	}
+	names := make([]string, len(authors))
+	for i, a := range authors {
+		names[i] = authorLast(a)
+	}
+	return joinNames(names, conj, false)
+}
+
+// joinNames joins names as a list with a serial comma before the final conj,
+// like "A, B, and C". Two names use a comma only if twoComma is true.
+func joinNames(names []string, conj string, twoComma bool) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		if twoComma {
+			return names[0] + ", " + conj + " " + names[1]
+		}
+		return names[0] + " " + conj + " " + names[1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", " + conj + " " + names[len(names)-1]
+}
+
+// citeField returns the text of the bibtex field or the empty string if the
+// citation doesn't have the field.
+func citeField(c *Citation, field bibtex.Field) string {
+	return optionalText(c.Bibtex.Tags[field])
+}
+
+// citeYear returns the year of the citation or "n.d." for no date.
+func citeYear(c *Citation) string {
+	if year := citeField(c, bibtex.FieldYear); year != "" {
+		return year
+	}
+	return "n.d."
+}
+
+// citePages returns the page range of the citation with an en dash, like
+// "82–101".
+func citePages(c *Citation) string {
+	return strings.Replace(citeField(c, bibtex.FieldPages), "--", texts.EnDash, 1)
+}
+
+// citeTitleHTML returns the title of the citation linked to the url field if
+// present.
+func citeTitleHTML(c *Citation) string {
+	title := citeField(c, bibtex.FieldTitle)
+	url := citeField(c, "url")
+	if url == "" {
+		return title
+	}
+	return `<a href="` + string(util.EscapeHTML([]byte(url))) + `">` + title + `</a>`
+}
+
+// citeDOIHTML returns the DOI of the citation as a link to doi.org or the
+// empty string if the citation has no DOI.
+func citeDOIHTML(c *Citation) string {
+	doi := citeField(c, "doi")
+	if doi == "" {
+		return ""
+	}
+	url := "https://doi.org/" + doi
+	return `<a href="` + url + `">` + url + `</a>`
+}
+
+// withPeriod appends a period to s unless s already ends with terminal
+// punctuation, like an initial.
+func withPeriod(s string) string {
+	if strings.HasSuffix(s, ".") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!") {
+		return s
+	}
+	return s + "."
+}
+
+// optionalText returns the simple text of x or the empty string if x is nil.
+func optionalText(x bibast.Expr) string {
+	if x == nil {
+		return ""
+	}
+	return assertSimpleText(x)
+}
@@ -0,0 +1,300 @@
+package mdext
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/jsc/pkg/cite"
+	"github.com/jschaf/jsc/pkg/htmls/tags"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
+	"github.com/jschaf/jsc/pkg/markdown/mdtest"
+	"github.com/jschaf/jsc/pkg/texts"
+)
+
+// newTestCitation returns a citation for the single bibtex entry in bib.
+func newTestCitation(t *testing.T, bib string) *Citation {
+	t.Helper()
+	bibAST, err := cite.Biber.Parse(strings.NewReader(bib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := cite.Biber.Resolve(bibAST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly 1 bibtex entry, had %d", len(entries))
+	}
+	c := NewCitation()
+	c.Key = entries[0].Key
+	c.Bibtex = entries[0]
+	return c
+}
+
+// renderTestReference renders the reference for the bibtex entry in style.
+func renderTestReference(t *testing.T, style CitationStyle, bib string) string {
+	t.Helper()
+	b := newTestBufWriter()
+	style.RenderReference(b, newTestCitation(t, bib))
+	return b.String()
+}
+
+// gorillaBib is a bibtex article with a DOI, volume, number, and pages.
+var gorillaBib = texts.Dedent(`
+	@article{pelkonen2015gorilla,
+		title   = {Gorilla: A Fast, Scalable, In-Memory Time Series Database},
+		author  = {Pelkonen, Tuomas and Franklin, Scott and Teller, Justin},
+		journal = {Proceedings of the VLDB Endowment},
+		volume  = {8},
+		number  = {12},
+		pages   = {1816--1827},
+		year    = {2015},
+		doi     = {10.14778/2824032.2824078}
+	}
+`)
+
+func TestCitationStyle_RenderReference(t *testing.T) {
+	tests := []struct {
+		style cite.Style
+		name  string
+		bib   string
+		want  string
+	}{
+		{
+			cite.ACM,
+			"article",
+			gorillaBib,
+			texts.JoinSpace(
+				`Tuomas Pelkonen, Scott Franklin, and Justin Teller. 2015.`,
+				`Gorilla: A Fast, Scalable, In-Memory Time Series Database.`,
+				`<em class=cite-journal>Proceedings of the VLDB Endowment</em> 8, 12 (2015), 1816`+texts.EnDash+`1827.`,
+				`<a href="https://doi.org/10.14778/2824032.2824078">https://doi.org/10.14778/2824032.2824078</a>`,
+			),
+		},
+		{
+			cite.ACM,
+			"book with others",
+			texts.Dedent(`
+				@book{smith,
+					title     = {Turtles All the Way Down},
+					author    = {Smith, Ann and others},
+					publisher = {Turtle Press},
+					year      = {2001}
+				}
+			`),
+			texts.JoinSpace(
+				`Ann Smith, et al. 2001.`,
+				`<em class=cite-book>Turtles All the Way Down</em>.`,
+				`Turtle Press.`,
+			),
+		},
+		{
+			cite.APA,
+			"article",
+			gorillaBib,
+			texts.JoinSpace(
+				`Pelkonen, T., Franklin, S., &amp; Teller, J. (2015).`,
+				`Gorilla: A Fast, Scalable, In-Memory Time Series Database.`,
+				`<em class=cite-journal>Proceedings of the VLDB Endowment</em>, <em>8</em>(12), 1816`+texts.EnDash+`1827.`,
+				`<a href="https://doi.org/10.14778/2824032.2824078">https://doi.org/10.14778/2824032.2824078</a>`,
+			),
+		},
+		{
+			cite.APA,
+			"inproceedings without year",
+			texts.Dedent(`
+				@inproceedings{smith,
+					title     = {Distributed Turtles},
+					author    = {Smith, Ann},
+					booktitle = {Proceedings of Turtles},
+					pages     = {1--10}
+				}
+			`),
+			texts.JoinSpace(
+				`Smith, A. (n.d.).`,
+				`Distributed Turtles.`,
+				`In <em class=cite-conference>Proceedings of Turtles</em> (pp. 1`+texts.EnDash+`10).`,
+			),
+		},
+		{
+			cite.Chicago,
+			"article",
+			gorillaBib,
+			texts.JoinSpace(
+				`Pelkonen, Tuomas, Scott Franklin, and Justin Teller. 2015.`,
+				`"Gorilla: A Fast, Scalable, In-Memory Time Series Database."`,
+				`<em class=cite-journal>Proceedings of the VLDB Endowment</em> 8 (12): 1816`+texts.EnDash+`1827.`,
+				`<a href="https://doi.org/10.14778/2824032.2824078">https://doi.org/10.14778/2824032.2824078</a>.`,
+			),
+		},
+		{
+			cite.Chicago,
+			"two authors",
+			texts.Dedent(`
+				@misc{smith,
+					title  = {Turtles},
+					author = {Smith, Ann and Jones, Bo},
+					url    = {https://example.com/turtles},
+					year   = {2020}
+				}
+			`),
+			texts.JoinSpace(
+				`Smith, Ann, and Bo Jones. 2020.`,
+				`"<a href="https://example.com/turtles">Turtles</a>."`,
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.style)+" "+tt.name, func(t *testing.T) {
+			got := renderTestReference(t, citationStyleFor(tt.style), tt.bib)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("RenderReference() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckCiteAuthors(t *testing.T) {
+	c := newTestCitation(t, gorillaBib)
+	if err := checkCiteAuthors(c); err != nil {
+		t.Fatalf("checkCiteAuthors() error = %v; want nil", err)
+	}
+	c.Bibtex.Tags[bibtex.FieldAuthor] = c.Bibtex.Tags[bibtex.FieldTitle]
+	want := "citation pelkonen2015gorilla: author must be a list of names"
+	if err := checkCiteAuthors(c); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("checkCiteAuthors() error = %v; want error containing %q", err, want)
+	}
+	if authors, _ := citeAuthors(c); authors != nil {
+		t.Errorf("citeAuthors() = %v; want no authors", authors)
+	}
+}
+
+var firstLinkCiteRegex = regexp.MustCompile(`(?s)^.*?<a [^>]*>(<cite.*?</cite>).*$`)
+
+// onlyFirstLinkCite keeps only the cite element of the first footnote link.
+var onlyFirstLinkCite = cmp.Transformer("onlyFirstLinkCite", func(s string) string {
+	return firstLinkCiteRegex.ReplaceAllString(s, "$1")
+})
+
+func TestNewFootnoteExt_InlineCitations(t *testing.T) {
+	tests := []struct {
+		style cite.Style
+		src   string
+		want  string
+	}{
+		{cite.IEEE, "[^@bib_foo]", tags.Cite("[1]")},
+		{cite.IEEE, "[^+@bib_foo]", tags.Cite("Blogs et al. [1]")},
+		{cite.ACM, "[^+@lea2000concurrent]", tags.Cite("Lea [1]")},
+		{cite.APA, "[^@bib_foo]", tags.CiteAttrs("class=cite-author-date", "(Blogs et al., 2016)")},
+		{cite.APA, "[^+@bib_foo]", tags.CiteAttrs("class=cite-author-date", "Blogs et al. (2016)")},
+		{cite.APA, "[^@lea2000concurrent]", tags.CiteAttrs("class=cite-author-date", "(Lea, 2000)")},
+		{cite.Chicago, "[^@bib_foo]", tags.CiteAttrs("class=cite-author-date", "(Blogs, Doe, and Idiot 2016)")},
+		{cite.Chicago, "[^+@corbett2012spanner]", tags.CiteAttrs("class=cite-author-date", "Corbett et al. (2012)")},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.style)+" "+tt.src, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t,
+				NewFootnoteExt(tt.style, NewCitationNopAttacher()),
+				NewColonBlockExt(), // footnote bodies are colon blocks
+				NewCustomExt(),     // cite tags are implemented via custom
+			)
+			SetTOMLMeta(ctx, PostMeta{
+				BibPaths: []string{"./testdata/citation_test.bib"},
+				Path:     testPath,
+			})
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want, onlyFirstLinkCite)
+		})
+	}
+}
+
+func TestNewFootnoteExt_PostCiteStyle(t *testing.T) {
+	src := "alpha [^@bib_foo]"
+	md, ctx := mdtest.NewTester(t,
+		NewFootnoteExt(cite.IEEE, NewCitationNopAttacher()),
+		NewColonBlockExt(), // footnote bodies are colon blocks
+		NewCustomExt(),     // cite tags are implemented via custom
+	)
+	SetTOMLMeta(ctx, PostMeta{
+		BibPaths:  []string{"./testdata/citation_test.bib"},
+		Path:      testPath,
+		CiteStyle: cite.APA,
+	})
+	doc := mdtest.MustParseMarkdown(t, md, ctx, src)
+	want := tags.CiteAttrs("class=cite-author-date", "(Blogs et al., 2016)")
+	mdtest.AssertNoRenderDiff(t, doc, md, src, want, onlyFirstLinkCite)
+}
+
+func TestNewFootnoteExt_AuthorDateReferences(t *testing.T) {
+	src := "alpha [^@bib_foo] bravo [^@lea2000concurrent] charlie [^@bib_bar] delta [^@bib_foo]"
+	md, ctx := mdtest.NewTester(t,
+		NewFootnoteExt(cite.APA, citeDocAttacher{}),
+		NewColonBlockExt(), // footnote bodies are colon blocks
+		NewCustomExt(),     // cite tags are implemented via custom
+	)
+	SetTOMLMeta(ctx, PostMeta{
+		BibPaths: []string{"./testdata/citation_test.bib"},
+		Path:     testPath,
+	})
+	doc := mdtest.MustParseMarkdown(t, md, ctx, src)
+	ref := func(key, content, citeIDs string) string {
+		return tags.DivAttrs("id=cite_ref_"+key+" class=cite-reference",
+			content, " ",
+			tags.CiteAttrs(`class=preview-target data-link-type=cite-reference-num data-cite-ids="`+citeIDs+`"`, "↩"))
+	}
+	// Sorted by author instead of by appearance.
+	want := tags.DivAttrs(`class="cite-references cite-references-author-date"`,
+		tags.H2("References"),
+		citeExportLinks(testPath),
+		ref("bib_foo",
+			`Blogs, F. Q., Doe, J. P., &amp; Idiot, A. (2016). Turtles in the time continuum. <em class=cite-journal>Turtles in the Applied Sciences</em>, <em>3</em>.`,
+			"footnote-link-bib_foo footnote-link-bib_foo-1"),
+		ref("lea2000concurrent",
+			`Lea, D. (2000). <em class=cite-book>Concurrent Programming in Java: Design Principles and Patterns</em>. Addison-Wesley Professional.`,
+			"footnote-link-lea2000concurrent"),
+		ref("bib_bar",
+			`Ortiz, E., Breads, J. L., &amp; Clarisse, C. (2019). Turtles in the time continuum. <em class=cite-journal>Nature</em>, <em>3</em>.`,
+			"footnote-link-bib_bar"),
+	)
+	mdtest.AssertNoRenderDiff(t, doc, md, src, want, removeAllButReferences)
+}
+
+// citeExportLinks returns the paragraph linking to the exported references
+// of the post at postPath.
+func citeExportLinks(postPath string) string {
+	return tags.WrapAttrs("p", "class=cite-export",
+		"Download as ",
+		tags.AAttrs(`href="`+path.Join(postPath, "references.bib")+`" download`, "BibTeX"),
+		" or ",
+		tags.AAttrs(`href="`+path.Join(postPath, "references.json")+`" download`, "CSL-JSON"),
+		".")
+}
+
+func TestNewFootnoteExt_ExportReferences(t *testing.T) {
+	src := "alpha [^@bib_foo] bravo [^@lea2000concurrent]"
+	md, ctx := mdtest.NewTester(t,
+		NewFootnoteExt(cite.IEEE, citeDocAttacher{}),
+		NewColonBlockExt(), // footnote bodies are colon blocks
+		NewCustomExt(),     // cite tags are implemented via custom
+	)
+	SetTOMLMeta(ctx, PostMeta{
+		BibPaths: []string{"./testdata/citation_test.bib"},
+		Path:     testPath,
+	})
+	_ = mdtest.MustParseMarkdown(t, md, ctx, src)
+	var dests []string
+	for _, blob := range mdctx.GetAssets(ctx) {
+		if blob.GenFunc == nil {
+			t.Errorf("asset %s has no GenFunc", blob.Dest)
+		}
+		dests = append(dests, blob.Dest)
+	}
+	want := []string{path.Join(testPath, "references.bib"), path.Join(testPath, "references.json")}
+	if diff := cmp.Diff(want, dests); diff != "" {
+		t.Errorf("exported reference assets mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -10,85 +10,29 @@ import (
 
 	"github.com/jschaf/bibtex"
 	bibast "github.com/jschaf/bibtex/ast"
-	"github.com/jschaf/jsc/pkg/markdown/asts"
 	"github.com/jschaf/jsc/pkg/texts"
-	"github.com/yuin/goldmark/renderer"
-
-	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/util"
 )
 
-// footnoteIEEERenderer renders an IEEE citation.
-type footnoteIEEERenderer struct {
-	includeRefs bool
-}
+// ieeeStyle formats citations in IEEE style.
+type ieeeStyle struct{}
 
-func (fr *footnoteIEEERenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
-	reg.Register(KindCitation, fr.renderCitation)
-	if fr.includeRefs {
-		reg.Register(KindCitationReferences, fr.renderReferenceList)
-	} else {
-		reg.Register(KindCitationReferences, asts.NopRender)
-	}
+func (ieeeStyle) RenderInline(w util.BufWriter, c *Citation, order int, narrative bool) {
+	renderNumericInline(w, c, order, narrative)
 }
 
-func (fr *footnoteIEEERenderer) renderCitation(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
-	if !entering {
-		return ast.WalkSkipChildren, nil
-	}
-	c := n.(*Citation)
+func (ieeeStyle) RenderReference(w util.BufWriter, c *Citation) {
 	renderCiteRefContent(w, c)
-	// Citations generate content solely from the citation, not children.
-	return ast.WalkSkipChildren, nil
 }
 
-func (fr *footnoteIEEERenderer) renderReferenceList(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
-	if !entering {
-		return ast.WalkSkipChildren, nil
-	}
-	refs := n.(*CitationReferences)
-	if len(refs.Refs) == 0 {
-		return ast.WalkSkipChildren, nil
+// renderNumericInline writes the in-text citation for numeric styles, like
+// "[1]", or "Pelkonen et al. [1]" for a narrative citation.
+func renderNumericInline(w util.BufWriter, c *Citation, order int, narrative bool) {
+	if narrative {
+		_, _ = w.WriteString(shortAuthors(c, "and", 2))
+		_ = w.WriteByte(' ')
 	}
-
-	_, _ = w.WriteString(`<div class=cite-references>`)
-	_, _ = w.WriteString(`<h2>References</h2>`)
-	for _, c := range refs.Refs {
-		fr.renderCiteRef(w, c)
-	}
-	_, _ = w.WriteString(`</div>`)
-
-	return ast.WalkContinue, nil
-}
-
-// allCiteIDs returns a slice of strings where each string is an HTML ID of a
-// citation.
-func allCiteIDs(cr *CitationRef) []string {
-	ids := make([]string, cr.Count)
-	for i := range ids {
-		ids[i] = cr.Citation.CiteID(i)
-	}
-	return ids
-}
-
-func (fr *footnoteIEEERenderer) renderCiteRef(w util.BufWriter, cr *CitationRef) {
-	citeIDs := allCiteIDs(cr)
-	_, _ = w.WriteString(`<div id="`)
-	_, _ = w.WriteString(cr.Citation.ReferenceID())
-	_, _ = w.WriteString(`" class=cite-reference>`)
-	_, _ = w.WriteString(`<cite class=preview-target data-link-type=cite-reference-num data-cite-ids="`)
-	for i, c := range citeIDs {
-		if i > 0 {
-			_ = w.WriteByte(' ')
-		}
-		_, _ = w.WriteString(c)
-	}
-	_, _ = w.WriteString(`">`)
-	_, _ = w.WriteString("[" + strconv.Itoa(cr.Order) + "]")
-	_, _ = w.WriteString(`</cite> `)
-
-	renderCiteRefContent(w, cr.Citation)
-	_, _ = w.WriteString(`</div>`)
+	_, _ = w.WriteString("[" + strconv.Itoa(order) + "]")
 }
 
 // renderCiteRefContent is the main formatter for an IEEE citation.
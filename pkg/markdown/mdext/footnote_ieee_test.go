@@ -221,6 +221,7 @@ func (c citeDocAttacher) Attach(doc *ast.Document, refs *CitationReferences) err
 func newCiteRefsIEEE(ts ...string) string {
 	return tags.DivAttrs("class=cite-references",
 		tags.H2("References"),
+		citeExportLinks(testPath),
 		strings.Join(ts, ""))
 }
 
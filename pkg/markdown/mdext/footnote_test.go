@@ -1,11 +1,15 @@
 package mdext
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/jschaf/jsc/pkg/cite"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 	"github.com/jschaf/jsc/pkg/markdown/mdtest"
 	"github.com/jschaf/jsc/pkg/texts"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
 )
 
 func TestNewFootnoteExt(t *testing.T) {
@@ -88,3 +92,12 @@ func TestNewFootnoteExt(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFootnoteExt_UnsupportedCiteStyle(t *testing.T) {
+	md, ctx := mdtest.NewTester(t, NewFootnoteExt("harvard", NewCitationNopAttacher()))
+	md.Parser().Parse(text.NewReader([]byte("alpha\n")), parser.WithContext(ctx))
+	errs := mdctx.PopErrors(ctx)
+	if want := `site cite style: unknown cite style "harvard"`; len(errs) != 1 || !strings.Contains(errs[0].Error(), want) {
+		t.Fatalf("parse errors mismatch: want %q, got %v", want, errs)
+	}
+}
@@ -19,12 +19,16 @@ import (
 const maxHeadingIDLen = 36
 
 // headingIDTransformer is an AST transformer that adds an ID attribute to each
-// heading.
+// heading without one. Headings with a cross-reference label already use the
+// label as the ID.
 type headingIDTransformer struct{}
 
 func (h headingIDTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
 	ids := mdctx.HeadingIDs(pc)
 	_ = asts.WalkHeadings(node, func(h *ast.Heading) (ast.WalkStatus, error) {
+		if _, ok := h.AttributeString("id"); ok {
+			return ast.WalkSkipChildren, nil
+		}
 		id := generateHeadingID(ids, h, reader.Source())
 		h.SetAttribute([]byte("id"), id)
 		return ast.WalkSkipChildren, nil
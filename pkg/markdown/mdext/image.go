@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/jschaf/jsc/pkg/images"
 	"github.com/jschaf/jsc/pkg/markdown/assets"
 	"github.com/jschaf/jsc/pkg/markdown/extenders"
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
@@ -20,11 +22,18 @@ import (
 	"github.com/yuin/goldmark/util"
 )
 
+// imageSizes is the sizes attribute for responsive images. Images span the
+// viewport on small screens and the fixed-width main column otherwise. Keep in
+// sync with the media queries in main.css.
+const imageSizes = "(max-width: 681px) 100vw, 650px"
+
 // imageASTTransformer extracts images we should copy over to the public dir
-// when publishing posts.
+// when publishing posts. Adds resized variants for local raster images and
+// sets the attributes to load them responsively.
 type imageASTTransformer struct{}
 
-func (f imageASTTransformer) Transform(doc *ast.Document, _ text.Reader, pc parser.Context) {
+func (f imageASTTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	numImgs := 0
 	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkSkipChildren, nil
@@ -33,6 +42,11 @@ func (f imageASTTransformer) Transform(doc *ast.Document, _ text.Reader, pc pars
 			return ast.WalkContinue, nil
 		}
 		img := n.(*ast.Image)
+		numImgs++
+		// The first image is likely above the fold so load it eagerly.
+		if numImgs > 1 {
+			img.SetAttributeString("loading", []byte("lazy"))
+		}
 
 		origDest := string(img.Destination)
 		if path.IsAbs(origDest) || strings.HasPrefix(origDest, "http") {
@@ -49,6 +63,11 @@ func (f imageASTTransformer) Transform(doc *ast.Document, _ text.Reader, pc pars
 			Src:  localPath,
 			Dest: remotePath,
 		})
+		if images.IsResizable(localPath) {
+			if err := addImageVariants(pc, img, localPath, remotePath); err != nil {
+				mdctx.PushWarningAt(pc, reader.Source(), img, err)
+			}
+		}
 		return ast.WalkSkipChildren, nil
 	})
 	if err != nil {
@@ -56,6 +75,41 @@ func (f imageASTTransformer) Transform(doc *ast.Document, _ text.Reader, pc pars
 	}
 }
 
+// addImageVariants adds an asset for each resized variant of the local image
+// narrower than the image. Sets the intrinsic size and srcset attributes on
+// img so browsers pick the smallest variant that fills the layout.
+func addImageVariants(pc parser.Context, img *ast.Image, localPath, remotePath string) error {
+	width, height, err := images.Size(localPath)
+	if err != nil {
+		return fmt.Errorf("read image size: %w", err)
+	}
+	img.SetAttributeString("width", []byte(strconv.Itoa(width)))
+	img.SetAttributeString("height", []byte(strconv.Itoa(height)))
+
+	srcset := make([]string, 0, len(images.Widths)+1)
+	for _, w := range images.Widths {
+		if w >= width {
+			break
+		}
+		variantPath := images.VariantPath(remotePath, w)
+		mdctx.AddAsset(pc, assets.Blob{
+			Src:  localPath,
+			Dest: variantPath,
+			GenFunc: func(dest string) error {
+				return images.Resize(dest, localPath, w)
+			},
+		})
+		srcset = append(srcset, filepath.ToSlash(variantPath)+" "+strconv.Itoa(w)+"w")
+	}
+	if len(srcset) == 0 {
+		return nil
+	}
+	srcset = append(srcset, string(img.Destination)+" "+strconv.Itoa(width)+"w")
+	img.SetAttributeString("srcset", []byte(strings.Join(srcset, ", ")))
+	img.SetAttributeString("sizes", []byte(imageSizes))
+	return nil
+}
+
 // imageRenderer writes images into HTML, replacing the default image renderer.
 type imageRenderer struct{}
 
@@ -63,14 +117,14 @@ func (ir imageRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindImage, ir.renderImage)
 }
 
-func (ir imageRenderer) renderImage(w util.BufWriter, _ []byte, node ast.Node, entering bool) (status ast.WalkStatus, err error) {
+func (ir imageRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (status ast.WalkStatus, err error) {
 	if !entering {
 		return ast.WalkContinue, nil
 	}
 	n := node.(*ast.Image)
 	tag := fmt.Sprintf(
 		"<img src=%q alt=%q title=%q",
-		n.Destination, n.Title, n.Title)
+		n.Destination, n.Text(source), n.Title)
 	_, _ = w.WriteString(tag)
 	if n.Attributes() != nil {
 		html.RenderAttributes(w, n, html.ImageAttributeFilter)
@@ -1,9 +1,15 @@
 package mdext
 
 import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jschaf/jsc/pkg/errs"
 	"github.com/jschaf/jsc/pkg/markdown/assets"
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 	"github.com/jschaf/jsc/pkg/markdown/mdtest"
@@ -65,3 +71,50 @@ func TestNewImageExt(t *testing.T) {
 		})
 	}
 }
+
+func TestNewImageExt_Responsive(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "qux.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, 1500, 1000))); err != nil {
+		t.Fatal(err)
+	}
+	errs.CaptureT(t, f.Close, "close png")
+
+	src := texts.Dedent(`
+		+++
+		slug = "some_slug"
+		+++
+
+		![first](https://example.com/a.png) ![second](./qux.png)
+	`)
+	want := texts.Dedent(`
+		<p>
+		  <img src="https://example.com/a.png" alt="first" title="">
+		  <img src="/some_slug/qux.png" alt="second" title="" loading="lazy" width="1500" height="1000"
+		    srcset="/some_slug/qux-640w.png 640w, /some_slug/qux-1280w.png 1280w, /some_slug/qux.png 1500w"
+		    sizes="(max-width: 681px) 100vw, 650px">
+		</p>
+	`)
+	wantAssets := []assets.Blob{
+		{Src: filepath.Join(dir, "qux.png"), Dest: "/some_slug/qux.png"},
+		{Src: filepath.Join(dir, "qux.png"), Dest: "/some_slug/qux-640w.png"},
+		{Src: filepath.Join(dir, "qux.png"), Dest: "/some_slug/qux-1280w.png"},
+	}
+
+	md, ctx := mdtest.NewTester(t, NewTOMLExt(), NewImageExt())
+	mdctx.SetFilePath(ctx, filepath.Join(dir, "file.md"))
+	doc := mdtest.MustParseMarkdown(t, md, ctx, src)
+	mdtest.AssertNoRenderDiff(t, doc, md, src, want)
+	gotAssets := mdctx.GetAssets(ctx)
+	if diff := cmp.Diff(wantAssets, gotAssets, cmpopts.IgnoreFields(assets.Blob{}, "GenFunc")); diff != "" {
+		t.Fatalf("assets context mismatch (-want +got):\n%s", diff)
+	}
+	for _, a := range gotAssets[1:] {
+		if a.GenFunc == nil {
+			t.Errorf("asset %s should have a GenFunc", a.Dest)
+		}
+	}
+}
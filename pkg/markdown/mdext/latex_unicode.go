@@ -24,11 +24,11 @@ func renderTextTitle(reader text.Reader, heading *ast.Heading) string {
 	// Assume there's only a single math element in the title.
 	lo := strings.IndexByte(s, '$')
 	if lo == -1 {
-		return string(heading.Text(reader.Source()))
+		return plainText(heading, reader.Source())
 	}
 	hi := strings.IndexByte(s[lo+1:], '$')
 	if hi == -1 {
-		return string(heading.Text(reader.Source()))
+		return plainText(heading, reader.Source())
 	}
 	hi += lo + 1
 
@@ -2,13 +2,16 @@ package mdext
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"html"
 	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/jschaf/jsc/pkg/markdown/assets"
 	"github.com/jschaf/jsc/pkg/markdown/extenders"
+	"github.com/jschaf/jsc/pkg/markdown/linkio"
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 	"github.com/jschaf/jsc/pkg/markdown/ord"
 
@@ -60,8 +63,10 @@ func (l *linkAssetTransformer) Transform(doc *ast.Document, _ text.Reader, pc pa
 }
 
 // linkDecorationTransform is an AST transformer that adds preview information
-// to links.
-type linkDecorationTransform struct{}
+// to links. Hand-written previews take precedence over fetched previews.
+type linkDecorationTransform struct {
+	previews *linkio.Cache
+}
 
 const (
 	LinkCitation linkType = "citation"
@@ -89,7 +94,11 @@ func (l linkDecorationTransform) Transform(doc *ast.Document, reader text.Reader
 			link.SetAttribute([]byte("data-link-type"), []byte(LinkWiki))
 		}
 
-		renderPreview(pc, origDest, reader, link)
+		if _, ok := GetPreview(pc, origDest); ok {
+			renderPreview(pc, origDest, reader, link)
+		} else if p, ok := l.previews.Get(origDest); ok {
+			renderFetchedPreview(p, link)
+		}
 
 		return ast.WalkSkipChildren, nil
 	})
@@ -106,7 +115,8 @@ func renderPreview(pc parser.Context, origDest string, reader text.Reader, link
 	}
 	renderer, ok := mdctx.GetRenderer(pc)
 	if !ok {
-		panic("link preview: no renderer")
+		mdctx.PushErrorAt(pc, reader.Source(), link, errors.New("link preview: no renderer"))
+		return
 	}
 
 	colonBlock := preview.Parent
@@ -126,7 +136,8 @@ func renderPreview(pc parser.Context, origDest string, reader text.Reader, link
 	title.SetAttributeString(attrs.CustomTagAttr, "div")
 	titleHTML := &bytes.Buffer{}
 	if err := renderer.Render(titleHTML, reader.Source(), title); err != nil {
-		panic(fmt.Sprintf("render preview title to HTML for %s: %s", mdctx.GetFilePath(pc), err.Error()))
+		pushRenderError(pc, reader.Source(), title, "render preview title to HTML", err)
+		return
 	}
 	link.SetAttribute([]byte("class"), []byte("preview-target"))
 	link.SetAttribute([]byte("data-preview-title"), bytes.Trim(titleHTML.Bytes(), " \n"))
@@ -136,20 +147,47 @@ func renderPreview(pc parser.Context, origDest string, reader text.Reader, link
 	snippetNode := title.NextSibling()
 	for snippetNode != nil {
 		if err := renderer.Render(snippetHTML, reader.Source(), snippetNode); err != nil {
-			panic(fmt.Sprintf("render preview snippet to HTML for %s: %s", mdctx.GetFilePath(pc), err.Error()))
+			pushRenderError(pc, reader.Source(), snippetNode, "render preview snippet to HTML", err)
+			return
 		}
 		snippetNode = snippetNode.NextSibling()
 	}
 	link.SetAttribute([]byte("data-preview-snippet"), bytes.Trim(snippetHTML.Bytes(), " \n"))
 }
 
-type LinkExt struct{}
+// renderFetchedPreview renders a fetched preview into the link attributes,
+// matching the HTML of a hand-written preview.
+func renderFetchedPreview(p linkio.Preview, link *ast.Link) {
+	title := `<div class="preview-title"><a href="` + html.EscapeString(string(link.Destination)) + `">` +
+		html.EscapeString(p.Title) + `</a></div>`
+	link.SetAttribute([]byte("class"), []byte("preview-target"))
+	link.SetAttribute([]byte("data-preview-title"), []byte(title))
+	link.SetAttribute([]byte("data-preview-snippet"), []byte(p.SnippetHTML))
+}
+
+type LinkExt struct {
+	previews *linkio.Cache
+}
 
-func NewLinkExt() *LinkExt {
-	return &LinkExt{}
+// NewLinkExt creates a link extension. Links without a hand-written preview
+// use the preview in previews, if any. A nil previews cache is empty.
+func NewLinkExt(previews *linkio.Cache) *LinkExt {
+	return &LinkExt{previews: previews}
 }
 
 func (l *LinkExt) Extend(m goldmark.Markdown) {
-	extenders.AddASTTransform(m, &linkDecorationTransform{}, ord.LinkDecorationTransformer)
+	extenders.AddASTTransform(m, &linkDecorationTransform{previews: l.previews}, ord.LinkDecorationTransformer)
 	extenders.AddASTTransform(m, &linkAssetTransformer{}, ord.LinkAssetTransformer)
 }
+
+// pushRenderError adds an error from rendering node to the context. Keeps the
+// position of a diagnostic returned by a renderer, like a code block error.
+func pushRenderError(pc parser.Context, source []byte, node ast.Node, msg string, err error) {
+	var d mdctx.Diagnostic
+	if errors.As(err, &d) && d.Line > 0 {
+		d.Err = fmt.Errorf("%s: %w", msg, d.Err)
+		mdctx.PushDiagnostic(pc, d)
+		return
+	}
+	mdctx.PushErrorAt(pc, source, node, fmt.Errorf("%s: %w", msg, err))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/jschaf/jsc/pkg/htmls/tags"
 	"github.com/jschaf/jsc/pkg/markdown/assets"
+	"github.com/jschaf/jsc/pkg/markdown/linkio"
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 	"github.com/jschaf/jsc/pkg/markdown/mdtest"
 
@@ -78,7 +79,7 @@ func TestNewLinkExt_context(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			md, ctx := mdtest.NewTester(t,
-				NewColonBlockExt(), NewTOMLExt(), NewLinkExt(), NewParagraphExt())
+				NewColonBlockExt(), NewTOMLExt(), NewLinkExt(nil), NewParagraphExt())
 			mdctx.SetFilePath(ctx, path)
 
 			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
@@ -125,7 +126,74 @@ func TestNewLinkExt_Preview(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			md, ctx := mdtest.NewTester(t,
-				NewColonBlockExt(), NewTOMLExt(), NewLinkExt(), NewParagraphExt())
+				NewColonBlockExt(), NewTOMLExt(), NewLinkExt(nil), NewParagraphExt())
+			mdctx.SetFilePath(ctx, path)
+
+			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			mdtest.AssertNoRenderDiff(t, doc, md, tt.src, tt.want)
+		})
+	}
+}
+
+func TestNewLinkExt_FetchedPreview(t *testing.T) {
+	const path = "/home/joe/file.md"
+	previews := linkio.NewCache("")
+	previews.Put(linkio.Preview{
+		URL:         "https://en.wikipedia.org/wiki/Wiki",
+		Title:       "Wiki & co",
+		SnippetHTML: "<p>A <b>wiki</b>.</p>",
+	})
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"fetched preview",
+			"[wiki link](https://en.wikipedia.org/wiki/Wiki)",
+			tags.P(
+				tags.AAttrs(
+					tags.Attrs(
+						`href="https://en.wikipedia.org/wiki/Wiki"`,
+						"data-link-type=wikipedia",
+						`class="preview-target"`,
+						`data-preview-title="<div class=&quot;preview-title&quot;><a href=&quot;https://en.wikipedia.org/wiki/Wiki&quot;>Wiki &amp;amp; co</a></div>"`,
+						`data-preview-snippet="<p>A <b>wiki</b>.</p>"`),
+					"wiki link"),
+			),
+		},
+		{
+			"hand-written preview wins",
+			texts.Dedent(`
+				[wiki link](https://en.wikipedia.org/wiki/Wiki)
+
+				::: preview https://en.wikipedia.org/wiki/Wiki
+				preview title
+
+				foo bar
+				:::
+      `),
+			tags.P(
+				tags.AAttrs(
+					tags.Attrs(
+						`href="https://en.wikipedia.org/wiki/Wiki"`,
+						"data-link-type=wikipedia",
+						`class="preview-target"`,
+						`data-preview-title="<div class=&quot;preview-title&quot;><a href=&quot;https://en.wikipedia.org/wiki/Wiki&quot;>preview title</a></div>"`,
+						`data-preview-snippet="<p>foo bar</p>"`),
+					"wiki link"),
+			),
+		},
+		{
+			"no preview",
+			"[other](https://example.com)",
+			tags.P(tags.AAttrs(`href="https://example.com"`, "other")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t,
+				NewColonBlockExt(), NewTOMLExt(), NewLinkExt(previews), NewParagraphExt())
 			mdctx.SetFilePath(ctx, path)
 
 			doc := mdtest.MustParseMarkdown(t, md, ctx, tt.src)
@@ -0,0 +1,114 @@
+package mdext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jschaf/jsc/pkg/markdown/extenders"
+	"github.com/jschaf/jsc/pkg/markdown/ord"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Tag is a topic that groups related posts.
+type Tag struct {
+	// Slug is the tag as written in the TOML frontmatter and the URL path
+	// segment, e.g. "go" for /tags/go/.
+	Slug string
+	// Name is the human-readable name of the tag.
+	Name string
+}
+
+// Path returns the absolute URL path for the tag page. Has trailing slash.
+func (t Tag) Path() string {
+	return "/tags/" + t.Slug + "/"
+}
+
+// tagRegistry declares all tags a post may use. Tags in the frontmatter that
+// aren't in the registry fail the build to catch typos. Add new tags here.
+var tagRegistry = map[string]Tag{
+	"bazel":       {Slug: "bazel", Name: "Bazel"},
+	"databases":   {Slug: "databases", Name: "Databases"},
+	"devops":      {Slug: "devops", Name: "DevOps"},
+	"go":          {Slug: "go", Name: "Go"},
+	"linux":       {Slug: "linux", Name: "Linux"},
+	"mathematica": {Slug: "mathematica", Name: "Mathematica"},
+	"papers":      {Slug: "papers", Name: "Papers"},
+	"security":    {Slug: "security", Name: "Security"},
+	"shell":       {Slug: "shell", Name: "Shell"},
+	"statistics":  {Slug: "statistics", Name: "Statistics"},
+	"typescript":  {Slug: "typescript", Name: "TypeScript"},
+}
+
+// LookupTag returns the declared tag for slug.
+func LookupTag(slug string) (Tag, error) {
+	t, ok := tagRegistry[slug]
+	if !ok {
+		known := make([]string, 0, len(tagRegistry))
+		for s := range tagRegistry {
+			known = append(known, s)
+		}
+		sort.Strings(known)
+		return Tag{}, fmt.Errorf("unknown tag %q; declare it in the tag registry or use one of: %s",
+			slug, strings.Join(known, ", "))
+	}
+	return t, nil
+}
+
+var KindTagList = ast.NewNodeKind("TagList")
+
+// TagList is a block node listing the tags of a post in the article header.
+type TagList struct {
+	ast.BaseBlock
+	Tags []Tag
+}
+
+func NewTagList(tags []Tag) *TagList {
+	return &TagList{Tags: tags}
+}
+
+func (t *TagList) Dump(source []byte, level int) {
+	ast.DumpHelper(t, source, level, nil, nil)
+}
+
+func (t *TagList) Kind() ast.NodeKind {
+	return KindTagList
+}
+
+// tagListRenderer renders HTML for a TagList node.
+type tagListRenderer struct{}
+
+func (tr tagListRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTagList, tr.render)
+}
+
+func (tr tagListRenderer) render(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*TagList)
+	_, _ = w.WriteString(`<ul class="tag-list">`)
+	for _, t := range n.Tags {
+		_, _ = w.WriteString(`<li><a href="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(t.Path())))
+		_, _ = w.WriteString(`">`)
+		_, _ = w.Write(util.EscapeHTML([]byte(t.Name)))
+		_, _ = w.WriteString(`</a></li>`)
+	}
+	_, _ = w.WriteString("</ul>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// TagListExt is the Goldmark extension to render a TagList node.
+type TagListExt struct{}
+
+func NewTagListExt() *TagListExt {
+	return &TagListExt{}
+}
+
+func (t *TagListExt) Extend(m goldmark.Markdown) {
+	extenders.AddRenderer(m, tagListRenderer{}, ord.TagListRenderer)
+}
@@ -0,0 +1,21 @@
+package testdata
+
+import "fmt"
+
+// region Greet
+func Greet(name string) {
+	// region Body
+	msg := "hello " + name
+	// endregion Body
+	fmt.Println(msg)
+}
+
+// endregion
+
+func Indented() {
+	// region Indented
+	if true {
+		fmt.Println("indented")
+	}
+	// endregion
+}
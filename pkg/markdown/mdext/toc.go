@@ -101,6 +101,8 @@ func (t tocTransformer) Transform(node *ast.Document, _ text.Reader, pc parser.C
 		return ast.WalkSkipChildren, nil
 	})
 
+	toc.Headings = headings
+
 	// The count per 1-indexed ast.Heading.Level: 1-6. Must increment before use.
 	counts := make([]int, 7)
 	l, _ := createTOCListLevel(headings, 2, counts) // start at 2 since 1 is the title
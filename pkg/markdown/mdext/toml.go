@@ -4,12 +4,19 @@ package mdext
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/jschaf/jsc/pkg/cite"
 	"github.com/jschaf/jsc/pkg/dirs"
 	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/markdown/assets"
 	"github.com/jschaf/jsc/pkg/markdown/extenders"
 	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 	"github.com/jschaf/jsc/pkg/markdown/ord"
@@ -26,6 +33,7 @@ const tomlSep = '+'
 
 const (
 	VisibilityPublished = "published"
+	VisibilityDraft     = "draft"
 )
 
 // PostMeta is the TOML metadata of a post.
@@ -43,6 +51,28 @@ type PostMeta struct {
 	Visibility string
 	// Paths (relative or absolute) to bibtex files to resolve references.
 	BibPaths []string `toml:"bib_paths"`
+	// Tag slugs from the markdown frontmatter. Each tag must be declared in the
+	// tag registry.
+	Tags []string
+	// The citation style for the post, like "APA". If empty, uses the cite
+	// style of the site.
+	CiteStyle cite.Style `toml:"cite_style"`
+	// A short summary shown in search results and shared links. Defaults to
+	// the text of the first paragraph.
+	Description string
+	// The URL path or URL of the image shown on shared links. A relative path
+	// starts from the post dir. Defaults to the first figure.
+	Image string
+	// If true, also compiles the post into an EPUB for e-readers, linked from
+	// the detail page.
+	EPUB bool `toml:"epub"`
+}
+
+// IsPublished returns true if readers may see the post at time now. A post is
+// published if the visibility is published and the date isn't in the future,
+// so a future-dated post goes live with the first build on or after its date.
+func (m PostMeta) IsPublished(now time.Time) bool {
+	return m.Visibility == VisibilityPublished && !m.Date.After(now)
 }
 
 var tomlCtxKey = parser.NewContextKey()
@@ -115,14 +145,14 @@ func (t *tomlParser) Close(node ast.Node, reader text.Reader, pc parser.Context)
 	}
 	meta := &PostMeta{}
 	if err := toml.Unmarshal(buf.Bytes(), &meta); err != nil {
-		panic(err)
+		pushTOMLError(pc, reader.Source(), node, err)
+		node.Parent().RemoveChild(node.Parent(), node)
+		return
 	}
-	switch {
-	case strings.Contains(mdctx.GetFilePath(pc), `/`+dirs.TIL+`/`):
-		meta.Path = "/til/" + meta.Slug + "/"
-	case strings.Contains(mdctx.GetFilePath(pc), `/`+dirs.Book+`/`):
+	// TILs are served from the root like posts. Only book chapters are nested.
+	if strings.Contains(mdctx.GetFilePath(pc), `/`+dirs.Book+`/`) {
 		meta.Path = "/book/" + meta.Slug + "/"
-	default:
+	} else {
 		meta.Path = "/" + meta.Slug + "/"
 	}
 
@@ -138,11 +168,60 @@ func (t *tomlParser) Close(node ast.Node, reader text.Reader, pc parser.Context)
 		}
 	}
 
+	if meta.Image != "" && !path.IsAbs(meta.Image) && !strings.HasPrefix(meta.Image, "http") {
+		mdctx.AddAsset(pc, assets.Blob{
+			Src:  filepath.Join(filepath.Dir(postPath), meta.Image),
+			Dest: path.Join(meta.Path, meta.Image),
+		})
+		meta.Image = path.Join(meta.Path, meta.Image)
+	}
+
+	for _, tag := range meta.Tags {
+		if _, err := LookupTag(tag); err != nil {
+			mdctx.PushErrorAt(pc, reader.Source(), node, err)
+		}
+	}
+
+	if meta.CiteStyle != "" {
+		style, err := cite.ParseStyle(string(meta.CiteStyle))
+		if err != nil {
+			mdctx.PushErrorAt(pc, reader.Source(), node, err)
+		}
+		meta.CiteStyle = style
+	}
+
 	SetTOMLMeta(pc, *meta)
 
 	node.Parent().RemoveChild(node.Parent(), node)
 }
 
+// tomlLinePrefix matches the line prefix of a TOML parse error. The line is
+// relative to the frontmatter, so it's misleading in a markdown diagnostic.
+var tomlLinePrefix = regexp.MustCompile(`^toml: line \d+( \(last key "[^"]*"\))?: `)
+
+// pushTOMLError adds a diagnostic for a frontmatter decode error, positioned
+// at the TOML syntax error if known or at the start of the frontmatter.
+func pushTOMLError(pc parser.Context, source []byte, node ast.Node, err error) {
+	line, col := mdctx.NodePosition(source, node)
+	msg := err.Error()
+	var perr toml.ParseError
+	if errors.As(err, &perr) && node.Lines().Len() > 0 {
+		// The TOML lines are contiguous in the source, so the offset into the
+		// TOML is an offset from the first line.
+		line, col = mdctx.OffsetPosition(source, node.Lines().At(0).Start+perr.Position.Start)
+		msg = tomlLinePrefix.ReplaceAllString(msg, "")
+		if perr.LastKey != "" {
+			msg = fmt.Sprintf("key %q: %s", perr.LastKey, msg)
+		}
+	}
+	mdctx.PushDiagnostic(pc, mdctx.Diagnostic{
+		Line:     line,
+		Col:      col,
+		Severity: mdctx.SeverityError,
+		Err:      errors.New("invalid TOML frontmatter: " + msg),
+	})
+}
+
 func (t *tomlParser) CanInterruptParagraph() bool {
 	return false
 }
@@ -160,4 +239,97 @@ func NewTOMLExt() goldmark.Extender {
 
 func (t *tomlFront) Extend(m goldmark.Markdown) {
 	extenders.AddBlockParser(m, newTOMLParser(), ord.TOMLParser)
+	extenders.AddASTTransform(m, metaFallbackTransformer{}, ord.MetaFallbackTransformer)
+}
+
+// maxDescriptionLen is the max length in bytes of a description taken from
+// the first paragraph. Search engines truncate longer descriptions.
+const maxDescriptionLen = 160
+
+// metaFallbackTransformer sets the description and image of the post meta
+// from the content if the frontmatter doesn't set them. Runs after the
+// figure transformer so the image is the destination of the first figure.
+type metaFallbackTransformer struct{}
+
+func (m metaFallbackTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	meta := GetTOMLMeta(pc)
+	if meta.Description != "" && meta.Image != "" {
+		return
+	}
+	src := reader.Source()
+	desc, img := "", ""
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Document, *Article:
+			return ast.WalkContinue, nil
+		case *ast.Paragraph:
+			if desc == "" {
+				desc = plainText(n, src)
+			}
+		case *Figure:
+			if img == "" {
+				img = string(n.Destination)
+			}
+		}
+		if desc != "" && img != "" {
+			return ast.WalkStop, nil
+		}
+		// Only consider top-level paragraphs and figures, not those in
+		// asides like notes.
+		return ast.WalkSkipChildren, nil
+	})
+	if desc == "" && img == "" {
+		return
+	}
+	if meta.Description == "" {
+		meta.Description = truncateWords(desc, maxDescriptionLen)
+	}
+	if meta.Image == "" {
+		meta.Image = img
+	}
+	SetTOMLMeta(pc, meta)
+}
+
+// plainText returns the text content of the node without footnotes,
+// citations, and TeX math, joining lines with a space.
+func plainText(n ast.Node, src []byte) string {
+	b := strings.Builder{}
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *FootnoteLink, *FootnoteBody, *Citation, *CitationRef:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			b.Write(n.Segment.Value(src))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *SmallCaps:
+			b.Write(n.Segment.Value(src))
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// truncateWords truncates s to at most n bytes, ending at a word boundary
+// with an ellipsis if truncated.
+func truncateWords(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := strings.LastIndexByte(s[:n], ' ')
+	if cut <= 0 {
+		// A single long word. Cut at a rune boundary.
+		for cut = n; cut > 0 && !utf8.RuneStart(s[cut]); cut-- {
+		}
+	}
+	return strings.TrimRight(s[:cut], " ,;:.") + "…"
 }
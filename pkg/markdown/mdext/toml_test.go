@@ -2,14 +2,19 @@ package mdext
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jschaf/jsc/pkg/cite"
 	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/markdown/mdctx"
 	"github.com/jschaf/jsc/pkg/markdown/mdtest"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/jschaf/jsc/pkg/texts"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
 )
 
 func TestMeta(t *testing.T) {
@@ -40,6 +45,44 @@ func TestMeta(t *testing.T) {
 				BibPaths: []string{"/md/test/ref.bib", filepath.Join(root, "r1/r2.bib")},
 			},
 		},
+		{
+			"tags",
+			texts.Dedent(`
+				+++
+				slug = "a_slug"
+				date = 2019-09-20
+				tags = ["go", "databases"]
+				+++
+				# Hello goldmark-meta
+      `),
+			texts.Dedent(`
+        <h1>Hello goldmark-meta</h1>
+      `),
+			PostMeta{
+				Path: "/a_slug/",
+				Slug: "a_slug",
+				Date: time.Date(2019, time.September, 20, 0, 0, 0, 0, time.Local),
+				Tags: []string{"go", "databases"},
+			},
+		},
+		{
+			"cite style ignores case",
+			texts.Dedent(`
+				+++
+				slug = "a_slug"
+				cite_style = "apa"
+				+++
+				# Hello goldmark-meta
+      `),
+			texts.Dedent(`
+        <h1>Hello goldmark-meta</h1>
+      `),
+			PostMeta{
+				Path:      "/a_slug/",
+				Slug:      "a_slug",
+				CiteStyle: cite.APA,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -54,8 +97,120 @@ func TestMeta(t *testing.T) {
 	}
 }
 
+func TestMeta_UnknownTag(t *testing.T) {
+	src := texts.Dedent(`
+		+++
+		slug = "a_slug"
+		tags = ["go", "golang"]
+		+++
+		# Hello goldmark-meta
+  `)
+	md, ctx := mdtest.NewTester(t, NewTOMLExt())
+	md.Parser().Parse(text.NewReader([]byte(src)), parser.WithContext(ctx))
+	errs := mdctx.PopErrors(ctx)
+	if len(errs) != 1 {
+		t.Fatalf("want 1 error for unknown tag; got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), `unknown tag "golang"`) {
+		t.Errorf("want unknown tag error for golang; got: %v", errs[0])
+	}
+}
+
+func TestMeta_UnknownCiteStyle(t *testing.T) {
+	src := texts.Dedent(`
+		+++
+		slug = "a_slug"
+		cite_style = "MLA"
+		+++
+		# Hello goldmark-meta
+  `)
+	md, ctx := mdtest.NewTester(t, NewTOMLExt())
+	md.Parser().Parse(text.NewReader([]byte(src)), parser.WithContext(ctx))
+	errs := mdctx.PopErrors(ctx)
+	if len(errs) != 1 {
+		t.Fatalf("want 1 error for unknown cite style; got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), `unknown cite style "MLA"`) {
+		t.Errorf("want unknown cite style error for MLA; got: %v", errs[0])
+	}
+}
+
 func cmpTimeDate() cmp.Option {
 	return cmp.Transformer("TimeDate", func(t time.Time) string {
 		return t.Format(time.DateOnly)
 	})
 }
+
+func TestMeta_DescriptionImage(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		wantDesc  string
+		wantImage string
+	}{
+		{
+			"fallback to first paragraph and figure",
+			texts.Dedent(`
+				+++
+				slug = "a_slug"
+				+++
+				# Hello goldmark-meta
+
+				The *first* paragraph with ` + "`code`" + `
+				on two lines.
+
+				![alt](first.png)
+
+				Second paragraph.
+
+				![alt](second.png)
+      `),
+			"The first paragraph with code on two lines.",
+			"/a_slug/first.png",
+		},
+		{
+			"frontmatter",
+			texts.Dedent(`
+				+++
+				slug = "a_slug"
+				description = "A summary."
+				image = "cover.png"
+				+++
+				# Hello goldmark-meta
+
+				The first paragraph.
+
+				![alt](first.png)
+      `),
+			"A summary.",
+			"/a_slug/cover.png",
+		},
+		{
+			"truncate long paragraph",
+			texts.Dedent(`
+				+++
+				slug = "a_slug"
+				image = "https://example.com/cover.png"
+				+++
+				# Hello goldmark-meta
+
+				` + strings.Repeat("word ", 40) + `
+      `),
+			strings.TrimSpace(strings.Repeat("word ", 32)) + "…",
+			"https://example.com/cover.png",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, ctx := mdtest.NewTester(t, NewTOMLExt(), NewFigureExt())
+			mdtest.MustParseMarkdown(t, md, ctx, tt.src)
+			meta := GetTOMLMeta(ctx)
+			if meta.Description != tt.wantDesc {
+				t.Errorf("description mismatch:\ngot:  %q\nwant: %q", meta.Description, tt.wantDesc)
+			}
+			if meta.Image != tt.wantImage {
+				t.Errorf("image mismatch: got %q, want %q", meta.Image, tt.wantImage)
+			}
+		})
+	}
+}
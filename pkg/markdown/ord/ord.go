@@ -14,6 +14,7 @@ const (
 	ColonBlockParser      ParserPriority = 10
 	ColonLineParser       ParserPriority = 12
 	FootnoteLinkParser    ParserPriority = 20
+	CrossRefParser        ParserPriority = 30
 	KatexParser           ParserPriority = 150
 	ContinueReadingParser ParserPriority = 800
 	SmallCapsParser       ParserPriority = 999
@@ -25,15 +26,20 @@ const (
 )
 
 const (
+	CrossRefHeadingTransformer ASTTransformerPriority = 590
+	CodeCalloutTransformer     ASTTransformerPriority = 595
+	CodeLangTransformer        ASTTransformerPriority = 596
 	HeadingIdTransformer       ASTTransformerPriority = 600
 	ArticleTransformer         ASTTransformerPriority = 900
 	LinkDecorationTransformer  ASTTransformerPriority = 900
 	LinkAssetTransformer       ASTTransformerPriority = 901
+	ImageTransformer           ASTTransformerPriority = 998
 	FigureTransformer          ASTTransformerPriority = 999
-	ImageTransformer           ASTTransformerPriority = 999
 	TableCaptionTransformer    ASTTransformerPriority = 999
 	FootnoteBodyTransformer    ASTTransformerPriority = 1000
+	MetaFallbackTransformer    ASTTransformerPriority = 1000
 	TOCTransformer             ASTTransformerPriority = 1000
+	CrossRefTransformer        ASTTransformerPriority = 1000
 	ContinueReadingTransformer ASTTransformerPriority = 1001
 	KatexFeatureTransformer    ASTTransformerPriority = 1200
 )
@@ -49,10 +55,12 @@ const (
 	ArticleRenderer         RendererPriority = 999
 	CitationRenderer        RendererPriority = 999
 	CodeBlockRenderer       RendererPriority = 999
+	CrossRefRenderer        RendererPriority = 999
 	CustomRenderer          RendererPriority = 999
 	FigureRenderer          RendererPriority = 999
 	HeaderRenderer          RendererPriority = 999
 	SmallCapsRenderer       RendererPriority = 999
+	TagListRenderer         RendererPriority = 999
 	ImageRenderer           RendererPriority = 500
 	FootnoteRenderer        RendererPriority = 1000
 	ColonBlockRenderer      RendererPriority = 1000
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+	"github.com/yuin/goldmark/ast"
+)
+
+// The weight of a single occurrence of a term in each part of a page. A term
+// in the title says more about the page than a term in the body.
+const (
+	titleWeight   = 10
+	headingWeight = 5
+	keyWeight     = 3
+	bodyWeight    = 1
+)
+
+// Doc is a searchable page.
+type Doc struct {
+	// The URL path of the page, like "/foo-bar/".
+	Path  string
+	Title string
+	Date  time.Time
+	// The TOC entries of the page so search results can link to a section.
+	Sections []Section
+	// The weighted count of each stemmed term in the page.
+	Terms map[string]int
+}
+
+// Section is a heading in the TOC of a page.
+type Section struct {
+	// The heading ID, used as the URL fragment.
+	ID    string
+	Title string
+}
+
+// NewDoc creates a search doc from the AST of a page. The AST must be parsed
+// with a TOC style that shows the TOC to index TOC entries.
+func NewDoc(a *markdown.AST) Doc {
+	d := Doc{
+		Path:  a.Meta.Path,
+		Title: a.Meta.Title,
+		Date:  a.Meta.Date,
+		Terms: make(map[string]int),
+	}
+	d.add(a.Meta.Title, titleWeight)
+	for _, tag := range a.Meta.Tags {
+		d.add(tag, keyWeight)
+	}
+	src := a.Source
+	_ = ast.Walk(a.Node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			// The H1 is the title.
+			if n.Level > 1 {
+				d.add(string(n.Text(src)), headingWeight)
+			}
+			return ast.WalkSkipChildren, nil
+		case *mdext.TOC:
+			for _, h := range n.Headings {
+				id, _ := h.AttributeString("id")
+				idBytes, _ := id.([]byte)
+				d.Sections = append(d.Sections, Section{ID: string(idBytes), Title: strings.TrimSpace(string(h.Text(src)))})
+			}
+			return ast.WalkSkipChildren, nil
+		case *mdext.Citation:
+			d.add(n.Key, keyWeight)
+		case *mdext.CitationReferences:
+			return ast.WalkSkipChildren, nil
+		case *ast.FencedCodeBlock:
+			d.addLines(n, src)
+		case *ast.CodeBlock:
+			d.addLines(n, src)
+		case *mdext.CodeInclude:
+			d.add(n.Code, bodyWeight)
+		case *ast.Text:
+			d.add(string(n.Segment.Value(src)), bodyWeight)
+		case *ast.String:
+			d.add(string(n.Value), bodyWeight)
+		}
+		return ast.WalkContinue, nil
+	})
+	return d
+}
+
+func (d Doc) add(text string, weight int) {
+	for _, t := range Terms(text) {
+		d.Terms[t] += weight
+	}
+}
+
+func (d Doc) addLines(n ast.Node, src []byte) {
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		d.add(string(seg.Value(src)), bodyWeight)
+	}
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/mdext"
+	"github.com/jschaf/jsc/pkg/texts"
+)
+
+func TestNewDoc(t *testing.T) {
+	src := texts.Dedent(`
+		+++
+		slug = "queries"
+		date = 2021-03-04
+		visibility = "published"
+		tags = ["go"]
+		+++
+		# Fast queries
+
+		:toc:
+
+		Queries run *fast*.
+
+		## Indexing data
+
+		` + "```go" + `
+		index := build()
+		` + "```" + `
+	`)
+	md := markdown.New(markdown.WithTOCStyle(mdext.TOCStyleShow))
+	ast, err := md.Parse("/md/test/path.md", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := NewDoc(ast)
+	want := Doc{
+		Path:     "/queries/",
+		Title:    "Fast queries",
+		Date:     time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC),
+		Sections: []Section{{ID: "indexing-data", Title: "Indexing data"}},
+		Terms: map[string]int{
+			"fast":  titleWeight + bodyWeight,
+			"query": titleWeight + bodyWeight,
+			"go":    keyWeight,
+			"run":   bodyWeight,
+			"index": headingWeight + bodyWeight,
+			"data":  headingWeight,
+			"build": bodyWeight,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NewDoc mismatch (-want +got):\n%s", diff)
+	}
+}
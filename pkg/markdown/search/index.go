@@ -0,0 +1,121 @@
+package search
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Index is the set of searchable docs on the site keyed by the source of the
+// page, like the markdown path. Safe for concurrent use so pages compiled in
+// parallel can add their docs.
+type Index struct {
+	mu   sync.Mutex
+	docs map[string][]Doc
+}
+
+func NewIndex() *Index {
+	return &Index{docs: make(map[string][]Doc)}
+}
+
+// Set replaces the docs built from source. A single source might build
+// several pages, like the chapters of a book.
+func (idx *Index) Set(source string, docs ...Doc) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs[source] = docs
+}
+
+// Remove removes the docs built from source.
+func (idx *Index) Remove(source string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.docs, source)
+}
+
+// Docs returns all docs sorted by date, newest first, then by path.
+func (idx *Index) Docs() []Doc {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var docs []Doc
+	for _, ds := range idx.docs {
+		docs = append(docs, ds...)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if !docs[i].Date.Equal(docs[j].Date) {
+			return docs[i].Date.After(docs[j].Date)
+		}
+		return docs[i].Path < docs[j].Path
+	})
+	return docs
+}
+
+// Manifest is the entry point of the serialized index, written to
+// /search-index.json. The postings are split into shards by the first
+// character of the term so a query only loads the shards for its terms.
+type Manifest struct {
+	Docs []ManifestDoc `json:"docs"`
+	// The shard keys, sorted. The client loads the shard for a term from
+	// ShardPath.
+	Shards []string `json:"shards"`
+}
+
+// ManifestDoc is a doc in the manifest. Postings refer to a doc by its index
+// in Manifest.Docs. Uses short JSON keys to keep the manifest small.
+type ManifestDoc struct {
+	Path  string `json:"p"`
+	Title string `json:"t"`
+	// The date formatted as YYYY-MM-DD.
+	Date string `json:"d,omitempty"`
+	// The TOC entries as [id, title] pairs.
+	Sections [][2]string `json:"s,omitempty"`
+}
+
+// Shard maps each term in the shard to a flat list of postings, alternating
+// the doc index and the weighted count of the term in the doc, sorted by
+// doc index.
+type Shard map[string][]int
+
+// ShardPath returns the URL path of the shard with the key.
+func ShardPath(key string) string {
+	return "/search-index/" + key + ".json"
+}
+
+// shardKey returns the key of the shard containing the term. Terms starting
+// with anything but an ASCII letter or digit share a shard.
+func shardKey(term string) string {
+	b := term[0]
+	if ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') {
+		return string(b)
+	}
+	return "_"
+}
+
+// Build serializes the docs into a manifest and the shards keyed by shard
+// key.
+func Build(docs []Doc) (Manifest, map[string]Shard) {
+	m := Manifest{Docs: make([]ManifestDoc, len(docs)), Shards: []string{}}
+	shards := make(map[string]Shard)
+	for i, d := range docs {
+		md := ManifestDoc{Path: d.Path, Title: d.Title}
+		if !d.Date.IsZero() {
+			md.Date = d.Date.Format(time.DateOnly)
+		}
+		for _, s := range d.Sections {
+			md.Sections = append(md.Sections, [2]string{s.ID, s.Title})
+		}
+		m.Docs[i] = md
+		for term, count := range d.Terms {
+			key := shardKey(term)
+			shard, ok := shards[key]
+			if !ok {
+				shard = make(Shard)
+				shards[key] = shard
+				m.Shards = append(m.Shards, key)
+			}
+			shard[term] = append(shard[term], i, count)
+		}
+	}
+	sort.Strings(m.Shards)
+	return m, shards
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIndex_Docs(t *testing.T) {
+	older := Doc{Path: "/older/", Date: time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC)}
+	newerA := Doc{Path: "/a/", Date: time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)}
+	newerB := Doc{Path: "/b/", Date: time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)}
+	removed := Doc{Path: "/removed/"}
+
+	idx := NewIndex()
+	idx.Set("older.md", older)
+	idx.Set("book.toml", newerB, newerA)
+	idx.Set("removed.md", removed)
+	idx.Remove("removed.md")
+
+	want := []Doc{newerA, newerB, older}
+	if diff := cmp.Diff(want, idx.Docs()); diff != "" {
+		t.Errorf("Docs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	docs := []Doc{
+		{
+			Path:     "/newer/",
+			Title:    "Newer",
+			Date:     time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC),
+			Sections: []Section{{ID: "intro", Title: "Intro"}},
+			Terms:    map[string]int{"go": 10, "alpha": 1, "42": 1},
+		},
+		{
+			Path:  "/older/",
+			Title: "Older",
+			Terms: map[string]int{"go": 3, "ümlaut": 1},
+		},
+	}
+	gotManifest, gotShards := Build(docs)
+
+	wantManifest := Manifest{
+		Docs: []ManifestDoc{
+			{Path: "/newer/", Title: "Newer", Date: "2021-03-04", Sections: [][2]string{{"intro", "Intro"}}},
+			{Path: "/older/", Title: "Older"},
+		},
+		Shards: []string{"4", "_", "a", "g"},
+	}
+	if diff := cmp.Diff(wantManifest, gotManifest); diff != "" {
+		t.Errorf("Build manifest mismatch (-want +got):\n%s", diff)
+	}
+	wantShards := map[string]Shard{
+		"4": {"42": {0, 1}},
+		"_": {"ümlaut": {1, 1}},
+		"a": {"alpha": {0, 1}},
+		"g": {"go": {0, 10, 1, 3}},
+	}
+	if diff := cmp.Diff(wantShards, gotShards); diff != "" {
+		t.Errorf("Build shards mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,108 @@
+// Package search builds a full-text search index of the site at build time.
+// The client-side search in static/main.ts loads the index and must tokenize
+// queries exactly like Terms, using static/search_terms.ts.
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Words shorter than minWordLen or longer than maxWordLen runes aren't
+// indexed. Long words are usually hashes or URLs.
+const (
+	minWordLen = 2
+	maxWordLen = 32
+)
+
+// stopWords are common English words that don't help find a post. A superset
+// of the stop words dropped from the end of heading slugs.
+var stopWords = func() map[string]struct{} {
+	words := strings.Fields(`
+		a about above after again all also am an and any are as at be because
+		been before being below between both but by can could did do does doing
+		down during each few for from further had has have having he her here
+		hers him his how if in into is it its itself just me more most my no nor
+		not now of off on once only or other our ours out over own same she
+		should so some such than that the their theirs them then there these
+		they this those through to too under until up very was we were what when
+		where which while who whom why will with would you your yours`)
+	m := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		m[w] = struct{}{}
+	}
+	return m
+}()
+
+// isStopWord returns true if the lowercase word is a stop word.
+func isStopWord(word string) bool {
+	_, ok := stopWords[word]
+	return ok
+}
+
+// Terms splits text into lowercase words, drops stop words, and stems each
+// word. A word is a run of letters and numbers, like the Unicode classes
+// \p{L} and \p{N} used by the client.
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	terms := words[:0]
+	for _, w := range words {
+		n := utf8.RuneCountInString(w)
+		if n < minWordLen || n > maxWordLen || isStopWord(w) {
+			continue
+		}
+		terms = append(terms, Stem(w))
+	}
+	return terms
+}
+
+// Stem reduces a lowercase word to a stem by removing common English suffixes
+// so that "parse", "parses", "parsed", and "parsing" share the stem "pars".
+// Much simpler than the Porter stemmer so the client can mirror it exactly.
+// Length checks count runes. The suffixes are ASCII, so trimming them by bytes
+// is safe.
+func Stem(w string) string {
+	n := utf8.RuneCountInString(w)
+	if n <= 3 {
+		return w
+	}
+	switch {
+	case strings.HasSuffix(w, "ies") && n > 4:
+		w = w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "sses"):
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "ing") && n >= 6:
+		w = undouble(w[:len(w)-3])
+	case strings.HasSuffix(w, "ed") && n >= 5:
+		w = undouble(w[:len(w)-2])
+	case strings.HasSuffix(w, "ly") && n >= 5:
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") &&
+		!strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		w = w[:len(w)-1]
+	}
+	if utf8.RuneCountInString(w) > 3 && strings.HasSuffix(w, "e") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
+
+// undouble removes the last letter of a stem ending in a doubled consonant,
+// like "runn" from "running", except for l, s, and z, like "call" and "pass".
+func undouble(w string) string {
+	n := len(w)
+	if n < 2 || w[n-1] != w[n-2] {
+		return w
+	}
+	switch w[n-1] {
+	case 'a', 'e', 'i', 'o', 'u', 'l', 's', 'z':
+		return w
+	}
+	if w[n-1] < 'a' || w[n-1] > 'z' {
+		return w
+	}
+	return w[:n-1]
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/git"
+)
+
+// termsVector is a test case in testdata/terms.json, shared with
+// static/search_terms_test.ts so the client tokenizes queries like the index.
+type termsVector struct {
+	Text string   `json:"text"`
+	Want []string `json:"want"`
+}
+
+func readTermsVectors(t *testing.T) []termsVector {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", "terms.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []termsVector
+	if err := json.Unmarshal(b, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	return vectors
+}
+
+func TestTerms(t *testing.T) {
+	for _, tt := range readTermsVectors(t) {
+		t.Run(tt.Text, func(t *testing.T) {
+			got := Terms(tt.Text)
+			if diff := cmp.Diff(tt.Want, got, cmpEmpty()); diff != "" {
+				t.Errorf("Terms(%q) mismatch (-want +got):\n%s", tt.Text, diff)
+			}
+		})
+	}
+}
+
+// TestTerms_Client runs the client tokenizer against the shared test vectors
+// with Node.
+func TestTerms_Client(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed")
+	}
+	out := filepath.Join(t.TempDir(), "search_terms_test.js")
+	result := esbuild.Build(esbuild.BuildOptions{
+		EntryPoints: []string{filepath.Join(git.RootDir(), dirs.Static, "search_terms_test.ts")},
+		Outfile:     out,
+		Bundle:      true,
+		Platform:    esbuild.PlatformNode,
+		Write:       true,
+		LogLevel:    esbuild.LogLevelError,
+	})
+	for _, e := range result.Errors {
+		t.Errorf("bundle client test: %s", e.Text)
+	}
+	if t.Failed() {
+		return
+	}
+	if b, err := exec.Command(node, out).CombinedOutput(); err != nil {
+		t.Errorf("client terms mismatch: %v\n%s", err, b)
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"go", "go"},
+		{"use", "use"},
+		{"types", "typ"},
+		{"typing", "typ"},
+		{"typed", "typ"},
+		{"queries", "query"},
+		{"classes", "class"},
+		{"stopped", "stop"},
+		{"called", "call"},
+		{"passing", "pass"},
+		{"quickly", "quick"},
+		{"status", "status"},
+		{"analysis", "analysis"},
+		{"thing", "thing"},
+	}
+	for _, tt := range tests {
+		if got := Stem(tt.word); got != tt.want {
+			t.Errorf("Stem(%q) = %q; want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+// cmpEmpty treats nil and empty slices as equal.
+func cmpEmpty() cmp.Option {
+	return cmp.FilterValues(func(x, y []string) bool { return len(x) == 0 && len(y) == 0 }, cmp.Ignore())
+}
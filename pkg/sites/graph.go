@@ -0,0 +1,103 @@
+package sites
+
+import (
+	"sort"
+	"sync"
+)
+
+// depGraph records which pages depend on which inputs. A page is identified by
+// the path of the markdown file that produces it. Inputs are absolute file
+// paths, like the markdown file itself, bib files, and post assets.
+type depGraph struct {
+	mu sync.Mutex
+	// dependents maps an input path to the pages that read the input.
+	dependents map[string]map[string]struct{}
+	// inputs maps a page to all inputs used for the last build of the page. Used
+	// to drop stale edges when a page is rebuilt.
+	inputs map[string][]string
+	// outputs maps a page to the dist paths written for the page.
+	outputs map[string][]string
+}
+
+func newDepGraph() *depGraph {
+	return &depGraph{
+		dependents: make(map[string]map[string]struct{}),
+		inputs:     make(map[string][]string),
+		outputs:    make(map[string][]string),
+	}
+}
+
+// setPage replaces all inputs and outputs recorded for page.
+func (g *depGraph) setPage(page string, inputs, outputs []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.removePageLocked(page)
+	for _, in := range inputs {
+		deps, ok := g.dependents[in]
+		if !ok {
+			deps = make(map[string]struct{})
+			g.dependents[in] = deps
+		}
+		deps[page] = struct{}{}
+	}
+	g.inputs[page] = inputs
+	g.outputs[page] = outputs
+}
+
+// removePage removes the page and returns the outputs of the page, if any.
+func (g *depGraph) removePage(page string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.removePageLocked(page)
+}
+
+func (g *depGraph) removePageLocked(page string) []string {
+	for _, in := range g.inputs[page] {
+		deps := g.dependents[in]
+		delete(deps, page)
+		if len(deps) == 0 {
+			delete(g.dependents, in)
+		}
+	}
+	outs := g.outputs[page]
+	delete(g.inputs, page)
+	delete(g.outputs, page)
+	return outs
+}
+
+// pageOutputs returns the dist paths written by the last build of page.
+func (g *depGraph) pageOutputs(page string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.outputs[page]
+}
+
+// affectedPages returns the sorted pages that depend on any of the inputs.
+func (g *depGraph) affectedPages(inputs ...string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	seen := make(map[string]struct{})
+	for _, in := range inputs {
+		for page := range g.dependents[in] {
+			seen[page] = struct{}{}
+		}
+	}
+	pages := make([]string, 0, len(seen))
+	for page := range seen {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+// allPages returns all sorted pages in the graph.
+func (g *depGraph) allPages() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	pages := make([]string, 0, len(g.inputs))
+	for page := range g.inputs {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	return pages
+}
@@ -0,0 +1,65 @@
+package sites
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDepGraph_affectedPages(t *testing.T) {
+	g := newDepGraph()
+	g.setPage("/posts/a.md", []string{"/posts/a.md", "/ref.bib", "/posts/a.png"}, []string{"/dist/a"})
+	g.setPage("/posts/b.md", []string{"/posts/b.md", "/ref.bib"}, []string{"/dist/b"})
+
+	tests := []struct {
+		name   string
+		inputs []string
+		want   []string
+	}{
+		{"unknown input", []string{"/posts/c.png"}, []string{}},
+		{"own source", []string{"/posts/a.md"}, []string{"/posts/a.md"}},
+		{"asset", []string{"/posts/a.png"}, []string{"/posts/a.md"}},
+		{"shared bib", []string{"/ref.bib"}, []string{"/posts/a.md", "/posts/b.md"}},
+		{"multiple inputs", []string{"/posts/a.png", "/posts/b.md"}, []string{"/posts/a.md", "/posts/b.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.affectedPages(tt.inputs...)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("affectedPages() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDepGraph_setPage_dropsStaleInputs(t *testing.T) {
+	g := newDepGraph()
+	g.setPage("/posts/a.md", []string{"/posts/a.md", "/posts/old.png"}, []string{"/dist/old-slug"})
+	g.setPage("/posts/a.md", []string{"/posts/a.md", "/posts/new.png"}, []string{"/dist/new-slug"})
+
+	if got := g.affectedPages("/posts/old.png"); len(got) != 0 {
+		t.Errorf("affectedPages(old.png) = %v; want no pages", got)
+	}
+	if diff := cmp.Diff([]string{"/posts/a.md"}, g.affectedPages("/posts/new.png")); diff != "" {
+		t.Errorf("affectedPages(new.png) mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"/dist/new-slug"}, g.pageOutputs("/posts/a.md")); diff != "" {
+		t.Errorf("pageOutputs() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDepGraph_removePage(t *testing.T) {
+	g := newDepGraph()
+	g.setPage("/posts/a.md", []string{"/posts/a.md", "/ref.bib"}, []string{"/dist/a"})
+
+	outs := g.removePage("/posts/a.md")
+	if diff := cmp.Diff([]string{"/dist/a"}, outs); diff != "" {
+		t.Errorf("removePage() outputs mismatch (-want +got):\n%s", diff)
+	}
+	if got := g.allPages(); len(got) != 0 {
+		t.Errorf("allPages() after remove = %v; want no pages", got)
+	}
+	if got := g.affectedPages("/ref.bib"); len(got) != 0 {
+		t.Errorf("affectedPages(ref.bib) after remove = %v; want no pages", got)
+	}
+}
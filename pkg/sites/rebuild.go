@@ -2,49 +2,150 @@ package sites
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jschaf/jsc/pkg/css"
 	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/git"
 	"github.com/jschaf/jsc/pkg/js"
+	"github.com/jschaf/jsc/pkg/linkcheck"
+	"github.com/jschaf/jsc/pkg/markdown"
 	"github.com/jschaf/jsc/pkg/markdown/compiler"
+	"github.com/jschaf/jsc/pkg/markdown/html"
+	"github.com/jschaf/jsc/pkg/markdown/search"
+	"github.com/jschaf/jsc/pkg/paths"
 	"github.com/jschaf/jsc/pkg/static"
+	"github.com/karrick/godirwalk"
 	"golang.org/x/sync/errgroup"
 )
 
-// Rebuild rebuilds everything on the site into distDir.
+// Rebuild rebuilds everything on the site into distDir for production,
+// skipping unpublished posts.
 func Rebuild(distDir string) error {
+	return NewBuilder(distDir, compiler.PublishModeProd).Rebuild()
+}
+
+// Builder builds the site into distDir. Builder records the inputs of each
+// page during a build so that RebuildChanged only recompiles the pages
+// affected by a changed file instead of the entire site.
+type Builder struct {
+	distDir string
+	mode    compiler.PublishMode
+	cfg     compiler.SiteConfig
+	detail  *compiler.DetailCompiler
+	book    *compiler.BookCompiler
+	index   *compiler.IndexCompiler
+	graph   *depGraph
+	// The index AST of each page source, reused by incremental rebuilds so a
+	// changed file only re-parses the affected posts for the index.
+	indexASTs map[string]*markdown.AST
+	// The published chapters of the last book build, listed in the sitemap.
+	bookChapters []*markdown.AST
+	// Guards indexASTs and bookChapters.
+	indexMu sync.Mutex
+	// The search docs of each page, rewritten to the search index after each
+	// build.
+	searchIdx *search.Index
+	// Serializes builds so concurrent file events don't interleave writes.
+	mu sync.Mutex
+}
+
+// NewBuilder creates a builder for distDir. The mode determines whether
+// unpublished posts are skipped or rendered with a draft banner.
+func NewBuilder(distDir string, mode compiler.PublishMode) *Builder {
+	cfg := compiler.DefaultSiteConfig()
+	return &Builder{
+		distDir:   distDir,
+		mode:      mode,
+		cfg:       cfg,
+		detail:    compiler.NewDetailCompiler(distDir, mode, cfg),
+		book:      compiler.NewBookCompiler(distDir, mode, cfg),
+		index:     compiler.NewIndexCompiler(distDir, cfg),
+		graph:     newDepGraph(),
+		searchIdx: search.NewIndex(),
+	}
+}
+
+// sourceDirs returns the absolute dirs that contain markdown pages.
+func sourceDirs() []string {
+	root := git.RootDir()
+	return []string{
+		filepath.Join(root, dirs.Posts),
+		filepath.Join(root, dirs.TIL),
+	}
+}
+
+// isPageSource returns true if path is a markdown file that produces a page.
+func isPageSource(path string) bool {
+	if filepath.Ext(path) != ".md" {
+		return false
+	}
+	for _, dir := range sourceDirs() {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rebuild cleans distDir and rebuilds everything on the site.
+func (b *Builder) Rebuild() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rebuildLocked()
+}
+
+func (b *Builder) rebuildLocked() error {
 	slog.Info("start rebuild site")
 	start := time.Now()
 
-	if err := dirs.CleanDir(distDir); err != nil {
+	if err := b.loadConfig(); err != nil {
+		return err
+	}
+
+	if err := dirs.CleanDir(b.distDir); err != nil {
 		return fmt.Errorf("failed to clean public dir: %w", err)
 	}
+	b.graph = newDepGraph()
+	b.searchIdx = search.NewIndex()
 
 	g, _ := errgroup.WithContext(context.Background())
 	g.Go(func() error {
 		slog.Debug("rebuild compile details")
-		c := compiler.NewDetailCompiler(distDir)
-		if err := c.Compile(""); err != nil {
-			return fmt.Errorf("compile all detail posts: %w", err)
+		for _, dir := range sourceDirs() {
+			if err := b.compilePageDir(dir); err != nil {
+				return fmt.Errorf("compile all detail posts: %w", err)
+			}
 		}
 		return nil
 	})
 
+	g.Go(func() error {
+		slog.Debug("rebuild compile book")
+		return b.compileBook()
+	})
+
 	g.Go(func() error {
 		slog.Debug("rebuild compile index")
-		ic := compiler.NewIndexCompiler(distDir)
-		if err := ic.Compile(); err != nil {
-			return fmt.Errorf("compile main index: %w", err)
+		if err := b.parseIndexASTs(); err != nil {
+			return err
 		}
-		return nil
+		return b.compileIndex()
 	})
 
 	g.Go(func() error {
 		slog.Debug("rebuild copy all css")
-		if _, err := css.CopyAllCSS(distDir); err != nil {
+		if _, err := css.CopyAllCSS(b.distDir); err != nil {
 			return fmt.Errorf("copy all css: %w", err)
 		}
 		return nil
@@ -52,7 +153,7 @@ func Rebuild(distDir string) error {
 
 	g.Go(func() error {
 		slog.Debug("rebuild copy all fonts")
-		if err := css.CopyAllFonts(distDir); err != nil {
+		if err := css.CopyAllFonts(b.distDir); err != nil {
 			return fmt.Errorf("copy all fonts: %w", err)
 		}
 		return nil
@@ -60,7 +161,7 @@ func Rebuild(distDir string) error {
 
 	g.Go(func() error {
 		slog.Debug("rebuild copy static files")
-		if err := static.CopyStaticFiles(distDir); err != nil {
+		if err := static.CopyStaticFiles(b.distDir); err != nil {
 			return fmt.Errorf("copy static files: %w", err)
 		}
 		return nil
@@ -68,7 +169,7 @@ func Rebuild(distDir string) error {
 
 	g.Go(func() error {
 		slog.Debug("rebuild link papers")
-		if err := static.LinkPapers(distDir); err != nil {
+		if err := static.LinkPapers(b.distDir); err != nil {
 			return fmt.Errorf("link papers: %w", err)
 		}
 		return nil
@@ -76,7 +177,7 @@ func Rebuild(distDir string) error {
 
 	g.Go(func() error {
 		slog.Debug("rebuild typescript")
-		if err := js.WriteTypeScriptMain(distDir); err != nil {
+		if err := js.WriteTypeScriptMain(b.distDir); err != nil {
 			return fmt.Errorf("write typescript bundle: %w", err)
 		}
 		return nil
@@ -86,6 +187,376 @@ func Rebuild(distDir string) error {
 		return fmt.Errorf("rebuild wait err group: %w", err)
 	}
 
+	// The search index needs the docs of every page and the sitemap needs
+	// the posts and the book.
+	if err := b.compileSearch(); err != nil {
+		return err
+	}
+	if err := b.compileSitemap(); err != nil {
+		return err
+	}
+
+	if err := b.checkLinks(); err != nil {
+		return err
+	}
+
 	slog.Info("finish rebuild site", "duration", time.Since(start))
 	return nil
 }
+
+// checkLinks checks the built site for broken internal links. Broken links
+// fail production builds but only log a warning in preview mode so the dev
+// server keeps serving while editing.
+func (b *Builder) checkLinks() error {
+	problems, err := linkcheck.Check(b.distDir)
+	if err != nil {
+		return fmt.Errorf("check links: %w", err)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if b.mode == compiler.PublishModePreview {
+		for _, p := range problems {
+			slog.Warn("broken link", "file", p.File, "url", p.URL, "reason", p.Reason)
+		}
+		return nil
+	}
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.String()
+	}
+	return fmt.Errorf("found %d broken links:\n%s", len(problems), strings.Join(msgs, "\n"))
+}
+
+// RebuildChanged rebuilds the pages that depend on any of the changed files
+// and the index. Changed paths must be absolute. A changed template rebuilds
+// every page and a changed site config or link preview cache rebuilds the
+// whole site. Paths that no page depends on are ignored.
+func (b *Builder) RebuildChanged(changed ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start := time.Now()
+
+	if slices.ContainsFunc(changed, compiler.IsSiteInput) {
+		// The config and the link previews apply to every page.
+		return b.rebuildLocked()
+	}
+
+	pages := b.graph.affectedPages(changed...)
+	for _, path := range changed {
+		switch {
+		case html.IsTemplate(path):
+			html.ResetTemplates()
+			pages = b.graph.allPages()
+		case isPageSource(path):
+			// New pages aren't in the graph yet.
+			pages = append(pages, path)
+		case path == b.book.ManifestPath():
+			// The book is a single page in the graph keyed by the manifest.
+			pages = append(pages, path)
+		}
+	}
+	pages = dedupe(pages)
+	if len(pages) == 0 {
+		slog.Debug("no pages affected by changed files", "changed", changed)
+		return nil
+	}
+	slog.Info("start incremental rebuild", "pages", len(pages))
+	if b.indexASTs == nil {
+		// No full build yet, so there are no index ASTs to update.
+		if err := b.loadConfig(); err != nil {
+			return err
+		}
+		if err := b.parseIndexASTs(); err != nil {
+			return err
+		}
+	}
+
+	g, _ := errgroup.WithContext(context.Background())
+	g.SetLimit(runtime.NumCPU())
+	for _, page := range pages {
+		g.Go(func() error {
+			if err := b.compilePage(page); err != nil {
+				return err
+			}
+			return b.updateIndexAST(page)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("incremental rebuild wait err group: %w", err)
+	}
+	// The index and feeds show an excerpt of every post, so any page change
+	// might change them.
+	if err := b.compileIndex(); err != nil {
+		return err
+	}
+	if err := b.compileSearch(); err != nil {
+		return err
+	}
+	if err := b.compileSitemap(); err != nil {
+		return err
+	}
+
+	slog.Info("finish incremental rebuild", "pages", len(pages), "duration", time.Since(start))
+	return nil
+}
+
+// HasDependents returns true if any page read the file at path, an absolute
+// path, during the last build.
+func (b *Builder) HasDependents(path string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.graph.affectedPages(path)) > 0
+}
+
+// loadConfig reads the site config and the link previews and configures the
+// compilers that depend on them.
+func (b *Builder) loadConfig() error {
+	cfg, err := compiler.LoadSite()
+	if err != nil {
+		return err
+	}
+	b.cfg = cfg
+	b.detail = compiler.NewDetailCompiler(b.distDir, b.mode, cfg)
+	b.book = compiler.NewBookCompiler(b.distDir, b.mode, cfg)
+	b.index = compiler.NewIndexCompiler(b.distDir, cfg)
+	return nil
+}
+
+// parseIndexASTs parses all posts for the index and replaces the cached index
+// ASTs.
+func (b *Builder) parseIndexASTs() error {
+	asts, err := b.index.ParseASTs()
+	if err != nil {
+		return fmt.Errorf("parse posts for main index: %w", err)
+	}
+	b.indexMu.Lock()
+	defer b.indexMu.Unlock()
+	b.indexASTs = make(map[string]*markdown.AST, len(asts))
+	for _, ast := range asts {
+		b.indexASTs[ast.Path] = ast
+	}
+	return nil
+}
+
+// updateIndexAST re-parses the cached index AST of the page. Removes the AST
+// if the markdown file no longer exists. Ignores pages that aren't posts, like
+// the book.
+func (b *Builder) updateIndexAST(page string) error {
+	if !isPageSource(page) {
+		return nil
+	}
+	var ast *markdown.AST
+	if _, err := os.Stat(page); !errors.Is(err, os.ErrNotExist) {
+		parsed, err := b.index.ParsePath(page)
+		if err != nil {
+			return fmt.Errorf("parse post for main index: %w", err)
+		}
+		ast = parsed
+	}
+	b.indexMu.Lock()
+	defer b.indexMu.Unlock()
+	if ast == nil {
+		delete(b.indexASTs, page)
+		return nil
+	}
+	b.indexASTs[page] = ast
+	return nil
+}
+
+// sortedIndexASTs returns the cached index ASTs newest first, as returned by
+// ParseASTs.
+func (b *Builder) sortedIndexASTs() []*markdown.AST {
+	b.indexMu.Lock()
+	asts := make([]*markdown.AST, 0, len(b.indexASTs))
+	for _, ast := range b.indexASTs {
+		asts = append(asts, ast)
+	}
+	b.indexMu.Unlock()
+	// Break ties by path so the order doesn't depend on map iteration.
+	sort.Slice(asts, func(i, j int) bool {
+		if !asts[i].Meta.Date.Equal(asts[j].Meta.Date) {
+			return asts[i].Meta.Date.After(asts[j].Meta.Date)
+		}
+		return asts[i].Path < asts[j].Path
+	})
+	return asts
+}
+
+// compileIndex compiles the pages built from all posts, like the main index
+// the feeds, and the tag pages, from the cached index ASTs.
+func (b *Builder) compileIndex() error {
+	asts := b.sortedIndexASTs()
+	ic := b.index
+	if err := ic.CompileASTs(asts); err != nil {
+		return fmt.Errorf("compile main index: %w", err)
+	}
+	fc := compiler.NewFeedCompiler(b.distDir, ic.Markdown(), compiler.FeedContentSummary, b.cfg)
+	if err := fc.CompileASTs(asts); err != nil {
+		return fmt.Errorf("compile feeds: %w", err)
+	}
+	if err := compiler.NewTagCompiler(b.distDir, ic.Markdown(), b.mode).CompileASTs(asts); err != nil {
+		return fmt.Errorf("compile tag pages: %w", err)
+	}
+	return nil
+}
+
+// compileSitemap writes the sitemap of all posts and book chapters. Runs after
+// the index and the book since it lists the pages of both.
+func (b *Builder) compileSitemap() error {
+	b.indexMu.Lock()
+	chapters := b.bookChapters
+	b.indexMu.Unlock()
+	if err := compiler.NewSitemapCompiler(b.distDir).CompileASTs(b.sortedIndexASTs(), chapters); err != nil {
+		return fmt.Errorf("compile sitemap: %w", err)
+	}
+	return nil
+}
+
+// compileSearch writes the search index for the docs of all compiled pages.
+func (b *Builder) compileSearch() error {
+	if err := compiler.NewSearchCompiler(b.distDir).CompileDocs(b.searchIdx.Docs()); err != nil {
+		return fmt.Errorf("compile search index: %w", err)
+	}
+	return nil
+}
+
+func (b *Builder) compilePageDir(dir string) error {
+	return paths.WalkConcurrent(dir, runtime.NumCPU(), func(path string, dirent *godirwalk.Dirent) error {
+		if !dirent.IsRegular() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		return b.compilePage(path)
+	})
+}
+
+// compilePage compiles the markdown page at path and records the inputs of
+// the page in the dependency graph. Removes the outputs of the page if the
+// markdown file no longer exists.
+func (b *Builder) compilePage(path string) error {
+	if path == b.book.ManifestPath() {
+		return b.compileBook()
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		slog.Debug("remove deleted page", "path", path)
+		return b.removePage(path)
+	}
+
+	ast, err := b.detail.CompilePath(path)
+	if err != nil {
+		return fmt.Errorf("compile page %s: %w", path, err)
+	}
+	if ast == nil {
+		// The publish mode skipped the page, like a draft in production. Remove
+		// output from when the page was published, if any.
+		return b.removePage(path)
+	}
+	outDir := filepath.Join(b.distDir, ast.Meta.Slug)
+
+	// Remove stale output if the slug changed.
+	var stale []string
+	for _, out := range b.graph.pageOutputs(path) {
+		if out != outDir {
+			stale = append(stale, out)
+		}
+	}
+	if err := removeAll(stale); err != nil {
+		return fmt.Errorf("remove stale output for page %s: %w", path, err)
+	}
+
+	b.graph.setPage(path, pageInputs(ast), []string{outDir})
+	b.searchIdx.Set(path, search.NewDoc(ast))
+	return nil
+}
+
+// compileBook compiles all chapters of the book and records the book as a
+// single page keyed by the manifest path. Every chapter page links to every
+// other chapter and the landing page has the bibliography of all chapters, so
+// a change to any chapter rebuilds the whole book.
+func (b *Builder) compileBook() error {
+	manifest := b.book.ManifestPath()
+	if _, err := os.Stat(manifest); errors.Is(err, os.ErrNotExist) {
+		slog.Debug("no book manifest, skipping book", "path", manifest)
+		b.indexMu.Lock()
+		b.bookChapters = nil
+		b.indexMu.Unlock()
+		return b.removePage(manifest)
+	}
+
+	chapters, err := b.book.Compile()
+	if err != nil {
+		return fmt.Errorf("compile book: %w", err)
+	}
+	b.indexMu.Lock()
+	b.bookChapters = chapters
+	b.indexMu.Unlock()
+	if len(chapters) == 0 {
+		return b.removePage(manifest)
+	}
+	inputs := []string{manifest}
+	outputs := []string{filepath.Join(b.distDir, dirs.Book, "index.html")}
+	docs := make([]search.Doc, 0, len(chapters))
+	for _, ch := range chapters {
+		inputs = append(inputs, pageInputs(ch)...)
+		outputs = append(outputs, filepath.Join(b.distDir, ch.Meta.Path))
+		docs = append(docs, search.NewDoc(ch))
+	}
+
+	// Remove chapters dropped from the manifest or with a changed slug.
+	var stale []string
+	for _, out := range b.graph.pageOutputs(manifest) {
+		if !slices.Contains(outputs, out) {
+			stale = append(stale, out)
+		}
+	}
+	if err := removeAll(stale); err != nil {
+		return fmt.Errorf("remove stale book output: %w", err)
+	}
+
+	b.graph.setPage(manifest, dedupe(inputs), outputs)
+	b.searchIdx.Set(manifest, docs...)
+	return nil
+}
+
+// removePage removes the page from the dependency graph and the search index
+// and removes the outputs of the page.
+func (b *Builder) removePage(page string) error {
+	b.searchIdx.Remove(page)
+	return removeAll(b.graph.removePage(page))
+}
+
+// pageInputs returns the absolute paths of all files read to build the page
+// for the AST.
+func pageInputs(ast *markdown.AST) []string {
+	inputs := make([]string, 0, 1+len(ast.Meta.BibPaths)+len(ast.Dependencies)+len(ast.Assets))
+	inputs = append(inputs, ast.Path)
+	inputs = append(inputs, ast.Meta.BibPaths...)
+	inputs = append(inputs, ast.Dependencies...)
+	for _, a := range ast.Assets {
+		if a.Src != "" {
+			inputs = append(inputs, a.Src)
+		}
+	}
+	return inputs
+}
+
+func removeAll(paths []string) error {
+	var es error
+	for _, p := range paths {
+		es = errors.Join(es, os.RemoveAll(p))
+	}
+	return es
+}
+
+func dedupe(ss []string) []string {
+	seen := make(map[string]struct{}, len(ss))
+	out := ss[:0]
+	for _, s := range ss {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
@@ -1,11 +1,52 @@
 package sites
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/jschaf/jsc/pkg/dirs"
+	"github.com/jschaf/jsc/pkg/git"
+	"github.com/jschaf/jsc/pkg/markdown"
+	"github.com/jschaf/jsc/pkg/markdown/compiler"
 )
 
+func TestBuilder_UpdateIndexAST_Deleted(t *testing.T) {
+	b := NewBuilder(t.TempDir(), compiler.PublishModePreview)
+	deleted := filepath.Join(git.RootDir(), dirs.Posts, "deleted-post", "deleted-post.md")
+	other := filepath.Join(git.RootDir(), dirs.Posts, "other", "other.md")
+	b.indexASTs = map[string]*markdown.AST{
+		deleted: {Path: deleted},
+		other:   {Path: other},
+	}
+
+	if err := b.updateIndexAST(deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := b.indexASTs[deleted]; ok {
+		t.Errorf("want index AST removed for deleted post %s", deleted)
+	}
+	if _, ok := b.indexASTs[other]; !ok {
+		t.Errorf("want index AST kept for unchanged post %s", other)
+	}
+}
+
+func TestBuilder_HasDependents(t *testing.T) {
+	b := NewBuilder(t.TempDir(), compiler.PublishModePreview)
+	post := filepath.Join(git.RootDir(), dirs.Posts, "capture-deferred-errors-in-go.md")
+	if err := b.compilePage(post); err != nil {
+		t.Fatal(err)
+	}
+
+	// The post includes the example of errs.Capture in a code block.
+	if included := filepath.Join(git.RootDir(), "pkg", "errs", "testdata", "capture.go"); !b.HasDependents(included) {
+		t.Errorf("HasDependents(%s) = false; want true", included)
+	}
+	if other := filepath.Join(git.RootDir(), "pkg", "errs", "errs.go"); b.HasDependents(other) {
+		t.Errorf("HasDependents(%s) = true; want false", other)
+	}
+}
+
 func BenchmarkRebuild(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		if err := Rebuild(dirs.Dist); err != nil {
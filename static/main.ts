@@ -1,19 +1,23 @@
+import {terms} from './search_terms';
+
 type AdblockStatus = 'unknown' | 'blocking' | 'none';
 
-// noinspection JSUnusedGlobalSymbols
-declare interface Window {
-  adblockStatus: AdblockStatus;
-  heap: HeapAnalytics;
-}
+declare global {
+  // noinspection JSUnusedGlobalSymbols
+  interface Window {
+    adblockStatus: AdblockStatus;
+    heap: HeapAnalytics;
+  }
 
-// noinspection JSUnusedGlobalSymbols
-declare interface Navigator {
-  connection?: NetworkInformation;
-}
+  // noinspection JSUnusedGlobalSymbols
+  interface Navigator {
+    connection?: NetworkInformation;
+  }
 
-declare interface NetworkInformation {
-  saveData?: boolean;
-  effectiveType?: 'slow-2g' | '2g' | '3g' | '4g';
+  interface NetworkInformation {
+    saveData?: boolean;
+    effectiveType?: 'slow-2g' | '2g' | '3g' | '4g';
+  }
 }
 
 type EventProps = Record<string, string | number>;
@@ -569,6 +573,197 @@ class PreviewLifecycle {
   }
 })();
 
+// Copy the commands of a terminal session code block when clicking the copy
+// button. The data-copy attribute contains only the commands, without prompts
+// or output.
+(() => {
+  const copyCode = (ev: Event) => {
+    const buttonEl = ev.currentTarget as HTMLButtonElement | null;
+    if (buttonEl == null) {
+      log.warn('copy-code: event target is undefined');
+      return;
+    }
+    const text = buttonEl.dataset.copy;
+    if (text == null || text === '') {
+      log.warn('copy-code: button data-copy is not defined');
+      return;
+    }
+    navigator.clipboard.writeText(text).then(() => {
+      buttonEl.textContent = 'Copied';
+      setTimeout(() => buttonEl.textContent = 'Copy', 1500);
+    }).catch((err) => {
+      log.warn('copy-code: failed to copy to clipboard', err);
+    });
+  };
+
+  const buttons = document.getElementsByClassName('code-copy');
+  for (const button of buttons) {
+    button.addEventListener('click', (ev) => copyCode(ev));
+  }
+})();
+
+// Full-text search on the /search/ page. The build writes a manifest of all
+// docs to /search-index.json and the postings of each term to a shard keyed by
+// the first character of the term. Queries only fetch the shards they need.
+// Tokenization must match pkg/markdown/search/terms.go, see search_terms.ts.
+(() => {
+  const inputEl = document.querySelector<HTMLInputElement>('.search-input');
+  const statusEl = document.querySelector<HTMLElement>('.search-status');
+  const resultsEl = document.querySelector<HTMLElement>('.search-results');
+  if (inputEl == null || statusEl == null || resultsEl == null) {
+    return;
+  }
+
+  interface SearchDoc {
+    p: string; // path
+    t: string; // title
+    d?: string; // date
+    s?: [string, string][]; // sections as [id, title]
+  }
+
+  interface SearchManifest {
+    docs: SearchDoc[];
+    shards: string[];
+  }
+
+  // Maps a term to alternating doc indexes and scores.
+  type SearchShard = Record<string, number[]>;
+
+  let manifest: Promise<SearchManifest> | null = null;
+  const shards = new Map<string, Promise<SearchShard>>();
+
+  const fetchJSON = async <T>(path: string): Promise<T> => {
+    const resp = await fetch(path);
+    if (!resp.ok) {
+      throw new Error(`fetch ${path}: ${resp.status}`);
+    }
+    return await resp.json() as T;
+  };
+
+  const shardKey = (term: string): string => /[a-z0-9]/.test(term[0]) ? term[0] : '_';
+
+  const loadShard = (m: SearchManifest, term: string): Promise<SearchShard> => {
+    const key = shardKey(term);
+    if (!m.shards.includes(key)) {
+      return Promise.resolve({});
+    }
+    let shard = shards.get(key);
+    if (shard == null) {
+      shard = fetchJSON<SearchShard>(`/search-index/${key}.json`);
+      shards.set(key, shard);
+    }
+    return shard;
+  };
+
+  // Returns the score of each doc matching the term. The last term of a query
+  // matches as a prefix so results appear while typing.
+  const matchTerm = async (m: SearchManifest, term: string, isPrefix: boolean): Promise<Map<number, number>> => {
+    const shard = await loadShard(m, term);
+    const scores = new Map<number, number>();
+    const keys = isPrefix ? Object.keys(shard).filter((k) => k.startsWith(term)) : [term];
+    for (const key of keys) {
+      const postings = shard[key] ?? [];
+      for (let i = 0; i < postings.length; i += 2) {
+        scores.set(postings[i], (scores.get(postings[i]) ?? 0) + postings[i + 1]);
+      }
+    }
+    return scores;
+  };
+
+  const renderResult = (doc: SearchDoc, queryTerms: string[]): HTMLElement => {
+    const li = document.createElement('li');
+    const link = document.createElement('a');
+    link.href = doc.p;
+    link.textContent = doc.t;
+    li.append(link);
+    if (doc.d) {
+      const time = document.createElement('time');
+      time.dateTime = doc.d;
+      time.textContent = doc.d;
+      li.append(' ', time);
+    }
+    const sections = (doc.s ?? []).filter(([, title]) =>
+      terms(title).some((t) => queryTerms.some((q) => t.startsWith(q)))).slice(0, 3);
+    if (sections.length > 0) {
+      const ul = document.createElement('ul');
+      for (const [id, title] of sections) {
+        const sectionLi = document.createElement('li');
+        const sectionLink = document.createElement('a');
+        sectionLink.href = `${doc.p}#${id}`;
+        sectionLink.textContent = title;
+        sectionLi.append(sectionLink);
+        ul.append(sectionLi);
+      }
+      li.append(ul);
+    }
+    return li;
+  };
+
+  const search = async (query: string): Promise<void> => {
+    const queryTerms = terms(query);
+    if (queryTerms.length === 0) {
+      statusEl.textContent = '';
+      resultsEl.replaceChildren();
+      return;
+    }
+    manifest ??= fetchJSON<SearchManifest>('/search-index.json');
+    const m = await manifest;
+    const isPrefix = !/\s$/.test(query);
+    const matches = await Promise.all(queryTerms.map((t, i) =>
+      matchTerm(m, t, isPrefix && i === queryTerms.length - 1)));
+
+    // A doc must match every term.
+    const [first, ...rest] = matches;
+    const scores = new Map<number, number>();
+    for (const [doc, score] of first) {
+      let total = score;
+      if (rest.every((r) => r.has(doc))) {
+        rest.forEach((r) => total += r.get(doc) ?? 0);
+        scores.set(doc, total);
+      }
+    }
+    // Docs are newest first, so break ties by doc index.
+    const ranked = [...scores.entries()].sort((a, b) => b[1] - a[1] || a[0] - b[0]);
+    if (inputEl.value !== query) {
+      return; // a newer query is running
+    }
+    statusEl.textContent = ranked.length === 0
+      ? `No results for “${query.trim()}”`
+      : `${ranked.length} result${ranked.length === 1 ? '' : 's'} for “${query.trim()}”`;
+    resultsEl.replaceChildren(...ranked.map(([doc]) => renderResult(m.docs[doc], queryTerms)));
+  };
+
+  const runSearch = () => {
+    const query = inputEl.value;
+    const url = new URL(location.href);
+    if (query.trim() === '') {
+      url.searchParams.delete('q');
+    } else {
+      url.searchParams.set('q', query);
+    }
+    history.replaceState(null, '', url.toString());
+    search(query).catch((err) => {
+      log.warn('search: failed to search', err);
+      statusEl.textContent = 'Search is unavailable.';
+    });
+  };
+
+  let debounceTimer = 0;
+  inputEl.addEventListener('input', () => {
+    clearTimeout(debounceTimer);
+    debounceTimer = setTimeout(runSearch, 150);
+  });
+  inputEl.form?.addEventListener('submit', (ev) => {
+    ev.preventDefault();
+    runSearch();
+  });
+
+  inputEl.value = new URLSearchParams(location.search).get('q') ?? '';
+  if (inputEl.value !== '') {
+    runSearch();
+  }
+})();
+
 // Prefetch URLs on the allowlisted domains on mouseover or touch start events.
 // Forked from instant.page, https://instant.page/license.
 (() => {
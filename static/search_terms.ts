@@ -0,0 +1,63 @@
+// Tokenizes search queries exactly like Terms in
+// pkg/markdown/search/terms.go tokenizes the search index. Word lengths count
+// code points, like runes in Go, instead of UTF-16 code units. The shared test
+// vectors in pkg/markdown/search/testdata/terms.json check both.
+
+// Words shorter than minWordLen or longer than maxWordLen aren't indexed.
+const minWordLen = 2;
+const maxWordLen = 32;
+
+const stopWords = new Set(`
+  a about above after again all also am an and any are as at be because
+  been before being below between both but by can could did do does doing
+  down during each few for from further had has have having he her here
+  hers him his how if in into is it its itself just me more most my no nor
+  not now of off on once only or other our ours out over own same she
+  should so some such than that the their theirs them then there these
+  they this those through to too under until up very was we were what when
+  where which while who whom why will with would you your yours`.trim().split(/\s+/));
+
+// runeLen returns the number of code points in w.
+const runeLen = (w: string): number => [...w].length;
+
+const undouble = (w: string): string => {
+  const n = w.length;
+  if (n < 2 || w[n - 1] !== w[n - 2] || !/[b-df-hj-kmnp-rtv-y]/.test(w[n - 1])) {
+    return w;
+  }
+  return w.slice(0, -1);
+};
+
+export const stem = (w: string): string => {
+  const n = runeLen(w);
+  if (n <= 3) {
+    return w;
+  }
+  if (w.endsWith('ies') && n > 4) {
+    w = w.slice(0, -3) + 'y';
+  } else if (w.endsWith('sses')) {
+    w = w.slice(0, -2);
+  } else if (w.endsWith('ing') && n >= 6) {
+    w = undouble(w.slice(0, -3));
+  } else if (w.endsWith('ed') && n >= 5) {
+    w = undouble(w.slice(0, -2));
+  } else if (w.endsWith('ly') && n >= 5) {
+    w = w.slice(0, -2);
+  } else if (w.endsWith('s') && !w.endsWith('ss') && !w.endsWith('us') && !w.endsWith('is')) {
+    w = w.slice(0, -1);
+  }
+  if (runeLen(w) > 3 && w.endsWith('e')) {
+    w = w.slice(0, -1);
+  }
+  return w;
+};
+
+// terms splits text into lowercase words, drops stop words, and stems each
+// word. A word is a run of letters and numbers.
+export const terms = (text: string): string[] => text.toLowerCase()
+  .split(/[^\p{L}\p{N}]+/u)
+  .filter((w) => {
+    const n = runeLen(w);
+    return n >= minWordLen && n <= maxWordLen && !stopWords.has(w);
+  })
+  .map(stem);
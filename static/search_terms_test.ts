@@ -0,0 +1,9 @@
+// Checks that the client tokenizes like Terms in pkg/markdown/search/terms.go
+// using the shared test vectors. Run by TestTerms_Client with Node.
+import * as assert from 'node:assert';
+import vectors from '../pkg/markdown/search/testdata/terms.json';
+import {terms} from './search_terms';
+
+for (const {text, want} of vectors) {
+  assert.deepStrictEqual(terms(text), want, `terms(${JSON.stringify(text)})`);
+}